@@ -0,0 +1,36 @@
+package v1
+
+import "time"
+
+type RequestDeletionRequest struct {
+	// Password 用 /v1/security/public_key 加密后的 base64 字符串
+	Password string `json:"password" binding:"required"`
+}
+
+type DeletionStatusData struct {
+	// Scheduled 是否已申请注销
+	Scheduled bool `json:"scheduled"`
+	// ScheduledAt 清除个人信息的时间，之前可以撤销
+	ScheduledAt *time.Time `json:"scheduledAt"`
+}
+
+type DeletionStatusResponse struct {
+	Response
+	Data DeletionStatusData
+}
+
+type DataExportData struct {
+	Id uint `json:"id"`
+	// Status pending/processing/ready/failed/expired
+	Status      string     `json:"status"`
+	Size        int64      `json:"size"`
+	CreateAt    time.Time  `json:"createAt"`
+	CompletedAt *time.Time `json:"completedAt"`
+	// ExpiresAt 超过后需要重新申请导出
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+type DataExportResponse struct {
+	Response
+	Data DataExportData
+}
@@ -0,0 +1,94 @@
+package v1
+
+import (
+	"time"
+
+	"go-chat/internal/model"
+)
+
+type AdminListUsersRequest struct {
+	// Keyword 模糊匹配用户名、邮箱、手机号
+	Keyword  string `form:"keyword" example:"alan"`
+	Identity string `form:"identity" example:"moderator"`
+	Status   string `form:"status" binding:"omitempty,oneof=active disabled deleted" example:"active"`
+	Page     int    `form:"page" example:"1"`
+	PageSize int    `form:"pageSize" example:"20"`
+}
+
+// AdminUserData 后台展示的用户资料，不包含密码和盐
+type AdminUserData struct {
+	Id            uint       `json:"id"`
+	Name          string     `json:"name"`
+	Email         string     `json:"email"`
+	Phone         string     `json:"phone"`
+	Avatar        string     `json:"avatar"`
+	Gender        string     `json:"gender"`
+	Motto         string     `json:"motto"`
+	Identity      string     `json:"identity"`
+	ClientIp      string     `json:"clientIp"`
+	DeviceInfo    string     `json:"deviceInfo"`
+	LoginTime     *time.Time `json:"loginTime"`
+	LoginOutTime  *time.Time `json:"loginOutTime"`
+	HeartBeatTime *time.Time `json:"heartBeatTime"`
+	IsLoginOut    int8       `json:"isLoginOut"`
+	DisabledAt    *time.Time `json:"disabledAt"`
+	CreateAt      time.Time  `json:"createAt"`
+	DeletedAt     *time.Time `json:"deletedAt"`
+}
+
+func NewAdminUserData(user *model.UserBasics) *AdminUserData {
+	data := &AdminUserData{
+		Id:            user.ID,
+		Name:          user.Name,
+		Email:         user.Email,
+		Phone:         user.Phone,
+		Avatar:        user.Avatar,
+		Gender:        user.Gender,
+		Motto:         user.Motto,
+		Identity:      user.Identity,
+		ClientIp:      user.ClientIp,
+		DeviceInfo:    user.DeviceInfo,
+		LoginTime:     user.LoginTime,
+		LoginOutTime:  user.LoginOutTime,
+		HeartBeatTime: user.HeartBeatTime,
+		IsLoginOut:    user.IsLoginOut,
+		DisabledAt:    user.DisabledAt,
+		CreateAt:      user.CreateAt,
+	}
+	if user.DeleteAt.Valid {
+		data.DeletedAt = &user.DeleteAt.Time
+	}
+	return data
+}
+
+type AdminListUsersResponseData struct {
+	List  []*AdminUserData `json:"list"`
+	Total int64            `json:"total"`
+}
+
+type AdminListUsersResponse struct {
+	Response
+	Data AdminListUsersResponseData
+}
+
+type AdminGetUserResponseData struct {
+	User *AdminUserData `json:"user"`
+	// Roles 额外分配的角色，主角色见 User.Identity
+	Roles        []string                 `json:"roles"`
+	Restrictions []*model.UserRestriction `json:"restrictions"`
+}
+
+type AdminGetUserResponse struct {
+	Response
+	Data AdminGetUserResponseData
+}
+
+type AdminResetPasswordResponseData struct {
+	// Password 临时密码，只返回这一次
+	Password string `json:"password"`
+}
+
+type AdminResetPasswordResponse struct {
+	Response
+	Data AdminResetPasswordResponseData
+}
@@ -0,0 +1,47 @@
+package v1
+
+import (
+	"time"
+
+	"go-chat/internal/model"
+)
+
+type ListAuditLogsRequest struct {
+	ActorId    uint   `form:"actorId" example:"1"`
+	TargetType string `form:"targetType" binding:"omitempty,oneof=user role report" example:"user"`
+	TargetId   string `form:"targetId" example:"1"`
+	Action     string `form:"action" example:"auth.login_failed"`
+	// Since/Until RFC3339 格式
+	Since    *time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until    *time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+	Page     int        `form:"page" example:"1"`
+	PageSize int        `form:"pageSize" example:"20"`
+}
+
+type ListAuditLogsResponseData struct {
+	List  []*model.AuditLog `json:"list"`
+	Total int64             `json:"total"`
+}
+
+type ListAuditLogsResponse struct {
+	Response
+	Data ListAuditLogsResponseData
+}
+
+type SecurityActivityRequest struct {
+	Limit int `form:"limit" example:"20"`
+}
+
+// SecurityActivityData 用户自己可见的安全记录，不包含操作人等后台信息
+type SecurityActivityData struct {
+	Action     string    `json:"action"`
+	Ip         string    `json:"ip"`
+	UserAgent  string    `json:"userAgent"`
+	DeviceInfo string    `json:"deviceInfo"`
+	CreateAt   time.Time `json:"createAt"`
+}
+
+type SecurityActivityResponse struct {
+	Response
+	Data []*SecurityActivityData
+}
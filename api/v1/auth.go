@@ -0,0 +1,17 @@
+package v1
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+type TokenPair struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	// ExpiresIn access token 的有效期（秒）
+	ExpiresIn int64 `json:"expiresIn"`
+}
+
+type RefreshTokenResponse struct {
+	Response
+	Data TokenPair
+}
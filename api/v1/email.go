@@ -0,0 +1,21 @@
+package v1
+
+import "go-chat/internal/model"
+
+type EmailQueueStatusRequest struct {
+	Status   string `form:"status" binding:"omitempty,oneof=pending sending sent failed" example:"failed"`
+	Page     int    `form:"page" example:"1"`
+	PageSize int    `form:"pageSize" example:"20"`
+}
+
+type EmailQueueStatusResponseData struct {
+	// Counts 各状态的邮件数量
+	Counts map[string]int64      `json:"counts"`
+	List   []*model.EmailMessage `json:"list"`
+	Total  int64                 `json:"total"`
+}
+
+type EmailQueueStatusResponse struct {
+	Response
+	Data EmailQueueStatusResponseData
+}
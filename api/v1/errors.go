@@ -5,7 +5,9 @@ var (
 	ErrSuccess             = newError(0, "ok")
 	ErrBadRequest          = newError(400, "Bad Request")
 	ErrUnauthorized        = newError(401, "Unauthorized")
+	ErrForbidden           = newError(403, "Forbidden")
 	ErrNotFound            = newError(404, "Not Found")
+	ErrTooManyRequests     = newError(429, "Too Many Requests")
 	ErrInternalServerError = newError(500, "Internal Server Error")
 	ErrFileUploadError     = newError(600, "File upload failed.")
 
@@ -18,4 +20,46 @@ var (
 	ErrUserEmailNotFound    = newError(1006, "The email is not found.")
 	ErrSendEmailFailed      = newError(1007, "Send email failed.")
 	ErrEmailCodeError       = newError(1008, "The email code is incorrect.")
+	ErrContentBlocked       = newError(1009, "The content contains prohibited words.")
+	ErrUserBanned           = newError(1010, "The account has been banned.")
+	ErrUserMuted            = newError(1011, "The account has been muted.")
+	ErrUserDisabled         = newError(1012, "The account has been disabled.")
+	ErrRefreshTokenInvalid  = newError(1013, "The refresh token is invalid or expired.")
+	ErrRefreshTokenReused   = newError(1014, "The refresh token has already been used, please log in again.")
+	ErrSessionNotFound      = newError(1015, "Session not found.")
+	ErrDecryptFailed        = newError(1016, "Unable to decrypt the data, fetch the latest public key and try again.")
+	ErrSendSmsFailed        = newError(1017, "Send sms failed.")
+	ErrSmsCodeError         = newError(1018, "The sms code is incorrect.")
+	ErrPhoneAlreadyUse      = newError(1019, "The phone number is already in use.")
+	ErrInvalidPhone         = newError(1020, "The phone number is invalid.")
+	ErrVerifyCodeCooldown   = newError(1021, "A code was sent recently, please wait a minute before requesting another.")
+	ErrVerifyCodeLocked     = newError(1022, "Too many incorrect codes, please try again later.")
+	ErrEmailChangeNeedsCode = newError(1023, "Verify the new email with a code before changing it.")
+
+	// two-factor errors
+	ErrTwoFactorCodeError        = newError(1101, "The two-factor code is incorrect.")
+	ErrTwoFactorChallengeInvalid = newError(1102, "The two-factor challenge is invalid or expired, please log in again.")
+	ErrTwoFactorAlreadyEnabled   = newError(1103, "Two-factor authentication is already enabled.")
+	ErrTwoFactorNotEnabled       = newError(1104, "Two-factor authentication is not enabled.")
+
+	// oidc errors
+	ErrOIDCProviderNotFound = newError(1201, "The identity provider is not configured.")
+	ErrOIDCStateInvalid     = newError(1202, "The login request is invalid or expired, please try again.")
+	ErrOIDCLoginFailed      = newError(1203, "Unable to sign in with the identity provider.")
+
+	// account errors
+	ErrDeletionNotScheduled = newError(1301, "The account is not scheduled for deletion.")
+	ErrDataExportNotFound   = newError(1302, "Data export not found.")
+	ErrDataExportNotReady   = newError(1303, "The data export is not ready yet.")
+
+	// report errors
+	ErrReportNotFound       = newError(2001, "Report not found.")
+	ErrReportAlreadyClaimed = newError(2002, "The report is claimed by another moderator.")
+	ErrReportNotClaimed     = newError(2003, "Claim the report before resolving it.")
+	ErrReportResolved       = newError(2004, "The report is already resolved.")
+
+	// rbac errors
+	ErrRoleNotFound       = newError(2101, "Role not found.")
+	ErrRoleAlreadyExists  = newError(2102, "The role already exists.")
+	ErrPermissionNotFound = newError(2103, "Permission not found.")
 )
@@ -0,0 +1,20 @@
+package v1
+
+import "encoding/json"
+
+type EventData struct {
+	Id        string          `json:"id"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt int64           `json:"createdAt"`
+}
+
+type PollEventsResponseData struct {
+	Events      []*EventData `json:"events"`
+	LastEventId string       `json:"lastEventId"`
+}
+
+type PollEventsResponse struct {
+	Response
+	Data PollEventsResponseData
+}
@@ -0,0 +1,27 @@
+package v1
+
+type OIDCProviderData struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type ListOIDCProvidersResponse struct {
+	Response
+	Data []*OIDCProviderData
+}
+
+type OIDCAuthorizeData struct {
+	// AuthorizationUrl 前端跳转到该地址，提供方登录后带着 code 和 state 回到 redirect_url
+	AuthorizationUrl string `json:"authorizationUrl"`
+	State            string `json:"state"`
+}
+
+type OIDCAuthorizeResponse struct {
+	Response
+	Data OIDCAuthorizeData
+}
+
+type OIDCCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
@@ -0,0 +1,15 @@
+package v1
+
+type CreateRoleRequest struct {
+	Name        string   `json:"name" binding:"required,max=64" example:"support"`
+	Description string   `json:"description" example:"客服"`
+	Permissions []string `json:"permissions" example:"users.read"`
+}
+
+type SetRolePermissionsRequest struct {
+	Permissions []string `json:"permissions" example:"users.read"`
+}
+
+type AssignUserRoleRequest struct {
+	Role string `json:"role" binding:"required" example:"moderator"`
+}
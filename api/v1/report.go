@@ -0,0 +1,43 @@
+package v1
+
+import "go-chat/internal/model"
+
+type CreateReportRequest struct {
+	TargetType string `json:"targetType" binding:"required,oneof=user message group" example:"user"`
+	TargetId   string `json:"targetId" binding:"required" example:"1"`
+	Reason     string `json:"reason" binding:"required,oneof=spam harassment inappropriate fraud other" example:"spam"`
+	Detail     string `json:"detail" binding:"max=500"`
+}
+
+type ListReportsRequest struct {
+	Status   string `form:"status" binding:"omitempty,oneof=pending claimed resolved" example:"pending"`
+	Page     int    `form:"page" example:"1"`
+	PageSize int    `form:"pageSize" example:"20"`
+}
+
+type ListReportsResponseData struct {
+	List  []*model.Report `json:"list"`
+	Total int64           `json:"total"`
+}
+
+type ListReportsResponse struct {
+	Response
+	Data ListReportsResponseData
+}
+
+type GetReportResponseData struct {
+	Report  *model.Report             `json:"report"`
+	Actions []*model.ModerationAction `json:"actions"`
+}
+
+type GetReportResponse struct {
+	Response
+	Data GetReportResponseData
+}
+
+type ResolveReportRequest struct {
+	Action string `json:"action" binding:"required,oneof=dismiss warn mute ban delete" example:"mute"`
+	Note   string `json:"note" binding:"max=500"`
+	// Duration mute/ban 的时长（小时），0 表示永久
+	Duration int `json:"duration" binding:"min=0" example:"24"`
+}
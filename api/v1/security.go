@@ -0,0 +1,14 @@
+package v1
+
+type PublicKeyResponseData struct {
+	KeyId string `json:"keyId"`
+	// PublicKey PEM 格式（PKIX）的 RSA 公钥
+	PublicKey string `json:"publicKey"`
+	// Schemes 支持的填充方式，密文格式为 <scheme>:<keyId>:<base64>，oaep 使用 SHA-256
+	Schemes []string `json:"schemes" example:"oaep,pkcs1"`
+}
+
+type PublicKeyResponse struct {
+	Response
+	Data PublicKeyResponseData
+}
@@ -0,0 +1,34 @@
+package v1
+
+import "time"
+
+type SessionData struct {
+	SessionId  string    `json:"sessionId"`
+	Name       string    `json:"name"`
+	DeviceInfo string    `json:"deviceInfo"`
+	ClientIp   string    `json:"clientIp"`
+	UserAgent  string    `json:"userAgent"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	CreateAt   time.Time `json:"createAt"`
+	// Current 是否为发起请求的会话
+	Current bool `json:"current"`
+}
+
+type ListSessionsResponse struct {
+	Response
+	Data []*SessionData
+}
+
+type RenameSessionRequest struct {
+	Name string `json:"name" binding:"required,max=64" example:"My iPhone"`
+}
+
+type RevokeOtherSessionsResponseData struct {
+	// Revoked 被注销的会话数
+	Revoked int `json:"revoked"`
+}
+
+type RevokeOtherSessionsResponse struct {
+	Response
+	Data RevokeOtherSessionsResponseData
+}
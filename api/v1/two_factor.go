@@ -0,0 +1,43 @@
+package v1
+
+type TwoFactorStatusData struct {
+	Enabled bool `json:"enabled"`
+	// RecoveryCodesLeft 剩余可用的恢复码数量
+	RecoveryCodesLeft int64 `json:"recoveryCodesLeft"`
+}
+
+type TwoFactorStatusResponse struct {
+	Response
+	Data TwoFactorStatusData
+}
+
+type TwoFactorEnrollData struct {
+	Secret string `json:"secret"`
+	// ProvisioningUri otpauth:// 链接，渲染为二维码供身份验证器扫描
+	ProvisioningUri string `json:"provisioningUri"`
+}
+
+type TwoFactorEnrollResponse struct {
+	Response
+	Data TwoFactorEnrollData
+}
+
+type TwoFactorCodeRequest struct {
+	// Code 身份验证器中的 6 位验证码，关闭或重新生成恢复码时也可以使用恢复码
+	Code string `json:"code" binding:"required" example:"123456"`
+}
+
+type RecoveryCodesData struct {
+	// Codes 只在生成时返回一次
+	Codes []string `json:"codes"`
+}
+
+type RecoveryCodesResponse struct {
+	Response
+	Data RecoveryCodesData
+}
+
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Code           string `json:"code" binding:"required" example:"123456"`
+}
@@ -1,6 +1,6 @@
 package v1
 
-import "go-chat/internal/model"
+import "time"
 
 type RegisterRequest struct {
 	Name       string `json:"name" binding:"required"`
@@ -18,14 +18,17 @@ type CheckRegisterEmailCodeRequest struct {
 
 type RegisterResponse struct {
 	Response
-	Token string            `json:"token"`
-	User  *model.UserBasics `json:"user"`
+	Token        string       `json:"token"`
+	RefreshToken string       `json:"refreshToken"`
+	ExpiresIn    int64        `json:"expiresIn"`
+	User         *UserProfile `json:"user"`
 }
 
+// UpdateUserInfoRequest 手机号、邮箱需要验证码，分别通过 /user/phone、/user/email 修改，
+// Email 只能为空或当前邮箱
 type UpdateUserInfoRequest struct {
 	UserName   string `json:"userName"`
-	Email      string `json:"email" binding:"required,email"`
-	Phone      string `json:"phone"`
+	Email      string `json:"email" binding:"omitempty,email"`
 	Avatar     string `json:"avatar"`
 	Motto      string `json:"motto"`
 	ClientIp   string `json:"clientIp"`
@@ -38,8 +41,13 @@ type LoginRequest struct {
 }
 
 type LoginResponseData struct {
-	Token    string            `json:"token"`
-	UserInfo *model.UserBasics `json:"user"`
+	Token        string       `json:"token"`
+	RefreshToken string       `json:"refreshToken"`
+	ExpiresIn    int64        `json:"expiresIn"`
+	UserInfo     *UserProfile `json:"user"`
+	// TwoFactorRequired 为 true 时只返回 ChallengeToken，提交 /auth/2fa/verify 后才签发 token
+	TwoFactorRequired bool   `json:"twoFactorRequired"`
+	ChallengeToken    string `json:"challengeToken,omitempty"`
 }
 
 type EmailLoginCheckRequest struct {
@@ -51,14 +59,53 @@ type EmailLoginRequest struct {
 	Email string `json:"email" binding:"required,email" example:"1234@gmail.com"`
 }
 
+type SmsLoginRequest struct {
+	Phone string `json:"phone" binding:"required" example:"13800138000"`
+}
+
+type SmsLoginCheckRequest struct {
+	Phone string `json:"phone" binding:"required" example:"13800138000"`
+	Code  string `json:"code" binding:"required" example:"123456"`
+}
+
+type SendPhoneCodeRequest struct {
+	Phone string `json:"phone" binding:"required" example:"13800138000"`
+}
+
+type VerifyPhoneRequest struct {
+	Phone string `json:"phone" binding:"required" example:"13800138000"`
+	Code  string `json:"code" binding:"required" example:"123456"`
+}
+
+type SendEmailCodeRequest struct {
+	Email string `json:"email" binding:"required,email" example:"1234@gmail.com"`
+}
+
+type VerifyEmailRequest struct {
+	Email string `json:"email" binding:"required,email" example:"1234@gmail.com"`
+	Code  string `json:"code" binding:"required" example:"123456"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email" example:"1234@gmail.com"`
+}
+
+type ResetPasswordRequest struct {
+	Email string `json:"email" binding:"required,email" example:"1234@gmail.com"`
+	Code  string `json:"code" binding:"required" example:"123456"`
+	// Password/RePassword 与登录一样使用 RSA 公钥加密
+	Password   string `json:"password" binding:"required"`
+	RePassword string `json:"rePassword" binding:"required"`
+}
+
 type LoginResponse struct {
 	Response
 	Data LoginResponseData
 }
 
+// UpdateProfileRequest 邮箱需要验证码，通过 /user/email 修改
 type UpdateProfileRequest struct {
 	Nickname string `json:"nickname" example:"alan"`
-	Email    string `json:"email" binding:"required,email" example:"1234@gmail.com"`
 }
 type GetProfileResponseData struct {
 	UserId   string `json:"userId"`
@@ -68,3 +115,65 @@ type GetProfileResponse struct {
 	Response
 	Data GetProfileResponseData
 }
+
+// UserProfile 用户本人的资料，不包含密码等内部字段
+type UserProfile struct {
+	Id            uint      `json:"id"`
+	Name          string    `json:"name"`
+	Avatar        string    `json:"avatar"`
+	Gender        string    `json:"gender"`
+	Phone         string    `json:"phone"`
+	PhoneVerified bool      `json:"phoneVerified"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"emailVerified"`
+	Motto         string    `json:"motto"`
+	Identity      string    `json:"identity"`
+	CreateAt      time.Time `json:"createAt"`
+}
+
+// PublicProfile 其他用户可见的资料
+type PublicProfile struct {
+	Id     uint   `json:"id"`
+	Name   string `json:"name"`
+	Avatar string `json:"avatar"`
+	Gender string `json:"gender"`
+	Motto  string `json:"motto"`
+}
+
+// SearchUsersRequest email、phone、name 三选一：email 和 phone 完整匹配，name 按前缀匹配
+type SearchUsersRequest struct {
+	Email    string `form:"email" binding:"omitempty,email" example:"1234@gmail.com"`
+	Phone    string `form:"phone" example:"13800138000"`
+	Name     string `form:"name" binding:"omitempty,min=2,max=64" example:"al"`
+	Page     int    `form:"page" example:"1"`
+	PageSize int    `form:"pageSize" example:"20"`
+}
+
+type SearchUsersResponseData struct {
+	List  []*PublicProfile `json:"list"`
+	Total int64            `json:"total"`
+}
+
+type SearchUsersResponse struct {
+	Response
+	Data SearchUsersResponseData
+}
+
+// DiscoverabilityData 其他用户能否通过搜索找到自己
+type DiscoverabilityData struct {
+	ByName  bool `json:"byName"`
+	ByEmail bool `json:"byEmail"`
+	ByPhone bool `json:"byPhone"`
+}
+
+type DiscoverabilityResponse struct {
+	Response
+	Data DiscoverabilityData
+}
+
+// UpdateDiscoverabilityRequest 为空的字段不修改
+type UpdateDiscoverabilityRequest struct {
+	ByName  *bool `json:"byName" example:"true"`
+	ByEmail *bool `json:"byEmail" example:"false"`
+	ByPhone *bool `json:"byPhone" example:"false"`
+}
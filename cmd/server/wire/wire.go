@@ -11,46 +11,93 @@ import (
 	"go-chat/internal/server"
 	"go-chat/internal/service"
 	"go-chat/pkg/app"
+	"go-chat/pkg/event"
 	"go-chat/pkg/jwt"
 	"go-chat/pkg/log"
+	"go-chat/pkg/mailer"
+	"go-chat/pkg/moderation"
+	"go-chat/pkg/oidc"
+	"go-chat/pkg/password"
+	"go-chat/pkg/ratelimit"
+	"go-chat/pkg/rsakey"
+	"go-chat/pkg/server/grpc"
 	"go-chat/pkg/server/http"
 	"go-chat/pkg/sid"
+	"go-chat/pkg/sms"
+	"go-chat/pkg/verifycode"
 )
 
 var repositorySet = wire.NewSet(
 	repository.NewDB,
-	//repository.NewRedis,
+	repository.NewRedis,
 	repository.NewRepository,
 	repository.NewTransaction,
 	repository.NewUserRepository,
+	repository.NewModerationRepository,
+	repository.NewReportRepository,
+	repository.NewRBACRepository,
+	repository.NewTokenRepository,
+	repository.NewAuditRepository,
+	repository.NewSessionRepository,
+	repository.NewTwoFactorRepository,
+	repository.NewIdentityRepository,
 	repository.NewEmailRepository,
+	repository.NewAccountRepository,
 )
 
 var serviceSet = wire.NewSet(
 	service.NewService,
 	service.NewUserService,
 	service.NewEmailService,
+	service.NewModerationService,
+	service.NewReportService,
+	service.NewRBACService,
+	service.NewTokenService,
+	service.NewAdminUserService,
+	service.NewAuditService,
+	service.NewSessionService,
+	service.NewTwoFactorService,
+	service.NewOIDCService,
+	service.NewSmsService,
+	service.NewEmailQueueService,
+	service.NewAccountService,
 )
 
 var handlerSet = wire.NewSet(
 	handler.NewHandler,
 	handler.NewUserHandler,
 	handler.NewUploadHandler,
+	handler.NewEventHandler,
+	handler.NewReportHandler,
+	handler.NewRBACHandler,
+	handler.NewAdminUserHandler,
+	handler.NewAuditHandler,
+	handler.NewAuthHandler,
+	handler.NewSessionHandler,
+	handler.NewSecurityHandler,
+	handler.NewTwoFactorHandler,
+	handler.NewOIDCHandler,
+	handler.NewEmailQueueHandler,
+	handler.NewAccountHandler,
+	handler.NewUserGrpcHandler,
+	handler.NewChatGrpcHandler,
 )
 
 var serverSet = wire.NewSet(
 	server.NewHTTPServer,
+	server.NewGRPCServer,
 	server.NewJob,
 )
 
 // build App
 func newApp(
 	httpServer *http.Server,
+	grpcServer *grpc.Server,
 	job *server.Job,
 	// task *server.Task,
 ) *app.App {
 	return app.NewApp(
-		app.WithServer(httpServer, job),
+		app.WithServer(httpServer, grpcServer, job),
 		app.WithName("demo-server"),
 	)
 }
@@ -63,6 +110,15 @@ func NewWire(*viper.Viper, *log.Logger) (*app.App, func(), error) {
 		serverSet,
 		sid.NewSid,
 		jwt.NewJwt,
+		password.NewManager,
+		rsakey.NewProvider,
+		oidc.NewRegistry,
+		sms.NewSender,
+		mailer.NewMailer,
+		verifycode.NewManager,
+		ratelimit.NewLimiter,
+		moderation.NewFilter,
+		event.NewHub,
 		newApp,
 	))
 }
@@ -14,51 +14,122 @@ import (
 	"go-chat/internal/server"
 	"go-chat/internal/service"
 	"go-chat/pkg/app"
+	"go-chat/pkg/event"
 	"go-chat/pkg/jwt"
 	"go-chat/pkg/log"
+	"go-chat/pkg/mailer"
+	"go-chat/pkg/moderation"
+	"go-chat/pkg/oidc"
+	"go-chat/pkg/password"
+	"go-chat/pkg/ratelimit"
+	"go-chat/pkg/rsakey"
+	"go-chat/pkg/server/grpc"
 	"go-chat/pkg/server/http"
 	"go-chat/pkg/sid"
+	"go-chat/pkg/sms"
+	"go-chat/pkg/verifycode"
 )
 
 // Injectors from wire.go:
 
 func NewWire(viperViper *viper.Viper, logger *log.Logger) (*app.App, func(), error) {
 	jwtJWT := jwt.NewJwt(viperViper)
+	manager := password.NewManager(viperViper)
+	provider, err := rsakey.NewProvider(viperViper)
+	if err != nil {
+		return nil, nil, err
+	}
+	redis := repository.NewRedis(viperViper)
+	limiter := ratelimit.NewLimiter(viperViper, redis)
 	handlerHandler := handler.NewHandler(logger)
 	db := repository.NewDB(viperViper, logger)
-	redis := repository.NewRedis(viperViper)
 	repositoryRepository := repository.NewRepository(logger, db, redis)
 	transaction := repository.NewTransaction(repositoryRepository)
 	sidSid := sid.NewSid()
 	serviceService := service.NewService(transaction, logger, sidSid, jwtJWT)
 	userRepository := repository.NewUserRepository(repositoryRepository)
+	verifycodeManager := verifycode.NewManager(viperViper, redis)
+	mailerMailer, err := mailer.NewMailer(viperViper)
+	if err != nil {
+		return nil, nil, err
+	}
 	emailRepository := repository.NewEmailRepository(repositoryRepository)
-	emailService := service.NewEmailService(serviceService, emailRepository)
-	userService := service.NewUserService(serviceService, emailService, userRepository)
+	emailQueueService := service.NewEmailQueueService(serviceService, viperViper, mailerMailer, emailRepository)
+	emailService := service.NewEmailService(serviceService, verifycodeManager, emailQueueService)
+	filter, err := moderation.NewFilter(viperViper, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	moderationRepository := repository.NewModerationRepository(repositoryRepository)
+	moderationService := service.NewModerationService(serviceService, filter, moderationRepository)
+	hub := event.NewHub()
+	auditRepository := repository.NewAuditRepository(repositoryRepository)
+	auditService := service.NewAuditService(serviceService, auditRepository, userRepository)
+	reportRepository := repository.NewReportRepository(repositoryRepository)
+	reportService := service.NewReportService(serviceService, viperViper, hub, auditService, reportRepository, userRepository)
+	tokenRepository := repository.NewTokenRepository(repositoryRepository)
+	sessionRepository := repository.NewSessionRepository(repositoryRepository)
+	tokenService := service.NewTokenService(serviceService, viperViper, hub, auditService, reportService, tokenRepository, sessionRepository, userRepository)
+	twoFactorRepository := repository.NewTwoFactorRepository(repositoryRepository)
+	twoFactorService := service.NewTwoFactorService(serviceService, viperViper, auditService, twoFactorRepository, userRepository)
+	sender, err := sms.NewSender(viperViper, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	smsService := service.NewSmsService(serviceService, viperViper, sender, limiter, verifycodeManager)
+	userService := service.NewUserService(serviceService, emailService, smsService, moderationService, reportService, auditService, tokenService, twoFactorService, manager, provider, userRepository)
 	userHandler := handler.NewUserHandler(handlerHandler, userService)
 	uploadHandler := handler.NewUploadHandler(handlerHandler)
-	httpServer := server.NewHTTPServer(logger, viperViper, jwtJWT, userHandler, uploadHandler)
-	job := server.NewJob(logger)
-	appApp := newApp(httpServer, job)
+	eventHandler := handler.NewEventHandler(handlerHandler, hub)
+	reportHandler := handler.NewReportHandler(handlerHandler, reportService)
+	rbacRepository := repository.NewRBACRepository(repositoryRepository)
+	rbacService := service.NewRBACService(serviceService, auditService, rbacRepository, userRepository)
+	rbacHandler := handler.NewRBACHandler(handlerHandler, rbacService)
+	adminUserService := service.NewAdminUserService(serviceService, tokenService, auditService, manager, userRepository, rbacRepository, reportRepository)
+	adminUserHandler := handler.NewAdminUserHandler(handlerHandler, adminUserService)
+	auditHandler := handler.NewAuditHandler(handlerHandler, auditService)
+	authHandler := handler.NewAuthHandler(handlerHandler, tokenService)
+	sessionService := service.NewSessionService(serviceService, tokenService, auditService, sessionRepository, userRepository)
+	sessionHandler := handler.NewSessionHandler(handlerHandler, sessionService)
+	securityHandler := handler.NewSecurityHandler(handlerHandler, provider)
+	twoFactorHandler := handler.NewTwoFactorHandler(handlerHandler, twoFactorService, userService)
+	registry, err := oidc.NewRegistry(viperViper)
+	if err != nil {
+		return nil, nil, err
+	}
+	identityRepository := repository.NewIdentityRepository(repositoryRepository)
+	oidcService := service.NewOIDCService(serviceService, registry, userService, moderationService, auditService, manager, identityRepository, userRepository)
+	oidcHandler := handler.NewOIDCHandler(handlerHandler, oidcService)
+	emailQueueHandler := handler.NewEmailQueueHandler(handlerHandler, emailQueueService)
+	accountRepository := repository.NewAccountRepository(repositoryRepository)
+	accountService := service.NewAccountService(serviceService, viperViper, emailService, tokenService, auditService, manager, provider, accountRepository, userRepository, twoFactorRepository, rbacRepository)
+	accountHandler := handler.NewAccountHandler(handlerHandler, accountService)
+	httpServer := server.NewHTTPServer(logger, viperViper, jwtJWT, limiter, userHandler, uploadHandler, eventHandler, reportHandler, rbacHandler, adminUserHandler, auditHandler, authHandler, sessionHandler, securityHandler, twoFactorHandler, oidcHandler, emailQueueHandler, accountHandler, rbacService, tokenService)
+	userGrpcHandler := handler.NewUserGrpcHandler(handlerHandler, userService, tokenService)
+	chatGrpcHandler := handler.NewChatGrpcHandler(handlerHandler, hub)
+	grpcServer := server.NewGRPCServer(logger, viperViper, jwtJWT, tokenService, userGrpcHandler, chatGrpcHandler)
+	job := server.NewJob(logger, hub, emailQueueService, accountService)
+	appApp := newApp(httpServer, grpcServer, job)
 	return appApp, func() {
 	}, nil
 }
 
 // wire.go:
 
-var repositorySet = wire.NewSet(repository.NewDB, repository.NewRepository, repository.NewTransaction, repository.NewUserRepository, repository.NewEmailRepository)
+var repositorySet = wire.NewSet(repository.NewDB, repository.NewRedis, repository.NewRepository, repository.NewTransaction, repository.NewUserRepository, repository.NewModerationRepository, repository.NewReportRepository, repository.NewRBACRepository, repository.NewTokenRepository, repository.NewAuditRepository, repository.NewSessionRepository, repository.NewTwoFactorRepository, repository.NewIdentityRepository, repository.NewEmailRepository, repository.NewAccountRepository)
 
-var serviceSet = wire.NewSet(service.NewService, service.NewUserService, service.NewEmailService)
+var serviceSet = wire.NewSet(service.NewService, service.NewUserService, service.NewEmailService, service.NewModerationService, service.NewReportService, service.NewRBACService, service.NewTokenService, service.NewAdminUserService, service.NewAuditService, service.NewSessionService, service.NewTwoFactorService, service.NewOIDCService, service.NewSmsService, service.NewEmailQueueService, service.NewAccountService)
 
-var handlerSet = wire.NewSet(handler.NewHandler, handler.NewUserHandler)
+var handlerSet = wire.NewSet(handler.NewHandler, handler.NewUserHandler, handler.NewUploadHandler, handler.NewEventHandler, handler.NewReportHandler, handler.NewRBACHandler, handler.NewAdminUserHandler, handler.NewAuditHandler, handler.NewAuthHandler, handler.NewSessionHandler, handler.NewSecurityHandler, handler.NewTwoFactorHandler, handler.NewOIDCHandler, handler.NewEmailQueueHandler, handler.NewAccountHandler, handler.NewUserGrpcHandler, handler.NewChatGrpcHandler)
 
-var serverSet = wire.NewSet(server.NewHTTPServer, server.NewJob)
+var serverSet = wire.NewSet(server.NewHTTPServer, server.NewGRPCServer, server.NewJob)
 
 // build App
 func newApp(
 	httpServer *http.Server,
+	grpcServer *grpc.Server,
 	job *server.Job,
 
 ) *app.App {
-	return app.NewApp(app.WithServer(httpServer, job), app.WithName("demo-server"))
+	return app.NewApp(app.WithServer(httpServer, grpcServer, job), app.WithName("demo-server"))
 }
@@ -3,4 +3,10 @@ package global
 var (
 	Register = "register"
 	Login    = "login"
+	// ResetPassword 找回密码
+	ResetPassword = "reset_password"
+	// BindPhone 绑定或更换手机号
+	BindPhone = "bind_phone"
+	// BindEmail 更换邮箱
+	BindEmail = "bind_email"
 )
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"net/http"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin"
+	v1 "go-chat/api/v1"
+	"go-chat/internal/service"
+)
+
+type AccountHandler struct {
+	*Handler
+	accountService service.AccountService
+}
+
+func NewAccountHandler(handler *Handler, accountService service.AccountService) *AccountHandler {
+	return &AccountHandler{
+		Handler:        handler,
+		accountService: accountService,
+	}
+}
+
+// DeletionStatus godoc
+// @Summary 注销状态
+// @Schemes
+// @Description 是否已申请注销，以及清除个人信息的时间
+// @Tags 用户模块
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} v1.DeletionStatusResponse
+// @Router /user/account/deletion [get]
+func (h *AccountHandler) DeletionStatus(ctx *gin.Context) {
+	data, err := h.accountService.DeletionStatus(ctx, GetUserIdFromCtx(ctx))
+	if err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, data)
+}
+
+// RequestDeletion godoc
+// @Summary 申请注销账号
+// @Schemes
+// @Description 校验密码后申请注销，宽限期内可以撤销，之后账号的个人信息被永久清除
+// @Tags 用户模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body v1.RequestDeletionRequest true "params"
+// @Success 200 {object} v1.DeletionStatusResponse
+// @Router /user/account/deletion [post]
+func (h *AccountHandler) RequestDeletion(ctx *gin.Context) {
+	var req v1.RequestDeletionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	data, err := h.accountService.RequestDeletion(ctx, GetUserIdFromCtx(ctx), &req)
+	if err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, data)
+}
+
+// CancelDeletion godoc
+// @Summary 撤销注销
+// @Schemes
+// @Description 宽限期内撤销注销申请
+// @Tags 用户模块
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} v1.Response
+// @Router /user/account/deletion [delete]
+func (h *AccountHandler) CancelDeletion(ctx *gin.Context) {
+	if err := h.accountService.CancelDeletion(ctx, GetUserIdFromCtx(ctx)); err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, true)
+}
+
+// RequestExport godoc
+// @Summary 申请导出个人数据
+// @Schemes
+// @Description 后台生成包含账号资料、会话、安全记录等数据的 zip 文件，完成后邮件通知
+// @Tags 用户模块
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} v1.DataExportResponse
+// @Router /user/data_export [post]
+func (h *AccountHandler) RequestExport(ctx *gin.Context) {
+	data, err := h.accountService.RequestExport(ctx, GetUserIdFromCtx(ctx))
+	if err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, data)
+}
+
+// LatestExport godoc
+// @Summary 数据导出状态
+// @Schemes
+// @Description 最近一次数据导出的状态
+// @Tags 用户模块
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} v1.DataExportResponse
+// @Router /user/data_export [get]
+func (h *AccountHandler) LatestExport(ctx *gin.Context) {
+	data, err := h.accountService.LatestExport(ctx, GetUserIdFromCtx(ctx))
+	if err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, data)
+}
+
+// DownloadExport godoc
+// @Summary 下载导出的数据
+// @Schemes
+// @Description 下载最近一次已生成且未过期的数据导出
+// @Tags 用户模块
+// @Produce application/zip
+// @Security Bearer
+// @Success 200 {file} file
+// @Router /user/data_export/download [get]
+func (h *AccountHandler) DownloadExport(ctx *gin.Context) {
+	path, err := h.accountService.ExportFile(ctx, GetUserIdFromCtx(ctx))
+	if err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	ctx.FileAttachment(path, "go-chat-export"+filepath.Ext(path))
+}
@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	v1 "go-chat/api/v1"
+	"go-chat/internal/service"
+)
+
+type AdminUserHandler struct {
+	*Handler
+	adminUserService service.AdminUserService
+}
+
+func NewAdminUserHandler(handler *Handler, adminUserService service.AdminUserService) *AdminUserHandler {
+	return &AdminUserHandler{
+		Handler:          handler,
+		adminUserService: adminUserService,
+	}
+}
+
+// ListUsers godoc
+// @Summary 用户列表
+// @Schemes
+// @Description 按关键字、身份、账号状态筛选用户，status=deleted 查询已注销用户
+// @Tags 后台用户管理
+// @Produce json
+// @Security Bearer
+// @Param keyword query string false "用户名、邮箱、手机号"
+// @Param identity query string false "身份"
+// @Param status query string false "active/disabled/deleted"
+// @Param page query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Success 200 {object} v1.AdminListUsersResponse
+// @Router /admin/users [get]
+func (h *AdminUserHandler) ListUsers(ctx *gin.Context) {
+	var req v1.AdminListUsersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	data, err := h.adminUserService.ListUsers(ctx, &req)
+	if err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, data)
+}
+
+// GetUser godoc
+// @Summary 用户详情
+// @Schemes
+// @Description 包含额外分配的角色和生效中的处罚
+// @Tags 后台用户管理
+// @Produce json
+// @Security Bearer
+// @Param id path int true "用户ID"
+// @Success 200 {object} v1.AdminGetUserResponse
+// @Router /admin/users/{id} [get]
+func (h *AdminUserHandler) GetUser(ctx *gin.Context) {
+	id, ok := userIdParam(ctx)
+	if !ok {
+		return
+	}
+
+	data, err := h.adminUserService.GetUser(ctx, id)
+	if err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, data)
+}
+
+// ForceLogout godoc
+// @Summary 强制下线
+// @Schemes
+// @Description 使用户所有已签发的 token 失效
+// @Tags 后台用户管理
+// @Produce json
+// @Security Bearer
+// @Param id path int true "用户ID"
+// @Success 200 {object} v1.Response
+// @Router /admin/users/{id}/logout [post]
+func (h *AdminUserHandler) ForceLogout(ctx *gin.Context) {
+	id, ok := userIdParam(ctx)
+	if !ok {
+		return
+	}
+
+	if err := h.adminUserService.ForceLogout(ctx, GetUserIdFromCtx(ctx), id); err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, true)
+}
+
+// DisableUser godoc
+// @Summary 禁用账号
+// @Schemes
+// @Description 禁用后无法登录，已登录的设备立即下线
+// @Tags 后台用户管理
+// @Produce json
+// @Security Bearer
+// @Param id path int true "用户ID"
+// @Success 200 {object} v1.Response
+// @Router /admin/users/{id}/disable [post]
+func (h *AdminUserHandler) DisableUser(ctx *gin.Context) {
+	id, ok := userIdParam(ctx)
+	if !ok {
+		return
+	}
+
+	if err := h.adminUserService.DisableUser(ctx, GetUserIdFromCtx(ctx), id); err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, true)
+}
+
+// EnableUser godoc
+// @Summary 启用账号
+// @Schemes
+// @Description
+// @Tags 后台用户管理
+// @Produce json
+// @Security Bearer
+// @Param id path int true "用户ID"
+// @Success 200 {object} v1.Response
+// @Router /admin/users/{id}/enable [post]
+func (h *AdminUserHandler) EnableUser(ctx *gin.Context) {
+	id, ok := userIdParam(ctx)
+	if !ok {
+		return
+	}
+
+	if err := h.adminUserService.EnableUser(ctx, GetUserIdFromCtx(ctx), id); err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, true)
+}
+
+// ResetPassword godoc
+// @Summary 重置密码
+// @Schemes
+// @Description 生成临时密码并返回，用户所有设备下线
+// @Tags 后台用户管理
+// @Produce json
+// @Security Bearer
+// @Param id path int true "用户ID"
+// @Success 200 {object} v1.AdminResetPasswordResponse
+// @Router /admin/users/{id}/reset_password [post]
+func (h *AdminUserHandler) ResetPassword(ctx *gin.Context) {
+	id, ok := userIdParam(ctx)
+	if !ok {
+		return
+	}
+
+	data, err := h.adminUserService.ResetPassword(ctx, GetUserIdFromCtx(ctx), id)
+	if err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, data)
+}
+
+// DeleteUser godoc
+// @Summary 注销账号
+// @Schemes
+// @Description 软删除，可通过 restore 恢复
+// @Tags 后台用户管理
+// @Produce json
+// @Security Bearer
+// @Param id path int true "用户ID"
+// @Success 200 {object} v1.Response
+// @Router /admin/users/{id} [delete]
+func (h *AdminUserHandler) DeleteUser(ctx *gin.Context) {
+	id, ok := userIdParam(ctx)
+	if !ok {
+		return
+	}
+
+	if err := h.adminUserService.DeleteUser(ctx, GetUserIdFromCtx(ctx), id); err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, true)
+}
+
+// RestoreUser godoc
+// @Summary 恢复账号
+// @Schemes
+// @Description 用户名或邮箱已被他人使用时无法恢复
+// @Tags 后台用户管理
+// @Produce json
+// @Security Bearer
+// @Param id path int true "用户ID"
+// @Success 200 {object} v1.Response
+// @Router /admin/users/{id}/restore [post]
+func (h *AdminUserHandler) RestoreUser(ctx *gin.Context) {
+	id, ok := userIdParam(ctx)
+	if !ok {
+		return
+	}
+
+	if err := h.adminUserService.RestoreUser(ctx, GetUserIdFromCtx(ctx), id); err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, true)
+}
+
+func userIdParam(ctx *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	v1 "go-chat/api/v1"
+	"go-chat/internal/service"
+)
+
+type AuditHandler struct {
+	*Handler
+	auditService service.AuditService
+}
+
+func NewAuditHandler(handler *Handler, auditService service.AuditService) *AuditHandler {
+	return &AuditHandler{
+		Handler:      handler,
+		auditService: auditService,
+	}
+}
+
+// ListAuditLogs godoc
+// @Summary 审计日志
+// @Schemes
+// @Description 按操作人、对象、动作和时间范围筛选，时间为 RFC3339 格式
+// @Tags 审计模块
+// @Produce json
+// @Security Bearer
+// @Param actorId query int false "操作人ID"
+// @Param targetType query string false "user/role/report"
+// @Param targetId query string false "对象ID"
+// @Param action query string false "动作"
+// @Param since query string false "开始时间"
+// @Param until query string false "结束时间"
+// @Param page query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Success 200 {object} v1.ListAuditLogsResponse
+// @Router /admin/audit_logs [get]
+func (h *AuditHandler) ListAuditLogs(ctx *gin.Context) {
+	var req v1.ListAuditLogsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	data, err := h.auditService.ListAuditLogs(ctx, &req)
+	if err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, data)
+}
+
+// SecurityActivity godoc
+// @Summary 最近安全活动
+// @Schemes
+// @Description 当前用户最近的登录、密码和邮箱修改以及管理员对账号的操作
+// @Tags 用户模块
+// @Produce json
+// @Security Bearer
+// @Param limit query int false "数量，默认20"
+// @Success 200 {object} v1.SecurityActivityResponse
+// @Router /user/security_activity [get]
+func (h *AuditHandler) SecurityActivity(ctx *gin.Context) {
+	var req v1.SecurityActivityRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	data, err := h.auditService.ListSecurityActivity(ctx, GetUserIdFromCtx(ctx), &req)
+	if err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, data)
+}
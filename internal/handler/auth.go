@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	v1 "go-chat/api/v1"
+	"go-chat/internal/service"
+)
+
+type AuthHandler struct {
+	*Handler
+	tokenService service.TokenService
+}
+
+func NewAuthHandler(handler *Handler, tokenService service.TokenService) *AuthHandler {
+	return &AuthHandler{
+		Handler:      handler,
+		tokenService: tokenService,
+	}
+}
+
+// Refresh godoc
+// @Summary 刷新 token
+// @Schemes
+// @Description 用 refresh token 换取新的 access token 和 refresh token，旧 refresh token 立即失效；旧 token 被重复使用时该次登录签发的所有 token 都会失效
+// @Tags 认证模块
+// @Accept json
+// @Produce json
+// @Param request body v1.RefreshTokenRequest true "params"
+// @Success 200 {object} v1.RefreshTokenResponse
+// @Router /auth/refresh [post]
+func (h *AuthHandler) Refresh(ctx *gin.Context) {
+	var req v1.RefreshTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	tokens, err := h.tokenService.Refresh(ctx, req.RefreshToken)
+	if err != nil {
+		switch err {
+		case v1.ErrRefreshTokenInvalid, v1.ErrRefreshTokenReused:
+			v1.HandleError(ctx, http.StatusUnauthorized, err, nil)
+		case v1.ErrUserBanned:
+			v1.HandleError(ctx, http.StatusForbidden, err, nil)
+		default:
+			v1.HandleError(ctx, http.StatusInternalServerError, v1.ErrInternalServerError, nil)
+		}
+		return
+	}
+	v1.HandleSuccess(ctx, tokens)
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"io"
+	"time"
+
+	pb "go-chat/api/proto/v1"
+	"go-chat/pkg/event"
+	"go-chat/pkg/jwt"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ChatGrpcHandler gRPC 长连接，与 WebSocket 网关推送相同的用户事件
+type ChatGrpcHandler struct {
+	pb.UnimplementedChatServiceServer
+	*Handler
+	hub *event.Hub
+}
+
+func NewChatGrpcHandler(handler *Handler, hub *event.Hub) *ChatGrpcHandler {
+	return &ChatGrpcHandler{
+		Handler: handler,
+		hub:     hub,
+	}
+}
+
+func (h *ChatGrpcHandler) Connect(stream pb.ChatService_ConnectServer) error {
+	ctx := stream.Context()
+	claims, ok := jwt.FromContext(ctx)
+	if !ok || claims.Uid() == 0 {
+		return status.Error(codes.Unauthenticated, "Unauthorized")
+	}
+
+	sub, _ := h.hub.SubscribeSession(claims.Uid(), claims.SessionId, "")
+	defer sub.Close()
+
+	// 读取客户端帧，出错或客户端关闭时结束连接
+	recvErr := make(chan error, 1)
+	pongs := make(chan *pb.Pong, 1)
+	go func() {
+		for {
+			frame, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			if ping := frame.GetPing(); ping != nil {
+				select {
+				case pongs <- &pb.Pong{Timestamp: time.Now().UnixMilli()}:
+				default:
+				}
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-recvErr:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case pong := <-pongs:
+			if err := stream.Send(&pb.ServerFrame{Frame: &pb.ServerFrame_Pong{Pong: pong}}); err != nil {
+				return err
+			}
+		case e, ok := <-sub.C:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(&pb.ServerFrame{Frame: &pb.ServerFrame_Event{Event: &pb.Event{
+				Id:        e.ID,
+				Type:      e.Type,
+				Payload:   e.Payload,
+				CreatedAt: e.CreatedAt.UnixMilli(),
+			}}}); err != nil {
+				h.logger.WithContext(ctx).Warn("grpc send event failed", zap.Error(err))
+				return err
+			}
+		}
+	}
+}
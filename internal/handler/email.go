@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	v1 "go-chat/api/v1"
+	"go-chat/internal/service"
+)
+
+type EmailQueueHandler struct {
+	*Handler
+	emailQueueService service.EmailQueueService
+}
+
+func NewEmailQueueHandler(handler *Handler, emailQueueService service.EmailQueueService) *EmailQueueHandler {
+	return &EmailQueueHandler{
+		Handler:           handler,
+		emailQueueService: emailQueueService,
+	}
+}
+
+// QueueStatus godoc
+// @Summary 邮件队列
+// @Schemes
+// @Description 各状态的邮件数量，以及按状态分页的邮件列表和最近一次失败原因
+// @Tags 邮件模块
+// @Produce json
+// @Security Bearer
+// @Param status query string false "pending/sending/sent/failed"
+// @Param page query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Success 200 {object} v1.EmailQueueStatusResponse
+// @Router /admin/email_queue [get]
+func (h *EmailQueueHandler) QueueStatus(ctx *gin.Context) {
+	var req v1.EmailQueueStatusRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	data, err := h.emailQueueService.QueueStatus(ctx, &req)
+	if err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, data)
+}
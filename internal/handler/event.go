@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	v1 "go-chat/api/v1"
+	"go-chat/pkg/event"
+)
+
+const (
+	// sseHeartbeat 心跳间隔，防止代理因空闲断开连接
+	sseHeartbeat = 25 * time.Second
+	// pollTimeout 长轮询默认等待时间
+	pollTimeout = 25 * time.Second
+	// pollMaxTimeout 长轮询最大等待时间
+	pollMaxTimeout = 60 * time.Second
+)
+
+// EventHandler WebSocket 不可用时的实时事件降级通道（SSE、长轮询）
+type EventHandler struct {
+	*Handler
+	hub *event.Hub
+}
+
+func NewEventHandler(handler *Handler, hub *event.Hub) *EventHandler {
+	return &EventHandler{
+		Handler: handler,
+		hub:     hub,
+	}
+}
+
+// Stream godoc
+// @Summary SSE 实时事件
+// @Schemes
+// @Description 以 Server-Sent Events 推送当前用户的实时事件，断线重连时通过 Last-Event-ID 补发
+// @Tags 实时事件模块
+// @Produce text/event-stream
+// @Security Bearer
+// @Param Last-Event-ID header string false "最后收到的事件ID"
+// @Success 200
+// @Router /events/stream [get]
+func (h *EventHandler) Stream(ctx *gin.Context) {
+	userId := GetUidFromCtx(ctx)
+	if userId == 0 {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	sub, backlog := h.hub.SubscribeSession(userId, GetSessionIdFromCtx(ctx), lastEventId(ctx))
+	defer sub.Close()
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	// 关闭 nginx 缓冲
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+
+	for _, e := range backlog {
+		writeSSEvent(ctx.Writer, e)
+	}
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Request.Context().Done():
+			return false
+		case <-heartbeat.C:
+			_, err := fmt.Fprint(w, ": ping\n\n")
+			return err == nil
+		case e, ok := <-sub.C:
+			if !ok {
+				return false
+			}
+			return writeSSEvent(w, e) == nil
+		}
+	})
+}
+
+// Poll godoc
+// @Summary 长轮询实时事件
+// @Schemes
+// @Description 返回 Last-Event-ID 之后的事件，没有新事件时最多等待 timeout 秒
+// @Tags 实时事件模块
+// @Produce json
+// @Security Bearer
+// @Param Last-Event-ID header string false "最后收到的事件ID"
+// @Param timeout query int false "等待秒数，默认25，最大60"
+// @Success 200 {object} v1.PollEventsResponse
+// @Router /events/poll [get]
+func (h *EventHandler) Poll(ctx *gin.Context) {
+	userId := GetUidFromCtx(ctx)
+	if userId == 0 {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	timeout := pollTimeout
+	if s := ctx.Query("timeout"); s != "" {
+		seconds, err := strconv.Atoi(s)
+		if err != nil || seconds < 0 {
+			v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+			return
+		}
+		timeout = time.Duration(seconds) * time.Second
+		if timeout > pollMaxTimeout {
+			timeout = pollMaxTimeout
+		}
+	}
+
+	lastId := lastEventId(ctx)
+	sub, events := h.hub.SubscribeSession(userId, GetSessionIdFromCtx(ctx), lastId)
+	defer sub.Close()
+	if lastId == "" {
+		// 首次轮询没有起点，返回当前最新的事件ID，下次轮询从这里补发
+		lastId = h.hub.Head()
+	}
+
+	if len(events) == 0 && timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case <-timer.C:
+		case e, ok := <-sub.C:
+			if ok {
+				events = append(events, e)
+			}
+		}
+	}
+	// 顺带取走已经到达的事件
+	for len(sub.C) > 0 {
+		events = append(events, <-sub.C)
+	}
+
+	data := v1.PollEventsResponseData{Events: make([]*v1.EventData, 0, len(events)), LastEventId: lastId}
+	for _, e := range events {
+		data.Events = append(data.Events, eventToData(e))
+		data.LastEventId = e.ID
+	}
+	v1.HandleSuccess(ctx, data)
+}
+
+// lastEventId 浏览器 EventSource 重连时自动携带 Last-Event-ID 请求头，其他客户端可用 query 参数
+func lastEventId(ctx *gin.Context) string {
+	if id := ctx.GetHeader("Last-Event-ID"); id != "" {
+		return id
+	}
+	return ctx.Query("lastEventId")
+}
+
+func writeSSEvent(w io.Writer, e *event.Event) error {
+	data, err := json.Marshal(eventToData(e))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
+
+func eventToData(e *event.Event) *v1.EventData {
+	payload := json.RawMessage(e.Payload)
+	if !json.Valid(payload) {
+		payload, _ = json.Marshal(string(e.Payload))
+	}
+	return &v1.EventData{
+		Id:        e.ID,
+		Type:      e.Type,
+		Payload:   payload,
+		CreatedAt: e.CreatedAt.UnixMilli(),
+	}
+}
@@ -1,7 +1,10 @@
 package handler
 
 import (
+	"net/http"
+
 	"github.com/gin-gonic/gin"
+	v1 "go-chat/api/v1"
 	"go-chat/pkg/jwt"
 	"go-chat/pkg/log"
 )
@@ -24,3 +27,49 @@ func GetUserIdFromCtx(ctx *gin.Context) string {
 	}
 	return v.(*jwt.MyCustomClaims).UserId
 }
+
+// GetUidFromCtx 返回 token 中的数字用户ID，没有 sub 时返回 0
+func GetUidFromCtx(ctx *gin.Context) uint {
+	v, exists := ctx.Get("claims")
+	if !exists {
+		return 0
+	}
+	return v.(*jwt.MyCustomClaims).Uid()
+}
+
+func GetSessionIdFromCtx(ctx *gin.Context) string {
+	v, exists := ctx.Get("claims")
+	if !exists {
+		return ""
+	}
+	return v.(*jwt.MyCustomClaims).SessionId
+}
+
+// errorStatus 业务错误对应的 HTTP 状态码，未列出的错误按 500 处理
+func errorStatus(err error) int {
+	switch err {
+	case v1.ErrForbidden:
+		return http.StatusForbidden
+	case v1.ErrUnauthorized:
+		return http.StatusUnauthorized
+	case v1.ErrBadRequest, v1.ErrTwoFactorCodeError, v1.ErrInvalidPhone, v1.ErrSmsCodeError,
+		v1.ErrEmailCodeError, v1.ErrDecryptFailed, v1.ErrContentBlocked, v1.ErrUserPasswordError,
+		v1.ErrEmailChangeNeedsCode:
+		return http.StatusBadRequest
+	case v1.ErrTooManyRequests, v1.ErrVerifyCodeCooldown, v1.ErrVerifyCodeLocked:
+		return http.StatusTooManyRequests
+	case v1.ErrTwoFactorChallengeInvalid:
+		return http.StatusUnauthorized
+	case v1.ErrUserDisabled, v1.ErrUserBanned:
+		return http.StatusForbidden
+	case v1.ErrReportNotFound, v1.ErrRoleNotFound, v1.ErrPermissionNotFound, v1.ErrUserNotFound, v1.ErrSessionNotFound,
+		v1.ErrDataExportNotFound:
+		return http.StatusNotFound
+	case v1.ErrReportAlreadyClaimed, v1.ErrReportNotClaimed, v1.ErrReportResolved, v1.ErrRoleAlreadyExists,
+		v1.ErrUserNameAlreadyUse, v1.ErrEmailAlreadyUse, v1.ErrTwoFactorAlreadyEnabled, v1.ErrTwoFactorNotEnabled,
+		v1.ErrPhoneAlreadyUse, v1.ErrDeletionNotScheduled, v1.ErrDataExportNotReady:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
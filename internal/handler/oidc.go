@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	v1 "go-chat/api/v1"
+	"go-chat/internal/service"
+)
+
+type OIDCHandler struct {
+	*Handler
+	oidcService service.OIDCService
+}
+
+func NewOIDCHandler(handler *Handler, oidcService service.OIDCService) *OIDCHandler {
+	return &OIDCHandler{
+		Handler:     handler,
+		oidcService: oidcService,
+	}
+}
+
+// Providers godoc
+// @Summary 第三方登录方式
+// @Schemes
+// @Description 已配置的 OpenID Connect 身份提供方
+// @Tags 认证模块
+// @Produce json
+// @Success 200 {object} v1.ListOIDCProvidersResponse
+// @Router /auth/oidc/providers [get]
+func (h *OIDCHandler) Providers(ctx *gin.Context) {
+	v1.HandleSuccess(ctx, h.oidcService.Providers(ctx))
+}
+
+// Authorize godoc
+// @Summary 发起第三方登录
+// @Schemes
+// @Description 返回提供方的授权地址（授权码 + PKCE），前端跳转过去，登录后提供方带着 code 和 state 回到配置的 redirect_url
+// @Tags 认证模块
+// @Produce json
+// @Param provider path string true "提供方名称"
+// @Success 200 {object} v1.OIDCAuthorizeResponse
+// @Router /auth/oidc/{provider}/authorize [post]
+func (h *OIDCHandler) Authorize(ctx *gin.Context) {
+	data, err := h.oidcService.Authorize(ctx, ctx.Param("provider"))
+	if err != nil {
+		v1.HandleError(ctx, oidcErrorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, data)
+}
+
+// Callback godoc
+// @Summary 完成第三方登录
+// @Schemes
+// @Description 提交回调中的 code 和 state，首次登录时按已验证的邮箱绑定已有账号或创建新账号；开启了二次验证时返回 challengeToken
+// @Tags 认证模块
+// @Accept json
+// @Produce json
+// @Param provider path string true "提供方名称"
+// @Param request body v1.OIDCCallbackRequest true "params"
+// @Success 200 {object} v1.LoginResponse
+// @Router /auth/oidc/{provider}/callback [post]
+func (h *OIDCHandler) Callback(ctx *gin.Context) {
+	var req v1.OIDCCallbackRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	data, err := h.oidcService.Callback(ctx, ctx.Param("provider"), &req)
+	if err != nil {
+		v1.HandleError(ctx, oidcErrorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, data)
+}
+
+func oidcErrorStatus(err error) int {
+	switch err {
+	case v1.ErrOIDCProviderNotFound:
+		return http.StatusNotFound
+	case v1.ErrOIDCStateInvalid:
+		return http.StatusBadRequest
+	case v1.ErrOIDCLoginFailed:
+		return http.StatusUnauthorized
+	default:
+		return errorStatus(err)
+	}
+}
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	v1 "go-chat/api/v1"
+	"go-chat/internal/service"
+)
+
+type RBACHandler struct {
+	*Handler
+	rbacService service.RBACService
+}
+
+func NewRBACHandler(handler *Handler, rbacService service.RBACService) *RBACHandler {
+	return &RBACHandler{
+		Handler:     handler,
+		rbacService: rbacService,
+	}
+}
+
+// ListRoles godoc
+// @Summary 角色列表
+// @Schemes
+// @Description 查看所有角色及其权限
+// @Tags 权限模块
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} v1.Response
+// @Router /admin/roles [get]
+func (h *RBACHandler) ListRoles(ctx *gin.Context) {
+	roles, err := h.rbacService.ListRoles(ctx)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, v1.ErrInternalServerError, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, roles)
+}
+
+// ListPermissions godoc
+// @Summary 权限列表
+// @Schemes
+// @Description
+// @Tags 权限模块
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} v1.Response
+// @Router /admin/permissions [get]
+func (h *RBACHandler) ListPermissions(ctx *gin.Context) {
+	permissions, err := h.rbacService.ListPermissions(ctx)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, v1.ErrInternalServerError, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, permissions)
+}
+
+// CreateRole godoc
+// @Summary 创建角色
+// @Schemes
+// @Description
+// @Tags 权限模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body v1.CreateRoleRequest true "params"
+// @Success 200 {object} v1.Response
+// @Router /admin/roles [post]
+func (h *RBACHandler) CreateRole(ctx *gin.Context) {
+	var req v1.CreateRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	role, err := h.rbacService.CreateRole(ctx, GetUserIdFromCtx(ctx), &req)
+	if err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, role)
+}
+
+// SetRolePermissions godoc
+// @Summary 设置角色权限
+// @Schemes
+// @Description 用请求中的权限覆盖角色现有权限
+// @Tags 权限模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "角色ID"
+// @Param request body v1.SetRolePermissionsRequest true "params"
+// @Success 200 {object} v1.Response
+// @Router /admin/roles/{id}/permissions [put]
+func (h *RBACHandler) SetRolePermissions(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+	var req v1.SetRolePermissionsRequest
+	if err = ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err = h.rbacService.SetRolePermissions(ctx, GetUserIdFromCtx(ctx), uint(id), &req); err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, true)
+}
+
+// AssignUserRole godoc
+// @Summary 分配角色
+// @Schemes
+// @Description 为用户额外分配角色，UserBasics.Identity 为用户的主角色
+// @Tags 权限模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "用户ID"
+// @Param request body v1.AssignUserRoleRequest true "params"
+// @Success 200 {object} v1.Response
+// @Router /admin/users/{id}/roles [post]
+func (h *RBACHandler) AssignUserRole(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+	var req v1.AssignUserRoleRequest
+	if err = ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err = h.rbacService.AssignUserRole(ctx, GetUserIdFromCtx(ctx), uint(id), req.Role); err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, true)
+}
+
+// RevokeUserRole godoc
+// @Summary 撤销角色
+// @Schemes
+// @Description
+// @Tags 权限模块
+// @Produce json
+// @Security Bearer
+// @Param id path int true "用户ID"
+// @Param role path string true "角色名"
+// @Success 200 {object} v1.Response
+// @Router /admin/users/{id}/roles/{role} [delete]
+func (h *RBACHandler) RevokeUserRole(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err = h.rbacService.RevokeUserRole(ctx, GetUserIdFromCtx(ctx), uint(id), ctx.Param("role")); err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, true)
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	v1 "go-chat/api/v1"
+	"go-chat/internal/service"
+)
+
+type ReportHandler struct {
+	*Handler
+	reportService service.ReportService
+}
+
+func NewReportHandler(handler *Handler, reportService service.ReportService) *ReportHandler {
+	return &ReportHandler{
+		Handler:       handler,
+		reportService: reportService,
+	}
+}
+
+// CreateReport godoc
+// @Summary 举报
+// @Schemes
+// @Description 举报用户、消息或群组
+// @Tags 举报模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body v1.CreateReportRequest true "params"
+// @Success 200 {object} v1.Response
+// @Router /report [post]
+func (h *ReportHandler) CreateReport(ctx *gin.Context) {
+	var req v1.CreateReportRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	report, err := h.reportService.CreateReport(ctx, GetUserIdFromCtx(ctx), &req)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	v1.HandleSuccess(ctx, report)
+}
+
+// ListReports godoc
+// @Summary 举报队列
+// @Schemes
+// @Description 管理员按状态分页查看举报
+// @Tags 举报模块
+// @Produce json
+// @Security Bearer
+// @Param status query string false "pending/claimed/resolved"
+// @Param page query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Success 200 {object} v1.ListReportsResponse
+// @Router /admin/reports [get]
+func (h *ReportHandler) ListReports(ctx *gin.Context) {
+	var req v1.ListReportsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	data, err := h.reportService.ListReports(ctx, GetUserIdFromCtx(ctx), &req)
+	if err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+
+	v1.HandleSuccess(ctx, data)
+}
+
+// GetReport godoc
+// @Summary 举报详情
+// @Schemes
+// @Description 查看举报及其处理记录
+// @Tags 举报模块
+// @Produce json
+// @Security Bearer
+// @Param id path int true "举报ID"
+// @Success 200 {object} v1.GetReportResponse
+// @Router /admin/reports/{id} [get]
+func (h *ReportHandler) GetReport(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	data, err := h.reportService.GetReport(ctx, GetUserIdFromCtx(ctx), uint(id))
+	if err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+
+	v1.HandleSuccess(ctx, data)
+}
+
+// ClaimReport godoc
+// @Summary 认领举报
+// @Schemes
+// @Description 认领后其他管理员不能处理该举报
+// @Tags 举报模块
+// @Produce json
+// @Security Bearer
+// @Param id path int true "举报ID"
+// @Success 200 {object} v1.Response
+// @Router /admin/reports/{id}/claim [post]
+func (h *ReportHandler) ClaimReport(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err = h.reportService.ClaimReport(ctx, GetUserIdFromCtx(ctx), uint(id)); err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+
+	v1.HandleSuccess(ctx, true)
+}
+
+// ResolveReport godoc
+// @Summary 处理举报
+// @Schemes
+// @Description 处理已认领的举报：dismiss 驳回、warn 警告、mute 禁言、ban 封禁、delete 删除内容
+// @Tags 举报模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "举报ID"
+// @Param request body v1.ResolveReportRequest true "params"
+// @Success 200 {object} v1.Response
+// @Router /admin/reports/{id}/resolve [post]
+func (h *ReportHandler) ResolveReport(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+	var req v1.ResolveReportRequest
+	if err = ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err = h.reportService.ResolveReport(ctx, GetUserIdFromCtx(ctx), uint(id), &req); err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+
+	v1.HandleSuccess(ctx, true)
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	v1 "go-chat/api/v1"
+	"go-chat/pkg/rsakey"
+	"go.uber.org/zap"
+)
+
+type SecurityHandler struct {
+	*Handler
+	rsaKeys rsakey.Provider
+}
+
+func NewSecurityHandler(handler *Handler, rsaKeys rsakey.Provider) *SecurityHandler {
+	return &SecurityHandler{
+		Handler: handler,
+		rsaKeys: rsaKeys,
+	}
+}
+
+// PublicKey godoc
+// @Summary 获取加密公钥
+// @Schemes
+// @Description 客户端用当前公钥加密密码后提交，密文格式为 <scheme>:<keyId>:<base64>，推荐使用 oaep
+// @Tags 安全模块
+// @Produce json
+// @Success 200 {object} v1.PublicKeyResponse
+// @Router /security/public_key [get]
+func (h *SecurityHandler) PublicKey(ctx *gin.Context) {
+	key := h.rsaKeys.Current()
+	publicKey, err := key.PublicKeyPEM()
+	if err != nil {
+		h.logger.WithContext(ctx).Error("marshal public key error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusInternalServerError, v1.ErrInternalServerError, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, v1.PublicKeyResponseData{
+		KeyId:     key.ID,
+		PublicKey: publicKey,
+		Schemes:   []string{rsakey.SchemeOAEP, rsakey.SchemePKCS1v15},
+	})
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	v1 "go-chat/api/v1"
+	"go-chat/internal/service"
+)
+
+type SessionHandler struct {
+	*Handler
+	sessionService service.SessionService
+}
+
+func NewSessionHandler(handler *Handler, sessionService service.SessionService) *SessionHandler {
+	return &SessionHandler{
+		Handler:        handler,
+		sessionService: sessionService,
+	}
+}
+
+// ListSessions godoc
+// @Summary 登录设备列表
+// @Schemes
+// @Description 当前用户未注销的登录会话，current 标记发起请求的设备
+// @Tags 用户模块
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} v1.ListSessionsResponse
+// @Router /user/sessions [get]
+func (h *SessionHandler) ListSessions(ctx *gin.Context) {
+	data, err := h.sessionService.ListSessions(ctx, GetUserIdFromCtx(ctx), GetSessionIdFromCtx(ctx))
+	if err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, data)
+}
+
+// RenameSession godoc
+// @Summary 重命名登录设备
+// @Schemes
+// @Description
+// @Tags 用户模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "会话ID"
+// @Param request body v1.RenameSessionRequest true "params"
+// @Success 200 {object} v1.Response
+// @Router /user/sessions/{id} [put]
+func (h *SessionHandler) RenameSession(ctx *gin.Context) {
+	var req v1.RenameSessionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err := h.sessionService.RenameSession(ctx, GetUserIdFromCtx(ctx), ctx.Param("id"), &req); err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, nil)
+}
+
+// RevokeSession godoc
+// @Summary 注销登录设备
+// @Schemes
+// @Description 立即使该设备的 token 失效并断开其实时连接
+// @Tags 用户模块
+// @Produce json
+// @Security Bearer
+// @Param id path string true "会话ID"
+// @Success 200 {object} v1.Response
+// @Router /user/sessions/{id} [delete]
+func (h *SessionHandler) RevokeSession(ctx *gin.Context) {
+	if err := h.sessionService.RevokeSession(ctx, GetUserIdFromCtx(ctx), ctx.Param("id")); err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, nil)
+}
+
+// RevokeOtherSessions godoc
+// @Summary 注销其他设备
+// @Schemes
+// @Description 注销除当前设备外的所有登录会话
+// @Tags 用户模块
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} v1.RevokeOtherSessionsResponse
+// @Router /user/sessions/revoke_others [post]
+func (h *SessionHandler) RevokeOtherSessions(ctx *gin.Context) {
+	revoked, err := h.sessionService.RevokeOtherSessions(ctx, GetUserIdFromCtx(ctx), GetSessionIdFromCtx(ctx))
+	if err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, v1.RevokeOtherSessionsResponseData{Revoked: revoked})
+}
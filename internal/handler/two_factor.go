@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	v1 "go-chat/api/v1"
+	"go-chat/internal/service"
+)
+
+type TwoFactorHandler struct {
+	*Handler
+	twoFactorService service.TwoFactorService
+	userService      service.UserService
+}
+
+func NewTwoFactorHandler(handler *Handler, twoFactorService service.TwoFactorService, userService service.UserService) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		Handler:          handler,
+		twoFactorService: twoFactorService,
+		userService:      userService,
+	}
+}
+
+// Verify godoc
+// @Summary 二次验证登录
+// @Schemes
+// @Description 登录返回 twoFactorRequired 时，提交 challengeToken 和身份验证器验证码（或恢复码）完成登录
+// @Tags 认证模块
+// @Accept json
+// @Produce json
+// @Param request body v1.TwoFactorVerifyRequest true "params"
+// @Success 200 {object} v1.LoginResponse
+// @Router /auth/2fa/verify [post]
+func (h *TwoFactorHandler) Verify(ctx *gin.Context) {
+	var req v1.TwoFactorVerifyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	data, err := h.userService.VerifyTwoFactor(ctx, &req)
+	if err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, data)
+}
+
+// Status godoc
+// @Summary 二次验证状态
+// @Schemes
+// @Description
+// @Tags 用户模块
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} v1.TwoFactorStatusResponse
+// @Router /user/2fa [get]
+func (h *TwoFactorHandler) Status(ctx *gin.Context) {
+	data, err := h.twoFactorService.Status(ctx, GetUserIdFromCtx(ctx))
+	if err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, data)
+}
+
+// Enroll godoc
+// @Summary 绑定身份验证器
+// @Schemes
+// @Description 生成 TOTP 密钥和 otpauth 链接，调用 confirm 提交第一个验证码后生效；重复调用会替换未确认的密钥
+// @Tags 用户模块
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} v1.TwoFactorEnrollResponse
+// @Router /user/2fa/enroll [post]
+func (h *TwoFactorHandler) Enroll(ctx *gin.Context) {
+	data, err := h.twoFactorService.Enroll(ctx, GetUserIdFromCtx(ctx))
+	if err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, data)
+}
+
+// Confirm godoc
+// @Summary 开启二次验证
+// @Schemes
+// @Description 提交身份验证器中的验证码确认绑定，返回只展示一次的恢复码
+// @Tags 用户模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body v1.TwoFactorCodeRequest true "params"
+// @Success 200 {object} v1.RecoveryCodesResponse
+// @Router /user/2fa/confirm [post]
+func (h *TwoFactorHandler) Confirm(ctx *gin.Context) {
+	var req v1.TwoFactorCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	data, err := h.twoFactorService.Confirm(ctx, GetUserIdFromCtx(ctx), req.Code)
+	if err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, data)
+}
+
+// Disable godoc
+// @Summary 关闭二次验证
+// @Schemes
+// @Description 需要提交验证码或恢复码
+// @Tags 用户模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body v1.TwoFactorCodeRequest true "params"
+// @Success 200 {object} v1.Response
+// @Router /user/2fa/disable [post]
+func (h *TwoFactorHandler) Disable(ctx *gin.Context) {
+	var req v1.TwoFactorCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err := h.twoFactorService.Disable(ctx, GetUserIdFromCtx(ctx), req.Code); err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, nil)
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary 重新生成恢复码
+// @Schemes
+// @Description 需要提交验证码或恢复码，旧的恢复码全部失效
+// @Tags 用户模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body v1.TwoFactorCodeRequest true "params"
+// @Success 200 {object} v1.RecoveryCodesResponse
+// @Router /user/2fa/recovery_codes [post]
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(ctx *gin.Context) {
+	var req v1.TwoFactorCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	data, err := h.twoFactorService.RegenerateRecoveryCodes(ctx, GetUserIdFromCtx(ctx), req.Code)
+	if err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, data)
+}
@@ -45,7 +45,7 @@ func (h *UserHandler) Register(ctx *gin.Context) {
 	}
 
 	if err := h.userService.Register(ctx, &req); err != nil {
-		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		v1.HandleError(ctx, errorStatus(err), err, nil)
 		return
 	}
 
@@ -74,7 +74,7 @@ func (h *UserHandler) CheckRegisterEmailCode(ctx *gin.Context) {
 	// 验证验证码是否正确
 	res, err := h.userService.CreateNewUser(ctx, &req)
 	if err != nil {
-		v1.HandleError(ctx, http.StatusInternalServerError, v1.ErrInternalServerError, nil)
+		v1.HandleError(ctx, errorStatus(err), err, nil)
 		return
 	}
 
@@ -82,11 +82,10 @@ func (h *UserHandler) CheckRegisterEmailCode(ctx *gin.Context) {
 	v1.HandleSuccess(ctx, res)
 }
 
-// UserInfoUpdate 更新用户信息
+// UserInfoUpdate 更新当前登录用户的信息
 func (h *UserHandler) UserInfoUpdate(ctx *gin.Context) {
 	var req v1.UpdateUserInfoRequest
-	// 从header中取出token和id
-	token := ctx.GetHeader("Authorization")
+	// 旧客户端会在 header 中携带 userId，只允许是当前登录用户本人
 	userId, _ := strconv.Atoi(ctx.GetHeader("userId"))
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -94,8 +93,9 @@ func (h *UserHandler) UserInfoUpdate(ctx *gin.Context) {
 		return
 	}
 
-	if err := h.userService.UpdateUserInfo(ctx, token, userId, &req); err != nil {
-		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+	if err := h.userService.UpdateUserInfo(ctx, GetUserIdFromCtx(ctx), uint(userId), &req); err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
 	}
 
 	v1.HandleSuccess(ctx, nil)
@@ -109,17 +109,14 @@ func (h *UserHandler) Login(ctx *gin.Context) {
 		return
 	}
 
-	// 返回token和userInfo
-	accessToken, userInfo, err := h.userService.Login(ctx, &req)
+	// 返回token和userInfo，开启二次验证时只返回 challenge token
+	data, err := h.userService.Login(ctx, &req)
 	if err != nil {
 		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
 		return
 	}
 
-	v1.HandleSuccess(ctx, v1.LoginResponseData{
-		Token:    accessToken,
-		UserInfo: userInfo,
-	})
+	v1.HandleSuccess(ctx, data)
 
 }
 
@@ -131,16 +128,13 @@ func (h *UserHandler) EmailLoginCodeCheck(ctx *gin.Context) {
 		return
 	}
 
-	token, user, err := h.userService.EmailLoginCodeCheck(ctx, req.Email, req.Code)
+	data, err := h.userService.EmailLoginCodeCheck(ctx, req.Email, req.Code)
 	if err != nil {
-		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		v1.HandleError(ctx, errorStatus(err), err, nil)
 		return
 	}
 
-	v1.HandleSuccess(ctx, &v1.LoginResponseData{
-		Token:    token,
-		UserInfo: user,
-	})
+	v1.HandleSuccess(ctx, data)
 
 }
 
@@ -157,12 +151,217 @@ func (h *UserHandler) EmailLogin(ctx *gin.Context) {
 	// 邮箱验证码登录
 	if err := h.userService.SendEmail(ctx, req.Email); err != nil {
 		h.logger.WithContext(ctx).Error("邮件发送失败")
-		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+
+	v1.HandleSuccess(ctx, true)
+
+}
+
+// SmsLogin godoc
+// @Summary 发送短信登录验证码
+// @Schemes
+// @Description 只向验证过的手机号发送，号码未绑定时同样返回成功；同一号码和同一 IP 有发送频率限制
+// @Tags 用户模块
+// @Accept json
+// @Produce json
+// @Param request body v1.SmsLoginRequest true "params"
+// @Success 200 {object} v1.Response
+// @Router /user/sms_login [post]
+func (h *UserHandler) SmsLogin(ctx *gin.Context) {
+	var req v1.SmsLoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err := h.userService.SendSmsLoginCode(ctx, req.Phone); err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+
+	v1.HandleSuccess(ctx, true)
+}
+
+// SmsLoginCodeCheck godoc
+// @Summary 短信验证码登录
+// @Schemes
+// @Description 开启了二次验证时返回 challengeToken
+// @Tags 用户模块
+// @Accept json
+// @Produce json
+// @Param request body v1.SmsLoginCheckRequest true "params"
+// @Success 200 {object} v1.LoginResponse
+// @Router /user/sms_login_code_check [post]
+func (h *UserHandler) SmsLoginCodeCheck(ctx *gin.Context) {
+	var req v1.SmsLoginCheckRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	data, err := h.userService.SmsLoginCodeCheck(ctx, req.Phone, req.Code)
+	if err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+
+	v1.HandleSuccess(ctx, data)
+}
+
+// SendPhoneCode godoc
+// @Summary 发送手机号绑定验证码
+// @Schemes
+// @Description 绑定或更换手机号，验证码发送到新号码
+// @Tags 用户模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body v1.SendPhoneCodeRequest true "params"
+// @Success 200 {object} v1.Response
+// @Router /user/phone/send_code [post]
+func (h *UserHandler) SendPhoneCode(ctx *gin.Context) {
+	var req v1.SendPhoneCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err := h.userService.SendPhoneCode(ctx, GetUserIdFromCtx(ctx), req.Phone); err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+
+	v1.HandleSuccess(ctx, true)
+}
+
+// VerifyPhone godoc
+// @Summary 绑定手机号
+// @Schemes
+// @Description 校验短信验证码后绑定手机号，绑定后可以使用短信登录
+// @Tags 用户模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body v1.VerifyPhoneRequest true "params"
+// @Success 200 {object} v1.Response
+// @Router /user/phone/verify [post]
+func (h *UserHandler) VerifyPhone(ctx *gin.Context) {
+	var req v1.VerifyPhoneRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err := h.userService.VerifyPhone(ctx, GetUserIdFromCtx(ctx), &req); err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+
+	v1.HandleSuccess(ctx, true)
+}
+
+// SendEmailCode godoc
+// @Summary 发送更换邮箱验证码
+// @Schemes
+// @Description 更换邮箱，验证码发送到新邮箱
+// @Tags 用户模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body v1.SendEmailCodeRequest true "params"
+// @Success 200 {object} v1.Response
+// @Router /user/email/send_code [post]
+func (h *UserHandler) SendEmailCode(ctx *gin.Context) {
+	var req v1.SendEmailCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err := h.userService.SendEmailCode(ctx, GetUserIdFromCtx(ctx), req.Email); err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+
+	v1.HandleSuccess(ctx, true)
+}
+
+// VerifyEmail godoc
+// @Summary 更换邮箱
+// @Schemes
+// @Description 校验邮箱验证码后更换邮箱，验证过的邮箱才能用于第三方登录绑定和按邮箱搜索
+// @Tags 用户模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body v1.VerifyEmailRequest true "params"
+// @Success 200 {object} v1.Response
+// @Router /user/email/verify [post]
+func (h *UserHandler) VerifyEmail(ctx *gin.Context) {
+	var req v1.VerifyEmailRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err := h.userService.VerifyEmail(ctx, GetUserIdFromCtx(ctx), &req); err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
 		return
 	}
 
 	v1.HandleSuccess(ctx, true)
+}
+
+// ForgotPassword godoc
+// @Summary 找回密码
+// @Schemes
+// @Description 向邮箱发送找回密码验证码，邮箱未注册时同样返回成功
+// @Tags 用户模块
+// @Accept json
+// @Produce json
+// @Param request body v1.ForgotPasswordRequest true "params"
+// @Success 200 {object} v1.Response
+// @Router /user/forgot_password [post]
+func (h *UserHandler) ForgotPassword(ctx *gin.Context) {
+	var req v1.ForgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err := h.userService.ForgotPassword(ctx, req.Email); err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
 
+	v1.HandleSuccess(ctx, true)
+}
+
+// ResetPassword godoc
+// @Summary 重置密码
+// @Schemes
+// @Description 使用找回密码验证码设置新密码，密码使用 RSA 公钥加密，成功后所有设备需要重新登录
+// @Tags 用户模块
+// @Accept json
+// @Produce json
+// @Param request body v1.ResetPasswordRequest true "params"
+// @Success 200 {object} v1.Response
+// @Router /user/reset_password [post]
+func (h *UserHandler) ResetPassword(ctx *gin.Context) {
+	var req v1.ResetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err := h.userService.ResetPassword(ctx, &req); err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+
+	v1.HandleSuccess(ctx, true)
 }
 
 // GetProfile godoc
@@ -218,3 +417,76 @@ func (h *UserHandler) UpdateProfile(ctx *gin.Context) {
 
 	v1.HandleSuccess(ctx, nil)
 }
+
+// SearchUsers godoc
+// @Summary 搜索用户
+// @Schemes
+// @Description email、phone、name 三选一：按完整邮箱、手机号或用户名前缀搜索，只返回允许被搜索到的用户的公开资料
+// @Tags 用户模块
+// @Produce json
+// @Security Bearer
+// @Param email query string false "完整邮箱"
+// @Param phone query string false "手机号"
+// @Param name query string false "用户名前缀，至少2个字符"
+// @Param page query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Success 200 {object} v1.SearchUsersResponse
+// @Router /user/search [get]
+func (h *UserHandler) SearchUsers(ctx *gin.Context) {
+	var req v1.SearchUsersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	data, err := h.userService.SearchUsers(ctx, GetUserIdFromCtx(ctx), &req)
+	if err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, data)
+}
+
+// Discoverability godoc
+// @Summary 可搜索设置
+// @Schemes
+// @Description 其他用户能否通过用户名、邮箱、手机号搜索到自己
+// @Tags 用户模块
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} v1.DiscoverabilityResponse
+// @Router /user/discoverability [get]
+func (h *UserHandler) Discoverability(ctx *gin.Context) {
+	data, err := h.userService.Discoverability(ctx, GetUserIdFromCtx(ctx))
+	if err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, data)
+}
+
+// UpdateDiscoverability godoc
+// @Summary 修改可搜索设置
+// @Schemes
+// @Description 按用户名搜索默认开启，按邮箱、手机号搜索默认关闭
+// @Tags 用户模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body v1.UpdateDiscoverabilityRequest true "params"
+// @Success 200 {object} v1.DiscoverabilityResponse
+// @Router /user/discoverability [put]
+func (h *UserHandler) UpdateDiscoverability(ctx *gin.Context) {
+	var req v1.UpdateDiscoverabilityRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	data, err := h.userService.UpdateDiscoverability(ctx, GetUserIdFromCtx(ctx), &req)
+	if err != nil {
+		v1.HandleError(ctx, errorStatus(err), err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, data)
+}
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"context"
+	"errors"
+
+	pb "go-chat/api/proto/v1"
+	v1 "go-chat/api/v1"
+	"go-chat/internal/service"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// UserGrpcHandler gRPC 版本的用户接口，复用 UserService
+type UserGrpcHandler struct {
+	pb.UnimplementedUserServiceServer
+	*Handler
+	userService  service.UserService
+	tokenService service.TokenService
+}
+
+func NewUserGrpcHandler(handler *Handler, userService service.UserService, tokenService service.TokenService) *UserGrpcHandler {
+	return &UserGrpcHandler{
+		Handler:      handler,
+		userService:  userService,
+		tokenService: tokenService,
+	}
+}
+
+func (h *UserGrpcHandler) Register(ctx context.Context, req *pb.RegisterRequest) (*emptypb.Empty, error) {
+	if req.Name == "" || req.Email == "" || req.Password == "" {
+		return nil, h.grpcError(ctx, v1.ErrBadRequest)
+	}
+
+	if err := h.userService.Register(ctx, &v1.RegisterRequest{
+		Name:       req.Name,
+		Email:      req.Email,
+		Password:   req.Password,
+		RePassword: req.RePassword,
+	}); err != nil {
+		return nil, h.grpcError(ctx, err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (h *UserGrpcHandler) CheckRegisterEmailCode(ctx context.Context, req *pb.CheckRegisterEmailCodeRequest) (*pb.LoginReply, error) {
+	res, err := h.userService.CreateNewUser(ctx, &v1.CheckRegisterEmailCodeRequest{
+		Name:     req.Name,
+		Email:    req.Email,
+		Password: req.Password,
+		Code:     req.Code,
+	})
+	if err != nil {
+		return nil, h.grpcError(ctx, err)
+	}
+	return &pb.LoginReply{Token: res.Token, RefreshToken: res.RefreshToken, ExpiresIn: res.ExpiresIn, User: userToPb(res.User)}, nil
+}
+
+func (h *UserGrpcHandler) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginReply, error) {
+	data, err := h.userService.Login(ctx, &v1.LoginRequest{
+		Name:     req.Name,
+		Password: req.Password,
+	})
+	if err != nil {
+		return nil, h.grpcError(ctx, err)
+	}
+	return loginReply(data), nil
+}
+
+func (h *UserGrpcHandler) EmailLogin(ctx context.Context, req *pb.EmailLoginRequest) (*emptypb.Empty, error) {
+	if req.Email == "" {
+		return nil, h.grpcError(ctx, v1.ErrBadRequest)
+	}
+	if err := h.userService.SendEmail(ctx, req.Email); err != nil {
+		return nil, h.grpcError(ctx, err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (h *UserGrpcHandler) EmailLoginCodeCheck(ctx context.Context, req *pb.EmailLoginCheckRequest) (*pb.LoginReply, error) {
+	data, err := h.userService.EmailLoginCodeCheck(ctx, req.Email, req.Code)
+	if err != nil {
+		return nil, h.grpcError(ctx, err)
+	}
+	return loginReply(data), nil
+}
+
+func (h *UserGrpcHandler) VerifyTwoFactor(ctx context.Context, req *pb.VerifyTwoFactorRequest) (*pb.LoginReply, error) {
+	if req.ChallengeToken == "" || req.Code == "" {
+		return nil, h.grpcError(ctx, v1.ErrBadRequest)
+	}
+	data, err := h.userService.VerifyTwoFactor(ctx, &v1.TwoFactorVerifyRequest{
+		ChallengeToken: req.ChallengeToken,
+		Code:           req.Code,
+	})
+	if err != nil {
+		return nil, h.grpcError(ctx, err)
+	}
+	return loginReply(data), nil
+}
+
+func (h *UserGrpcHandler) Refresh(ctx context.Context, req *pb.RefreshRequest) (*pb.TokenReply, error) {
+	if req.RefreshToken == "" {
+		return nil, h.grpcError(ctx, v1.ErrBadRequest)
+	}
+	tokens, err := h.tokenService.Refresh(ctx, req.RefreshToken)
+	if err != nil {
+		return nil, h.grpcError(ctx, err)
+	}
+	return &pb.TokenReply{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	}, nil
+}
+
+func loginReply(data *v1.LoginResponseData) *pb.LoginReply {
+	return &pb.LoginReply{
+		Token:             data.Token,
+		RefreshToken:      data.RefreshToken,
+		ExpiresIn:         data.ExpiresIn,
+		User:              userToPb(data.UserInfo),
+		TwoFactorRequired: data.TwoFactorRequired,
+		ChallengeToken:    data.ChallengeToken,
+	}
+}
+
+func userToPb(user *v1.UserProfile) *pb.User {
+	if user == nil {
+		return nil
+	}
+	return &pb.User{
+		Id:     uint64(user.Id),
+		Name:   user.Name,
+		Avatar: user.Avatar,
+		Gender: user.Gender,
+		Phone:  user.Phone,
+		Email:  user.Email,
+		Motto:  user.Motto,
+	}
+}
+
+// grpcError 将业务错误转换为 gRPC status，未知错误只记录日志，不把内部信息返回给客户端
+func (h *UserGrpcHandler) grpcError(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, v1.ErrBadRequest):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, v1.ErrUnauthorized), errors.Is(err, v1.ErrUserPasswordError), errors.Is(err, v1.ErrEmailCodeError),
+		errors.Is(err, v1.ErrRefreshTokenInvalid), errors.Is(err, v1.ErrRefreshTokenReused),
+		errors.Is(err, v1.ErrTwoFactorCodeError), errors.Is(err, v1.ErrTwoFactorChallengeInvalid):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, v1.ErrNotFound), errors.Is(err, v1.ErrUserNotFound), errors.Is(err, v1.ErrUserEmailNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, v1.ErrEmailAlreadyUse), errors.Is(err, v1.ErrUserNameAlreadyUse):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, v1.ErrUserDisabled), errors.Is(err, v1.ErrUserBanned):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, v1.ErrVerifyCodeCooldown), errors.Is(err, v1.ErrVerifyCodeLocked):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	default:
+		h.logger.WithContext(ctx).Error("grpc handler error", zap.Error(err))
+		return status.Error(codes.Internal, "internal error")
+	}
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"go-chat/pkg/clientinfo"
+)
+
+// ClientInfo 记录客户端 IP、User-Agent、设备信息和首选语言，供 service 层通过 clientinfo.FromContext 读取
+func ClientInfo() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		clientinfo.NewContext(ctx, &clientinfo.Info{
+			IP:         ctx.ClientIP(),
+			UserAgent:  ctx.Request.UserAgent(),
+			DeviceInfo: ctx.GetHeader(clientinfo.DeviceInfoHeader),
+			Locale:     clientinfo.ParseLocale(ctx.GetHeader(clientinfo.LocaleHeader)),
+		})
+		ctx.Next()
+	}
+}
@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/duke-git/lancet/v2/cryptor"
+	"github.com/duke-git/lancet/v2/random"
+	"go-chat/pkg/clientinfo"
+	"go-chat/pkg/jwt"
+	"go-chat/pkg/log"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// GrpcUnaryAuth 校验 metadata 中的 authorization，publicMethods 中的方法无需登录
+func GrpcUnaryAuth(j *jwt.JWT, revocation TokenRevocation, logger *log.Logger, publicMethods ...string) grpc.UnaryServerInterceptor {
+	public := make(map[string]bool, len(publicMethods))
+	for _, m := range publicMethods {
+		public[m] = true
+	}
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if public[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		ctx, err := grpcAuth(ctx, j, revocation, logger, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// GrpcStreamAuth 流式接口一律需要登录
+func GrpcStreamAuth(j *jwt.JWT, revocation TokenRevocation, logger *log.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := grpcAuth(ss.Context(), j, revocation, logger, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &wrappedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func grpcAuth(ctx context.Context, j *jwt.JWT, revocation TokenRevocation, logger *log.Logger, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 || values[0] == "" {
+		logger.WithContext(ctx).Warn("No token", zap.String("method", method))
+		return nil, status.Error(codes.Unauthenticated, "Unauthorized")
+	}
+
+	claims, err := j.ParseToken(values[0])
+	if err != nil {
+		logger.WithContext(ctx).Error("token error", zap.String("method", method), zap.Error(err))
+		return nil, status.Error(codes.Unauthenticated, "Unauthorized")
+	}
+
+	revoked, err := revocation.IsRevoked(ctx, claims)
+	if err != nil {
+		logger.WithContext(ctx).Error("token revocation check error", zap.String("method", method), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Server Error")
+	}
+	if revoked {
+		logger.WithContext(ctx).Warn("token revoked", zap.String("method", method), zap.String("UserId", claims.UserId))
+		return nil, status.Error(codes.Unauthenticated, "Unauthorized")
+	}
+
+	ctx = jwt.NewContext(ctx, claims)
+	return logger.WithValue(ctx, zap.String("UserId", claims.UserId)), nil
+}
+
+func GrpcUnaryLog(logger *log.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx = grpcTrace(ctx, logger, info.FullMethod)
+		logger.WithContext(ctx).Info("Request")
+		startTime := time.Now()
+		resp, err := handler(ctx, req)
+		logger.WithContext(ctx).Info("Response",
+			zap.String("code", status.Code(err).String()),
+			zap.Any("time", time.Since(startTime).String()),
+		)
+		return resp, err
+	}
+}
+
+func GrpcStreamLog(logger *log.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := grpcTrace(ss.Context(), logger, info.FullMethod)
+		logger.WithContext(ctx).Info("Stream open")
+		startTime := time.Now()
+		err := handler(srv, &wrappedStream{ServerStream: ss, ctx: ctx})
+		logger.WithContext(ctx).Info("Stream close",
+			zap.String("code", status.Code(err).String()),
+			zap.Any("time", time.Since(startTime).String()),
+		)
+		return err
+	}
+}
+
+func grpcTrace(ctx context.Context, logger *log.Logger, method string) context.Context {
+	uuid, err := random.UUIdV4()
+	if err == nil {
+		ctx = logger.WithTrace(ctx, cryptor.Md5String(uuid))
+	}
+	info := &clientinfo.Info{}
+	if p, ok := peer.FromContext(ctx); ok {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			info.IP = host
+		}
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("user-agent"); len(values) > 0 {
+			info.UserAgent = values[0]
+		}
+		if values := md.Get(clientinfo.DeviceInfoHeader); len(values) > 0 {
+			info.DeviceInfo = values[0]
+		}
+		if values := md.Get(clientinfo.LocaleHeader); len(values) > 0 {
+			info.Locale = clientinfo.ParseLocale(values[0])
+		}
+	}
+	ctx = clientinfo.NewContext(ctx, info)
+	return logger.WithValue(ctx, zap.String("grpc_method", method))
+}
+
+// wrappedStream 替换 ServerStream 的 context
+type wrappedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (w *wrappedStream) Context() context.Context {
+	return w.ctx
+}
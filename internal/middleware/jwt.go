@@ -1,20 +1,36 @@
 package middleware
 
 import (
+	"context"
+
 	"github.com/gin-gonic/gin"
 	"go-chat/api/v1"
 	"go-chat/pkg/jwt"
 	"go-chat/pkg/log"
 	"go.uber.org/zap"
 	"net/http"
+	"strings"
 )
 
-func StrictAuth(j *jwt.JWT, logger *log.Logger) gin.HandlerFunc {
+// EventsPathPrefix 实时事件通道（SSE、长轮询）的路由前缀，
+// 只有这些接口允许通过 query 传递 token，其他接口必须使用请求头
+const EventsPathPrefix = "/v1/events/"
+
+// TokenRevocation 判断签发过的 token 是否已被吊销（强制下线、禁用账号等）
+type TokenRevocation interface {
+	IsRevoked(ctx context.Context, claims *jwt.MyCustomClaims) (bool, error)
+}
+
+func StrictAuth(j *jwt.JWT, revocation TokenRevocation, logger *log.Logger) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		tokenString := ctx.Request.Header.Get("Authorization")
+		if tokenString == "" && strings.HasPrefix(ctx.FullPath(), EventsPathPrefix) {
+			// 浏览器 EventSource 无法设置请求头，允许通过 query 传递
+			tokenString = ctx.Query("accessToken")
+		}
 		if tokenString == "" {
 			logger.WithContext(ctx).Warn("No token", zap.Any("data", map[string]interface{}{
-				"url":    ctx.Request.URL,
+				"url":    redactURL(ctx.Request.URL),
 				"params": ctx.Params,
 			}))
 			v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
@@ -25,7 +41,7 @@ func StrictAuth(j *jwt.JWT, logger *log.Logger) gin.HandlerFunc {
 		claims, err := j.ParseToken(tokenString)
 		if err != nil {
 			logger.WithContext(ctx).Error("token error", zap.Any("data", map[string]interface{}{
-				"url":    ctx.Request.URL,
+				"url":    redactURL(ctx.Request.URL),
 				"params": ctx.Params,
 			}), zap.Error(err))
 			v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
@@ -33,6 +49,20 @@ func StrictAuth(j *jwt.JWT, logger *log.Logger) gin.HandlerFunc {
 			return
 		}
 
+		revoked, err := revocation.IsRevoked(ctx, claims)
+		if err != nil {
+			logger.WithContext(ctx).Error("token revocation check error", zap.Error(err))
+			v1.HandleError(ctx, http.StatusInternalServerError, v1.ErrInternalServerError, nil)
+			ctx.Abort()
+			return
+		}
+		if revoked {
+			logger.WithContext(ctx).Warn("token revoked", zap.String("UserId", claims.UserId))
+			v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+			ctx.Abort()
+			return
+		}
+
 		ctx.Set("claims", claims)
 		recoveryLoggerFunc(ctx, logger)
 		ctx.Next()
@@ -8,9 +8,14 @@ import (
 	"go-chat/pkg/log"
 	"go.uber.org/zap"
 	"io"
+	"net/url"
+	"strings"
 	"time"
 )
 
+// sensitiveQueryKeys 写日志前需要隐去的 query 参数
+var sensitiveQueryKeys = []string{"accessToken"}
+
 func RequestLogMiddleware(logger *log.Logger) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		// The configuration is initialized once per request
@@ -19,10 +24,10 @@ func RequestLogMiddleware(logger *log.Logger) gin.HandlerFunc {
 			return
 		}
 		trace := cryptor.Md5String(uuid)
-		logger.WithValue(ctx, zap.String("trace", trace))
+		logger.WithTrace(ctx, trace)
 		logger.WithValue(ctx, zap.String("request_method", ctx.Request.Method))
 		//logger.WithValue(ctx, zap.Any("request_headers", ctx.Request.Header))
-		logger.WithValue(ctx, zap.String("request_url", ctx.Request.URL.String()))
+		logger.WithValue(ctx, zap.String("request_url", redactURL(ctx.Request.URL)))
 		if ctx.Request.Body != nil {
 			bodyBytes, _ := ctx.GetRawData()
 			ctx.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes)) // 关键点
@@ -34,6 +39,13 @@ func RequestLogMiddleware(logger *log.Logger) gin.HandlerFunc {
 }
 func ResponseLogMiddleware(logger *log.Logger) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
+		// 实时事件通道是长连接，缓存响应体会让内存一直增长到客户端断开
+		if strings.HasPrefix(ctx.FullPath(), EventsPathPrefix) {
+			startTime := time.Now()
+			ctx.Next()
+			logger.WithContext(ctx).Info("Response", zap.Any("time", time.Since(startTime).String()))
+			return
+		}
 		blw := &bodyLogWriter{body: bytes.NewBufferString(""), ResponseWriter: ctx.Writer}
 		ctx.Writer = blw
 		startTime := time.Now()
@@ -49,6 +61,24 @@ type bodyLogWriter struct {
 }
 
 func (w bodyLogWriter) Write(b []byte) (int, error) {
-	w.body.Write(b)
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream") {
+		w.body.Write(b)
+	}
 	return w.ResponseWriter.Write(b)
 }
+
+// redactURL 返回隐去 token 等敏感 query 参数后的地址，用于写日志
+func redactURL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.String()
+	}
+	query := u.Query()
+	for _, key := range sensitiveQueryKeys {
+		if query.Has(key) {
+			query.Set(key, "***")
+		}
+	}
+	redacted := *u
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	v1 "go-chat/api/v1"
+	"go-chat/pkg/jwt"
+	"go-chat/pkg/log"
+	"go.uber.org/zap"
+)
+
+type PermissionChecker interface {
+	HasPermission(ctx context.Context, userName string, permission string) (bool, error)
+}
+
+// RequirePermission 需要在 StrictAuth 之后使用
+func RequirePermission(checker PermissionChecker, logger *log.Logger, permission string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		v, exists := ctx.Get("claims")
+		claims, ok := v.(*jwt.MyCustomClaims)
+		if !exists || !ok {
+			v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+			ctx.Abort()
+			return
+		}
+
+		allowed, err := checker.HasPermission(ctx, claims.UserId, permission)
+		if err != nil {
+			logger.WithContext(ctx).Error("permission check error", zap.String("permission", permission), zap.Error(err))
+			v1.HandleError(ctx, http.StatusInternalServerError, v1.ErrInternalServerError, nil)
+			ctx.Abort()
+			return
+		}
+		if !allowed {
+			logger.WithContext(ctx).Warn("permission denied", zap.String("permission", permission))
+			v1.HandleError(ctx, http.StatusForbidden, v1.ErrForbidden, nil)
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
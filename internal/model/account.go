@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// 数据导出状态
+const (
+	DataExportStatusPending    = "pending"
+	DataExportStatusProcessing = "processing"
+	DataExportStatusReady      = "ready"
+	DataExportStatusFailed     = "failed"
+	// DataExportStatusExpired 导出文件已过期删除
+	DataExportStatusExpired = "expired"
+)
+
+// DataExport 用户申请的个人数据导出，由 Job 生成 zip 文件
+type DataExport struct {
+	Model
+	UserId uint   `json:"user_id" gorm:"user_id;index"`
+	Status string `json:"status" gorm:"status;size:16;index"`
+	// FileName 导出目录下的文件名，不对外返回
+	FileName string `json:"-" gorm:"file_name;size:128"`
+	Size     int64  `json:"size" gorm:"size"`
+	// LockedUntil Job 领取后的租约，进程在生成中退出时租约到期后重新生成
+	LockedUntil *time.Time `json:"-" gorm:"locked_until"`
+	LastError   string     `json:"-" gorm:"last_error;type:text"`
+	CompletedAt *time.Time `json:"completed_at" gorm:"completed_at"`
+	// ExpiresAt 导出文件的下载截止时间
+	ExpiresAt *time.Time `json:"expires_at" gorm:"expires_at;index"`
+}
+
+func (*DataExport) TableName() string {
+	return "data_exports"
+}
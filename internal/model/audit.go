@@ -0,0 +1,68 @@
+package model
+
+import "time"
+
+// 审计动作
+const (
+	AuditActionLogin       = "auth.login"
+	AuditActionLoginFailed = "auth.login_failed"
+	// AuditActionRefreshTokenReuse 已轮换的 refresh token 被再次使用，整个 token 家族被吊销
+	AuditActionRefreshTokenReuse = "auth.refresh_token_reuse"
+	AuditActionPasswordChange    = "user.password_change"
+	AuditActionEmailChange       = "user.email_change"
+	AuditActionPhoneChange       = "user.phone_change"
+	AuditActionSessionRevoke     = "user.session_revoke"
+	AuditActionTwoFactorEnable   = "user.2fa_enable"
+	AuditActionTwoFactorDisable  = "user.2fa_disable"
+	// AuditActionRecoveryCodes 重新生成恢复码
+	AuditActionRecoveryCodes = "user.2fa_recovery_codes"
+	// AuditActionIdentityLink 外部身份提供方账号绑定到本地用户
+	AuditActionIdentityLink = "user.identity_link"
+	// AuditActionDeletionRequest 用户申请注销账号，宽限期后清除个人信息
+	AuditActionDeletionRequest = "user.deletion_request"
+	AuditActionDeletionCancel  = "user.deletion_cancel"
+	// AuditActionAnonymize 宽限期结束，账号的个人信息已清除
+	AuditActionAnonymize  = "user.anonymize"
+	AuditActionDataExport = "user.data_export"
+
+	AuditActionUserLogout        = "admin.user_logout"
+	AuditActionUserDisable       = "admin.user_disable"
+	AuditActionUserEnable        = "admin.user_enable"
+	AuditActionUserPasswordReset = "admin.user_password_reset"
+	AuditActionUserDelete        = "admin.user_delete"
+	AuditActionUserRestore       = "admin.user_restore"
+	AuditActionReportResolve     = "admin.report_resolve"
+
+	AuditActionRoleCreate      = "rbac.role_create"
+	AuditActionRolePermissions = "rbac.role_permissions"
+	AuditActionRoleAssign      = "rbac.role_assign"
+	AuditActionRoleRevoke      = "rbac.role_revoke"
+)
+
+// 审计对象类型
+const (
+	AuditTargetUser   = "user"
+	AuditTargetRole   = "role"
+	AuditTargetReport = "report"
+)
+
+// AuditLog 安全相关操作的审计记录，只允许追加，不嵌入 Model 以避免更新和软删除
+type AuditLog struct {
+	ID uint `json:"id" gorm:"primaryKey"`
+	// ActorId 为 0 表示匿名操作，如登录失败
+	ActorId    uint      `json:"actor_id" gorm:"actor_id;index"`
+	ActorName  string    `json:"actor_name" gorm:"actor_name"`
+	TargetType string    `json:"target_type" gorm:"target_type;index:idx_audit_target"`
+	TargetId   string    `json:"target_id" gorm:"target_id;index:idx_audit_target"`
+	Action     string    `json:"action" gorm:"action;index"`
+	Metadata   string    `json:"metadata" gorm:"metadata;type:text"`
+	Ip         string    `json:"ip" gorm:"ip"`
+	UserAgent  string    `json:"user_agent" gorm:"user_agent"`
+	DeviceInfo string    `json:"device_info" gorm:"device_info"`
+	TraceId    string    `json:"trace_id" gorm:"trace_id;index"`
+	CreateAt   time.Time `json:"create_at" gorm:"autoCreateTime;index"`
+}
+
+func (*AuditLog) TableName() string {
+	return "audit_logs"
+}
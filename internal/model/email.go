@@ -1,5 +1,38 @@
 package model
 
-// Email 邮件发送（授权码）
-type Email struct {
+import "time"
+
+// 邮件队列状态
+const (
+	EmailStatusPending = "pending"
+	EmailStatusSending = "sending"
+	EmailStatusSent    = "sent"
+	EmailStatusFailed  = "failed"
+)
+
+// EmailStatuses 后台统计时按此顺序展示
+var EmailStatuses = []string{EmailStatusPending, EmailStatusSending, EmailStatusSent, EmailStatusFailed}
+
+// EmailMessage 邮件队列，由 Job 中的 worker 投递，失败后按指数退避重试
+type EmailMessage struct {
+	Model
+	Recipient string `json:"recipient" gorm:"recipient;size:255"`
+	Template  string `json:"template" gorm:"template;size:64"`
+	Locale    string `json:"locale" gorm:"locale;size:16"`
+	// Data 模板数据的 JSON，可能包含验证码，发送成功或最终失败后清空
+	Data          string    `json:"-" gorm:"data;type:text"`
+	Status        string    `json:"status" gorm:"status;size:16;index:idx_email_status_next"`
+	Attempts      int       `json:"attempts" gorm:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at" gorm:"next_attempt_at;index:idx_email_status_next"`
+	// LockedUntil worker 领取后的租约，进程在发送中退出时租约到期后重新投递
+	LockedUntil *time.Time `json:"locked_until" gorm:"locked_until"`
+	// ExpiresAt 验证码邮件在验证码过期后不再发送
+	ExpiresAt *time.Time `json:"expires_at" gorm:"expires_at"`
+	LastError string     `json:"last_error" gorm:"last_error;type:text"`
+	SentAt    *time.Time `json:"sent_at" gorm:"sent_at"`
+	FailedAt  *time.Time `json:"failed_at" gorm:"failed_at"`
+}
+
+func (*EmailMessage) TableName() string {
+	return "email_messages"
 }
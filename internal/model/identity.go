@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// UserIdentity 外部身份提供方（OIDC）账号与本地用户的绑定，Provider + Subject 唯一
+type UserIdentity struct {
+	Model
+	UserId   uint   `json:"user_id" gorm:"user_id;index"`
+	Provider string `json:"provider" gorm:"provider;size:64;uniqueIndex:idx_identity_provider_subject"`
+	// Subject 提供方 id_token 中的 sub
+	Subject     string     `json:"subject" gorm:"subject;size:255;uniqueIndex:idx_identity_provider_subject"`
+	Email       string     `json:"email" gorm:"email"`
+	LastLoginAt *time.Time `json:"last_login_at" gorm:"last_login_at"`
+}
+
+func (*UserIdentity) TableName() string {
+	return "user_identities"
+}
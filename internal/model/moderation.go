@@ -0,0 +1,14 @@
+package model
+
+// ModerationFlag 命中 flag 词库、等待人工复核的用户内容
+type ModerationFlag struct {
+	Model
+	UserId  uint   `json:"user_id" gorm:"user_id;index"`
+	Field   string `json:"field" gorm:"field"`
+	Content string `json:"content" gorm:"content"`
+	Words   string `json:"words" gorm:"words"`
+}
+
+func (*ModerationFlag) TableName() string {
+	return "moderation_flags"
+}
@@ -0,0 +1,60 @@
+package model
+
+// 权限码，新增权限后需重新执行 migration 写入数据库
+const (
+	PermissionUsersRead     = "users.read"
+	PermissionUsersManage   = "users.manage"
+	PermissionUsersBan      = "users.ban"
+	PermissionReportsReview = "reports.review"
+	PermissionRolesManage   = "roles.manage"
+	PermissionAuditRead     = "audit.read"
+	PermissionMailRead      = "mail.read"
+)
+
+// Permissions 权限目录，migration 时写入 permissions 表
+var Permissions = map[string]string{
+	PermissionUsersRead:     "查看用户",
+	PermissionUsersManage:   "管理用户账号",
+	PermissionUsersBan:      "禁言、封禁用户",
+	PermissionReportsReview: "处理举报",
+	PermissionRolesManage:   "管理角色和权限",
+	PermissionAuditRead:     "查看审计日志",
+	PermissionMailRead:      "查看邮件队列",
+}
+
+// DefaultRolePermissions 内置角色，admin 拥有全部权限
+var DefaultRolePermissions = map[string][]string{
+	IdentityModerator: {PermissionUsersRead, PermissionUsersBan, PermissionReportsReview},
+}
+
+type Permission struct {
+	Model
+	Code        string `json:"code" gorm:"code;uniqueIndex;size:64"`
+	Description string `json:"description" gorm:"description"`
+}
+
+func (*Permission) TableName() string {
+	return "permissions"
+}
+
+// Role 角色，UserBasics.Identity 为用户的主角色，UserRole 为额外分配的角色
+type Role struct {
+	Model
+	Name        string        `json:"name" gorm:"name;uniqueIndex;size:64"`
+	Description string        `json:"description" gorm:"description"`
+	Permissions []*Permission `json:"permissions" gorm:"many2many:role_permissions"`
+}
+
+func (*Role) TableName() string {
+	return "roles"
+}
+
+type UserRole struct {
+	Model
+	UserId uint `json:"user_id" gorm:"user_id;uniqueIndex:idx_user_role"`
+	RoleId uint `json:"role_id" gorm:"role_id;uniqueIndex:idx_user_role"`
+}
+
+func (*UserRole) TableName() string {
+	return "user_roles"
+}
@@ -0,0 +1,74 @@
+package model
+
+import "time"
+
+// 举报对象类型
+const (
+	ReportTargetUser    = "user"
+	ReportTargetMessage = "message"
+	ReportTargetGroup   = "group"
+)
+
+// 举报处理状态
+const (
+	ReportStatusPending  = "pending"
+	ReportStatusClaimed  = "claimed"
+	ReportStatusResolved = "resolved"
+)
+
+// 处理动作
+const (
+	ModerationActionDismiss = "dismiss"
+	ModerationActionWarn    = "warn"
+	ModerationActionMute    = "mute"
+	ModerationActionBan     = "ban"
+	ModerationActionDelete  = "delete"
+)
+
+// Report 用户举报，进入审核队列由管理员处理
+type Report struct {
+	Model
+	ReporterId   uint       `json:"reporter_id" gorm:"reporter_id;index"`
+	TargetType   string     `json:"target_type" gorm:"target_type;index:idx_report_target"`
+	TargetId     string     `json:"target_id" gorm:"target_id;index:idx_report_target"`
+	TargetUserId uint       `json:"target_user_id" gorm:"target_user_id;index"`
+	Reason       string     `json:"reason" gorm:"reason"`
+	Detail       string     `json:"detail" gorm:"detail"`
+	Status       string     `json:"status" gorm:"status;index"`
+	ModeratorId  uint       `json:"moderator_id" gorm:"moderator_id"`
+	ClaimedAt    *time.Time `json:"claimed_at" gorm:"claimed_at"`
+	ResolvedAt   *time.Time `json:"resolved_at" gorm:"resolved_at"`
+	Action       string     `json:"action" gorm:"action"`
+}
+
+func (*Report) TableName() string {
+	return "reports"
+}
+
+// ModerationAction 管理员或系统的每一次处理记录，ModeratorId 为 0 表示系统自动处理
+type ModerationAction struct {
+	Model
+	ReportId     uint       `json:"report_id" gorm:"report_id;index"`
+	ModeratorId  uint       `json:"moderator_id" gorm:"moderator_id"`
+	TargetUserId uint       `json:"target_user_id" gorm:"target_user_id;index"`
+	Action       string     `json:"action" gorm:"action"`
+	Note         string     `json:"note" gorm:"note"`
+	ExpiresAt    *time.Time `json:"expires_at" gorm:"expires_at"`
+}
+
+func (*ModerationAction) TableName() string {
+	return "moderation_actions"
+}
+
+// UserRestriction 用户处罚（禁言、封禁），ExpiresAt 为空表示永久
+type UserRestriction struct {
+	Model
+	UserId    uint       `json:"user_id" gorm:"user_id;index"`
+	Type      string     `json:"type" gorm:"type"`
+	Reason    string     `json:"reason" gorm:"reason"`
+	ExpiresAt *time.Time `json:"expires_at" gorm:"expires_at"`
+}
+
+func (*UserRestriction) TableName() string {
+	return "user_restrictions"
+}
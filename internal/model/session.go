@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// Session 一次登录对应的设备会话，SessionId 与该次登录的 refresh token FamilyId 相同
+type Session struct {
+	Model
+	UserId    uint   `json:"user_id" gorm:"user_id;index"`
+	SessionId string `json:"session_id" gorm:"session_id;uniqueIndex;size:32"`
+	// Name 用户自定义的设备名称
+	Name       string    `json:"name" gorm:"name"`
+	DeviceInfo string    `json:"device_info" gorm:"device_info"`
+	ClientIp   string    `json:"client_ip" gorm:"client_ip"`
+	UserAgent  string    `json:"user_agent" gorm:"user_agent"`
+	LastSeenAt time.Time `json:"last_seen_at" gorm:"last_seen_at"`
+	// ExpiresAt 随 refresh token 轮换顺延，过期后会话不再展示
+	ExpiresAt time.Time  `json:"expires_at" gorm:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at" gorm:"revoked_at"`
+}
+
+func (*Session) TableName() string {
+	return "sessions"
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// RefreshToken 服务端保存的 refresh token，只存哈希。
+// 同一次登录轮换出来的 token 属于同一个 FamilyId，任意一个被重复使用时整个家族失效
+type RefreshToken struct {
+	Model
+	UserId    uint   `json:"user_id" gorm:"user_id;index"`
+	FamilyId  string `json:"family_id" gorm:"family_id;index;size:32"`
+	TokenHash string `json:"-" gorm:"token_hash;uniqueIndex;size:64"`
+	// AccessTokenId 同时签发的 access token 的 jti，家族被吊销时一并吊销
+	AccessTokenId string     `json:"access_token_id" gorm:"access_token_id;size:32"`
+	ExpiresAt     time.Time  `json:"expires_at" gorm:"expires_at"`
+	UsedAt        *time.Time `json:"used_at" gorm:"used_at"`
+	RevokedAt     *time.Time `json:"revoked_at" gorm:"revoked_at"`
+}
+
+func (*RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// TwoFactor 用户的 TOTP 配置，EnabledAt 为空表示已生成密钥但还未用验证码确认
+type TwoFactor struct {
+	Model
+	UserId    uint       `json:"user_id" gorm:"user_id;uniqueIndex"`
+	Secret    string     `json:"-" gorm:"secret;size:64"`
+	EnabledAt *time.Time `json:"enabled_at" gorm:"enabled_at"`
+	// LastUsedStep 最近一次通过校验的时间步，同一验证码不能重复使用
+	LastUsedStep int64 `json:"-" gorm:"last_used_step"`
+}
+
+func (*TwoFactor) TableName() string {
+	return "two_factors"
+}
+
+// RecoveryCode 一次性恢复码，只存哈希
+type RecoveryCode struct {
+	Model
+	UserId   uint       `json:"user_id" gorm:"user_id;index"`
+	CodeHash string     `json:"-" gorm:"code_hash;size:64"`
+	UsedAt   *time.Time `json:"used_at" gorm:"used_at"`
+}
+
+func (*RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
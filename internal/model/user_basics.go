@@ -12,10 +12,16 @@ type Model struct {
 	DeleteAt gorm.DeletedAt `json:"delete_at" gorm:"index"`
 }
 
+// 用户身份
+const (
+	IdentityAdmin     = "admin"
+	IdentityModerator = "moderator"
+)
+
 type UserBasics struct {
 	Model
 	Name          string     `json:"name" gorm:"name"`
-	PassWord      string     `json:"pass_word" gorm:"pass_word"`
+	PassWord      string     `json:"-" gorm:"pass_word"`
 	Avatar        string     `json:"avatar" gorm:"avatar"`
 	Gender        string     `json:"gender" gorm:"gender"`
 	Phone         string     `json:"phone" gorm:"phone"`
@@ -24,12 +30,27 @@ type UserBasics struct {
 	Identity      string     `json:"identity" gorm:"identity"`
 	ClientIp      string     `json:"client_ip" gorm:"client_ip"`
 	ClientPort    string     `json:"client_port" gorm:"client_port"`
-	Salt          string     `json:"salt" gorm:"salt"`
+	Salt          string     `json:"-" gorm:"salt"` // 只有旧版 MD5 密码使用，新格式的随机盐在 PassWord 的哈希串中
 	LoginTime     *time.Time `json:"login_time" gorm:"login_time"`
 	HeartBeatTime *time.Time `json:"heart_beat_time" gorm:"heart_beat_time"`
 	LoginOutTime  *time.Time `json:"login_out_time" gorm:"login_out_time"`
 	IsLoginOut    int8       `json:"is_login_out" gorm:"is_login_out"`
 	DeviceInfo    string     `json:"device_info" gorm:"device_info"`
+	// DisabledAt 管理员禁用账号的时间，为空表示正常
+	DisabledAt *time.Time `json:"disabled_at" gorm:"disabled_at"`
+	// PhoneVerifiedAt 手机号通过短信验证的时间，未验证的号码不能用于短信登录
+	PhoneVerifiedAt *time.Time `json:"phone_verified_at" gorm:"phone_verified_at"`
+	// EmailVerifiedAt 邮箱通过验证码确认的时间，未确认的邮箱不会被第三方登录按邮箱绑定
+	EmailVerifiedAt *time.Time `json:"email_verified_at" gorm:"email_verified_at"`
+	// DeletionScheduledAt 用户申请注销后计划清除个人信息的时间，宽限期内可以撤销
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at" gorm:"deletion_scheduled_at;index"`
+	// AnonymizedAt 注销完成、个人信息已清除的时间，之后不能再恢复
+	AnonymizedAt *time.Time `json:"anonymized_at" gorm:"anonymized_at"`
+	// DiscoverableByName 其他用户能否按用户名前缀搜索到
+	DiscoverableByName bool `json:"discoverable_by_name" gorm:"discoverable_by_name;default:true"`
+	// DiscoverableByEmail/DiscoverableByPhone 能否按完整邮箱、已验证的手机号搜索到，默认关闭
+	DiscoverableByEmail bool `json:"discoverable_by_email" gorm:"discoverable_by_email;default:false"`
+	DiscoverableByPhone bool `json:"discoverable_by_phone" gorm:"discoverable_by_phone;default:false"`
 }
 
 func (*UserBasics) TableName() string {
@@ -0,0 +1,302 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-chat/internal/model"
+	"gorm.io/gorm"
+)
+
+// AccountData 导出给用户本人的数据
+type AccountData struct {
+	Sessions     []*model.Session
+	Identities   []*model.UserIdentity
+	Reports      []*model.Report
+	Restrictions []*model.UserRestriction
+	AuditLogs    []*model.AuditLog
+}
+
+type AccountRepository interface {
+	// ScheduleDeletion at 为空表示撤销注销
+	ScheduleDeletion(ctx context.Context, userId uint, at *time.Time) error
+	// ListDueDeletions 宽限期已结束、尚未清除个人信息的用户
+	ListDueDeletions(ctx context.Context, now time.Time, limit int) ([]*model.UserBasics, error)
+	// Anonymize 按注销策略清除用户的个人信息，需要在事务中调用
+	Anonymize(ctx context.Context, user *model.UserBasics, now time.Time) error
+
+	CreateExport(ctx context.Context, export *model.DataExport) error
+	// FindLatestExport 没有时返回 nil
+	FindLatestExport(ctx context.Context, userId uint) (*model.DataExport, error)
+	// ClaimExport 领取一个待生成的导出任务，没有时返回 nil
+	ClaimExport(ctx context.Context, now time.Time, lease time.Duration) (*model.DataExport, error)
+	MarkExportReady(ctx context.Context, id uint, fileName string, size int64, completedAt time.Time, expiresAt time.Time) error
+	MarkExportFailed(ctx context.Context, id uint, lastError string, completedAt time.Time) error
+	// ListExpiredExports 下载期已过、文件还未删除的导出
+	ListExpiredExports(ctx context.Context, now time.Time, limit int) ([]*model.DataExport, error)
+	MarkExportExpired(ctx context.Context, id uint) error
+	// ListExportFiles 用户所有未删除的导出文件名，注销时一并删除
+	ListExportFiles(ctx context.Context, userId uint) ([]string, error)
+	LoadAccountData(ctx context.Context, userId uint) (*AccountData, error)
+}
+
+func NewAccountRepository(
+	repository *Repository,
+) AccountRepository {
+	return &accountRepository{
+		Repository: repository,
+	}
+}
+
+type accountRepository struct {
+	*Repository
+}
+
+func (r *accountRepository) ScheduleDeletion(ctx context.Context, userId uint, at *time.Time) error {
+	if err := r.DB(ctx).Model(&model.UserBasics{}).Where(" id= ?", userId).Update("deletion_scheduled_at", at).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *accountRepository) ListDueDeletions(ctx context.Context, now time.Time, limit int) ([]*model.UserBasics, error) {
+	var users []*model.UserBasics
+	// 宽限期内被管理员注销的账号同样需要清除
+	if err := r.DB(ctx).Unscoped().
+		Where("deletion_scheduled_at <= ? AND anonymized_at IS NULL", now).
+		Order("deletion_scheduled_at").
+		Limit(limit).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// Anonymize 注销策略：
+//   - user_basics 保留一行占位并软删除，名称改为 deleted_<id>，其余个人资料全部清空，
+//     举报和审计记录中的用户 ID 仍然有效，用户名和邮箱可以被重新注册
+//   - 登录会话、refresh token、二次验证、第三方账号绑定和角色直接删除
+//   - 内容审核记录中用户发布的原文直接删除，用户提交的举报保留处理结果，清空补充说明
+//   - 审计日志保留，用户本人操作的记录和以用户为对象的记录清空 IP、设备和附加信息
+//     （附加信息中有旧用户名、新旧邮箱等），用户名或邮箱不存在时的登录失败记录清空附加信息
+//   - 邮件队列中发往该邮箱的邮件直接删除
+func (r *accountRepository) Anonymize(ctx context.Context, user *model.UserBasics, now time.Time) error {
+	db := r.DB(ctx)
+	placeholder := fmt.Sprintf("deleted_%d", user.ID)
+	if err := db.Unscoped().Model(&model.UserBasics{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"name":                  placeholder,
+		"pass_word":             "",
+		"avatar":                "",
+		"gender":                "",
+		"phone":                 "",
+		"phone_verified_at":     nil,
+		"email":                 "",
+		"email_verified_at":     nil,
+		"motto":                 "",
+		"identity":              "",
+		"client_ip":             "",
+		"client_port":           "",
+		"salt":                  "",
+		"device_info":           "",
+		"deletion_scheduled_at": nil,
+		"anonymized_at":         now,
+		"delete_at":             now,
+	}).Error; err != nil {
+		return err
+	}
+
+	for _, m := range []interface{}{
+		&model.Session{},
+		&model.RefreshToken{},
+		&model.TwoFactor{},
+		&model.RecoveryCode{},
+		&model.UserIdentity{},
+		&model.UserRole{},
+		&model.ModerationFlag{},
+	} {
+		if err := db.Unscoped().Where("user_id = ?", user.ID).Delete(m).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := db.Model(&model.Report{}).Where("reporter_id = ?", user.ID).Update("detail", "").Error; err != nil {
+		return err
+	}
+	if err := db.Model(&model.AuditLog{}).Where("actor_id = ?", user.ID).Updates(map[string]interface{}{
+		"actor_name":  placeholder,
+		"metadata":    "",
+		"ip":          "",
+		"user_agent":  "",
+		"device_info": "",
+	}).Error; err != nil {
+		return err
+	}
+	if err := db.Model(&model.AuditLog{}).
+		Where("target_type = ? AND target_id = ?", model.AuditTargetUser, fmt.Sprint(user.ID)).
+		Updates(map[string]interface{}{
+			"metadata":    "",
+			"ip":          "",
+			"user_agent":  "",
+			"device_info": "",
+		}).Error; err != nil {
+		return err
+	}
+	// 找不到用户时的登录失败记录没有对象 ID，按附加信息中的用户名、邮箱匹配
+	for key, value := range map[string]string{"name": user.Name, "email": user.Email} {
+		if value == "" {
+			continue
+		}
+		encoded, _ := json.Marshal(value)
+		fragment := fmt.Sprintf(`"%s":%s`, key, encoded)
+		if err := db.Model(&model.AuditLog{}).
+			Where("action = ? AND target_id = ? AND metadata LIKE ? ESCAPE '!'", model.AuditActionLoginFailed, "", "%"+escapeLike(fragment)+"%").
+			Update("metadata", "").Error; err != nil {
+			return err
+		}
+	}
+	if user.Email != "" {
+		if err := db.Unscoped().Where("recipient = ?", user.Email).Delete(&model.EmailMessage{}).Error; err != nil {
+			return err
+		}
+	}
+	if err := db.Unscoped().Where("user_id = ?", user.ID).Delete(&model.DataExport{}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *accountRepository) CreateExport(ctx context.Context, export *model.DataExport) error {
+	if err := r.DB(ctx).Create(export).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *accountRepository) FindLatestExport(ctx context.Context, userId uint) (*model.DataExport, error) {
+	var export model.DataExport
+	if err := r.DB(ctx).Where("user_id = ?", userId).Order("id desc").First(&export).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &export, nil
+}
+
+const exportDueCondition = "status = ? OR (status = ? AND locked_until < ?)"
+
+func (r *accountRepository) ClaimExport(ctx context.Context, now time.Time, lease time.Duration) (*model.DataExport, error) {
+	var ids []uint
+	if err := r.DB(ctx).Model(&model.DataExport{}).
+		Where(exportDueCondition, model.DataExportStatusPending, model.DataExportStatusProcessing, now).
+		Order("id").
+		Limit(5).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		result := r.DB(ctx).Model(&model.DataExport{}).
+			Where("id = ?", id).
+			Where(exportDueCondition, model.DataExportStatusPending, model.DataExportStatusProcessing, now).
+			Updates(map[string]interface{}{
+				"status":       model.DataExportStatusProcessing,
+				"locked_until": now.Add(lease),
+			})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		var export model.DataExport
+		if err := r.DB(ctx).Where("id = ?", id).First(&export).Error; err != nil {
+			return nil, err
+		}
+		return &export, nil
+	}
+	return nil, nil
+}
+
+func (r *accountRepository) MarkExportReady(ctx context.Context, id uint, fileName string, size int64, completedAt time.Time, expiresAt time.Time) error {
+	if err := r.DB(ctx).Model(&model.DataExport{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       model.DataExportStatusReady,
+		"file_name":    fileName,
+		"size":         size,
+		"locked_until": nil,
+		"completed_at": completedAt,
+		"expires_at":   expiresAt,
+	}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *accountRepository) MarkExportFailed(ctx context.Context, id uint, lastError string, completedAt time.Time) error {
+	if err := r.DB(ctx).Model(&model.DataExport{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       model.DataExportStatusFailed,
+		"last_error":   lastError,
+		"locked_until": nil,
+		"completed_at": completedAt,
+	}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *accountRepository) ListExpiredExports(ctx context.Context, now time.Time, limit int) ([]*model.DataExport, error) {
+	var exports []*model.DataExport
+	if err := r.DB(ctx).
+		Where("status = ? AND expires_at < ?", model.DataExportStatusReady, now).
+		Order("id").
+		Limit(limit).
+		Find(&exports).Error; err != nil {
+		return nil, err
+	}
+	return exports, nil
+}
+
+func (r *accountRepository) MarkExportExpired(ctx context.Context, id uint) error {
+	if err := r.DB(ctx).Model(&model.DataExport{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":    model.DataExportStatusExpired,
+		"file_name": "",
+	}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *accountRepository) ListExportFiles(ctx context.Context, userId uint) ([]string, error) {
+	var names []string
+	if err := r.DB(ctx).Model(&model.DataExport{}).
+		Where("user_id = ? AND file_name <> ''", userId).
+		Pluck("file_name", &names).Error; err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+func (r *accountRepository) LoadAccountData(ctx context.Context, userId uint) (*AccountData, error) {
+	data := &AccountData{}
+	db := r.DB(ctx)
+	if err := db.Where("user_id = ?", userId).Order("id").Find(&data.Sessions).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("user_id = ?", userId).Order("id").Find(&data.Identities).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("reporter_id = ?", userId).Order("id").Find(&data.Reports).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("user_id = ?", userId).Order("id").Find(&data.Restrictions).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("actor_id = ? OR (target_type = ? AND target_id = ?)", userId, model.AuditTargetUser, fmt.Sprint(userId)).
+		Order("id").
+		Find(&data.AuditLogs).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go-chat/internal/model"
+)
+
+type AuditRepository interface {
+	// Create 在 ctx 所在的事务中写入
+	Create(ctx context.Context, log *model.AuditLog) error
+	List(ctx context.Context, filter *AuditFilter, offset int, limit int) ([]*model.AuditLog, int64, error)
+}
+
+type AuditFilter struct {
+	ActorId    uint
+	TargetType string
+	TargetId   string
+	Actions    []string
+	Since      *time.Time
+	Until      *time.Time
+}
+
+func NewAuditRepository(
+	r *Repository,
+) AuditRepository {
+	return &auditRepository{
+		Repository: r,
+	}
+}
+
+type auditRepository struct {
+	*Repository
+}
+
+func (r *auditRepository) Create(ctx context.Context, log *model.AuditLog) error {
+	if err := r.DB(ctx).Create(log).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *auditRepository) List(ctx context.Context, filter *AuditFilter, offset int, limit int) ([]*model.AuditLog, int64, error) {
+	var (
+		logs  []*model.AuditLog
+		total int64
+	)
+	db := r.DB(ctx).Model(&model.AuditLog{})
+	if filter.ActorId != 0 {
+		db = db.Where("actor_id = ?", filter.ActorId)
+	}
+	if filter.TargetType != "" {
+		db = db.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetId != "" {
+		db = db.Where("target_id = ?", filter.TargetId)
+	}
+	if len(filter.Actions) > 0 {
+		db = db.Where("action IN ?", filter.Actions)
+	}
+	if filter.Since != nil {
+		db = db.Where("create_at >= ?", *filter.Since)
+	}
+	if filter.Until != nil {
+		db = db.Where("create_at < ?", *filter.Until)
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := db.Order("id desc").Offset(offset).Limit(limit).Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
@@ -2,13 +2,24 @@ package repository
 
 import (
 	"context"
-	"errors"
 	"time"
+
+	"go-chat/internal/model"
+	"gorm.io/gorm"
 )
 
 type EmailRepository interface {
-	SaveVerifyCode(ctx context.Context, key string, codeValue string) error
-	GetEmailCodeByKey(ctx context.Context, key string) (string, error)
+	Enqueue(ctx context.Context, message *model.EmailMessage) error
+	// ClaimDue 领取到期的待发送邮件和租约已过期的发送中邮件，领取时 Attempts 加一。
+	// 逐条按条件更新，多个 worker 同时领取时每封邮件只会被一个 worker 拿到
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.EmailMessage, error)
+	MarkSent(ctx context.Context, id uint, sentAt time.Time) error
+	// MarkRetry 放回队列，nextAttemptAt 之后重新投递
+	MarkRetry(ctx context.Context, id uint, lastError string, nextAttemptAt time.Time) error
+	MarkFailed(ctx context.Context, id uint, lastError string, failedAt time.Time) error
+	CountByStatus(ctx context.Context) (map[string]int64, error)
+	// List status 为空时返回全部，最新的在前
+	List(ctx context.Context, status string, offset int, limit int) ([]*model.EmailMessage, int64, error)
 }
 
 func NewEmailRepository(
@@ -23,25 +34,131 @@ type emailRepository struct {
 	*Repository
 }
 
-func (r *emailRepository) SaveVerifyCode(ctx context.Context, key string, codeValue string) error {
-	// setNx 插入redis，插入失败说明验证码未过期
-	success, err := r.rdb.SetNX(ctx, key, codeValue, time.Duration(5)*time.Minute).Result()
+const emailDueCondition = "(status = ? AND next_attempt_at <= ?) OR (status = ? AND locked_until < ?)"
+
+func (r *emailRepository) Enqueue(ctx context.Context, message *model.EmailMessage) error {
+	if err := r.DB(ctx).Create(message).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *emailRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.EmailMessage, error) {
+	var ids []uint
+	if err := r.DB(ctx).Model(&model.EmailMessage{}).
+		Where(emailDueCondition, model.EmailStatusPending, now, model.EmailStatusSending, now).
+		Order("next_attempt_at").
+		Limit(limit).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+
+	lockedUntil := now.Add(lease)
+	claimed := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		result := r.DB(ctx).Model(&model.EmailMessage{}).
+			Where("id = ?", id).
+			Where(emailDueCondition, model.EmailStatusPending, now, model.EmailStatusSending, now).
+			Updates(map[string]interface{}{
+				"status":       model.EmailStatusSending,
+				"locked_until": lockedUntil,
+				"attempts":     gorm.Expr("attempts + 1"),
+			})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			claimed = append(claimed, id)
+		}
+	}
+	if len(claimed) == 0 {
+		return nil, nil
+	}
+
+	var messages []*model.EmailMessage
+	if err := r.DB(ctx).Where("id IN ?", claimed).Order("next_attempt_at").Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func (r *emailRepository) MarkSent(ctx context.Context, id uint, sentAt time.Time) error {
+	return r.finish(ctx, id, map[string]interface{}{
+		"status":     model.EmailStatusSent,
+		"sent_at":    sentAt,
+		"last_error": "",
+	})
+}
 
-	if err != nil {
+func (r *emailRepository) MarkRetry(ctx context.Context, id uint, lastError string, nextAttemptAt time.Time) error {
+	if err := r.DB(ctx).Model(&model.EmailMessage{}).
+		Where("id = ? AND status = ?", id, model.EmailStatusSending).
+		Updates(map[string]interface{}{
+			"status":          model.EmailStatusPending,
+			"last_error":      lastError,
+			"next_attempt_at": nextAttemptAt,
+			"locked_until":    nil,
+		}).Error; err != nil {
 		return err
 	}
+	return nil
+}
 
-	if !success {
-		return errors.New("验证码未过期")
+func (r *emailRepository) MarkFailed(ctx context.Context, id uint, lastError string, failedAt time.Time) error {
+	return r.finish(ctx, id, map[string]interface{}{
+		"status":     model.EmailStatusFailed,
+		"failed_at":  failedAt,
+		"last_error": lastError,
+	})
+}
+
+// finish 结束一封邮件，同时清空可能包含验证码的模板数据
+func (r *emailRepository) finish(ctx context.Context, id uint, updates map[string]interface{}) error {
+	updates["data"] = ""
+	updates["locked_until"] = nil
+	if err := r.DB(ctx).Model(&model.EmailMessage{}).
+		Where("id = ? AND status = ?", id, model.EmailStatusSending).
+		Updates(updates).Error; err != nil {
+		return err
 	}
 	return nil
 }
 
-func (r *emailRepository) GetEmailCodeByKey(ctx context.Context, key string) (string, error) {
-	success, err := r.rdb.Get(ctx, key).Result()
-	if err != nil {
-		return "", err
+func (r *emailRepository) CountByStatus(ctx context.Context) (map[string]int64, error) {
+	var rows []struct {
+		Status string
+		Count  int64
 	}
+	if err := r.DB(ctx).Model(&model.EmailMessage{}).
+		Select("status, count(*) as count").
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(model.EmailStatuses))
+	for _, status := range model.EmailStatuses {
+		counts[status] = 0
+	}
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
 
-	return success, nil
+func (r *emailRepository) List(ctx context.Context, status string, offset int, limit int) ([]*model.EmailMessage, int64, error) {
+	var (
+		messages []*model.EmailMessage
+		total    int64
+	)
+	db := r.DB(ctx).Model(&model.EmailMessage{})
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := db.Order("id desc").Offset(offset).Limit(limit).Find(&messages).Error; err != nil {
+		return nil, 0, err
+	}
+	return messages, total, nil
 }
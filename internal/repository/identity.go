@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go-chat/internal/model"
+	"gorm.io/gorm"
+)
+
+// OIDCState 跳转到提供方之前保存的授权请求，回调时按 state 取回
+type OIDCState struct {
+	Provider string `json:"provider"`
+	Verifier string `json:"verifier"`
+	Nonce    string `json:"nonce"`
+}
+
+type IdentityRepository interface {
+	// FindByProviderSubject 没有时返回 nil
+	FindByProviderSubject(ctx context.Context, provider string, subject string) (*model.UserIdentity, error)
+	Create(ctx context.Context, identity *model.UserIdentity) error
+	UpdateLastLogin(ctx context.Context, id uint, email string, lastLoginAt time.Time) error
+
+	SaveState(ctx context.Context, state string, value *OIDCState, ttl time.Duration) error
+	// TakeState 取出并删除，state 只能使用一次，不存在或已过期时返回 nil
+	TakeState(ctx context.Context, state string) (*OIDCState, error)
+}
+
+func NewIdentityRepository(
+	repository *Repository,
+) IdentityRepository {
+	return &identityRepository{
+		Repository: repository,
+	}
+}
+
+type identityRepository struct {
+	*Repository
+}
+
+func (r *identityRepository) FindByProviderSubject(ctx context.Context, provider string, subject string) (*model.UserIdentity, error) {
+	var identity model.UserIdentity
+	if err := r.DB(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *identityRepository) Create(ctx context.Context, identity *model.UserIdentity) error {
+	if err := r.DB(ctx).Create(identity).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *identityRepository) UpdateLastLogin(ctx context.Context, id uint, email string, lastLoginAt time.Time) error {
+	if err := r.DB(ctx).Model(&model.UserIdentity{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"email":         email,
+		"last_login_at": lastLoginAt,
+	}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *identityRepository) SaveState(ctx context.Context, state string, value *OIDCState, ttl time.Duration) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return r.rdb.Set(ctx, "oidc:state:"+state, b, ttl).Err()
+}
+
+func (r *identityRepository) TakeState(ctx context.Context, state string) (*OIDCState, error) {
+	b, err := r.rdb.GetDel(ctx, "oidc:state:"+state).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var value OIDCState
+	if err = json.Unmarshal(b, &value); err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"go-chat/internal/model"
+)
+
+type ModerationRepository interface {
+	CreateFlag(ctx context.Context, flag *model.ModerationFlag) error
+}
+
+func NewModerationRepository(
+	r *Repository,
+) ModerationRepository {
+	return &moderationRepository{
+		Repository: r,
+	}
+}
+
+type moderationRepository struct {
+	*Repository
+}
+
+func (r *moderationRepository) CreateFlag(ctx context.Context, flag *model.ModerationFlag) error {
+	if err := r.DB(ctx).Create(flag).Error; err != nil {
+		return err
+	}
+	return nil
+}
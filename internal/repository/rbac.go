@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go-chat/internal/model"
+	"gorm.io/gorm"
+)
+
+const (
+	rbacVersionKey = "rbac:version"
+	rbacCacheTTL   = 5 * time.Minute
+)
+
+type RBACRepository interface {
+	ListRoles(ctx context.Context) ([]*model.Role, error)
+	FindRoleByName(ctx context.Context, name string) (*model.Role, error)
+	FindRoleById(ctx context.Context, id uint) (*model.Role, error)
+	CreateRole(ctx context.Context, role *model.Role) error
+	SetRolePermissions(ctx context.Context, role *model.Role, permissions []*model.Permission) error
+	FindPermissionsByCodes(ctx context.Context, codes []string) ([]*model.Permission, error)
+	ListPermissions(ctx context.Context) ([]*model.Permission, error)
+
+	AssignUserRole(ctx context.Context, userId uint, roleId uint) error
+	RevokeUserRole(ctx context.Context, userId uint, roleId uint) error
+	ListUserRoleNames(ctx context.Context, userId uint) ([]string, error)
+	ListPermissionCodesByRoleNames(ctx context.Context, roleNames []string) ([]string, error)
+
+	// GetCachedPermissions 缓存未命中时返回 nil, nil
+	GetCachedPermissions(ctx context.Context, userName string) ([]string, error)
+	SetCachedPermissions(ctx context.Context, userName string, codes []string) error
+	// InvalidatePermissionCache 角色或分配变更后使所有用户的权限缓存失效
+	InvalidatePermissionCache(ctx context.Context) error
+}
+
+func NewRBACRepository(
+	r *Repository,
+) RBACRepository {
+	return &rbacRepository{
+		Repository: r,
+	}
+}
+
+type rbacRepository struct {
+	*Repository
+}
+
+func (r *rbacRepository) ListRoles(ctx context.Context) ([]*model.Role, error) {
+	var roles []*model.Role
+	if err := r.DB(ctx).Preload("Permissions").Order("id asc").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (r *rbacRepository) FindRoleByName(ctx context.Context, name string) (*model.Role, error) {
+	var role model.Role
+	if err := r.DB(ctx).Preload("Permissions").Where("name = ?", name).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *rbacRepository) FindRoleById(ctx context.Context, id uint) (*model.Role, error) {
+	var role model.Role
+	if err := r.DB(ctx).Preload("Permissions").Where("id = ?", id).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *rbacRepository) CreateRole(ctx context.Context, role *model.Role) error {
+	if err := r.DB(ctx).Create(role).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *rbacRepository) SetRolePermissions(ctx context.Context, role *model.Role, permissions []*model.Permission) error {
+	if err := r.DB(ctx).Model(role).Association("Permissions").Replace(permissions); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *rbacRepository) FindPermissionsByCodes(ctx context.Context, codes []string) ([]*model.Permission, error) {
+	var permissions []*model.Permission
+	if len(codes) == 0 {
+		return permissions, nil
+	}
+	if err := r.DB(ctx).Where("code IN ?", codes).Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+func (r *rbacRepository) ListPermissions(ctx context.Context) ([]*model.Permission, error) {
+	var permissions []*model.Permission
+	if err := r.DB(ctx).Order("code asc").Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+func (r *rbacRepository) AssignUserRole(ctx context.Context, userId uint, roleId uint) error {
+	userRole := model.UserRole{UserId: userId, RoleId: roleId}
+	if err := r.DB(ctx).Where(&userRole).FirstOrCreate(&userRole).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *rbacRepository) RevokeUserRole(ctx context.Context, userId uint, roleId uint) error {
+	// 物理删除，便于再次分配
+	if err := r.DB(ctx).Unscoped().Where("user_id = ? AND role_id = ?", userId, roleId).Delete(&model.UserRole{}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *rbacRepository) ListUserRoleNames(ctx context.Context, userId uint) ([]string, error) {
+	var names []string
+	if err := r.DB(ctx).Model(&model.Role{}).
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id AND user_roles.delete_at IS NULL").
+		Where("user_roles.user_id = ?", userId).
+		Pluck("roles.name", &names).Error; err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+func (r *rbacRepository) ListPermissionCodesByRoleNames(ctx context.Context, roleNames []string) ([]string, error) {
+	var codes []string
+	if len(roleNames) == 0 {
+		return codes, nil
+	}
+	if err := r.DB(ctx).Model(&model.Permission{}).
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("JOIN roles ON roles.id = role_permissions.role_id AND roles.delete_at IS NULL").
+		Where("roles.name IN ?", roleNames).
+		Distinct().
+		Pluck("permissions.code", &codes).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (r *rbacRepository) GetCachedPermissions(ctx context.Context, userName string) ([]string, error) {
+	key, err := r.permissionCacheKey(ctx, userName)
+	if err != nil {
+		return nil, err
+	}
+	codes, err := r.rdb.SMembers(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(codes) == 0 {
+		return nil, nil
+	}
+	return codes, nil
+}
+
+func (r *rbacRepository) SetCachedPermissions(ctx context.Context, userName string, codes []string) error {
+	key, err := r.permissionCacheKey(ctx, userName)
+	if err != nil {
+		return err
+	}
+	// 没有任何权限时写入空占位，避免每次都查询数据库
+	members := []interface{}{""}
+	for _, c := range codes {
+		members = append(members, c)
+	}
+	pipe := r.rdb.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.SAdd(ctx, key, members...)
+	pipe.Expire(ctx, key, rbacCacheTTL)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (r *rbacRepository) InvalidatePermissionCache(ctx context.Context) error {
+	return r.rdb.Incr(ctx, rbacVersionKey).Err()
+}
+
+// permissionCacheKey 缓存 key 带版本号，版本号递增后旧缓存自然过期
+func (r *rbacRepository) permissionCacheKey(ctx context.Context, userName string) (string, error) {
+	version, err := r.rdb.Get(ctx, rbacVersionKey).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return "", err
+	}
+	return fmt.Sprintf("rbac:permissions:%d:%s", version, userName), nil
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	v1 "go-chat/api/v1"
+	"go-chat/internal/model"
+	"gorm.io/gorm"
+)
+
+type ReportRepository interface {
+	Create(ctx context.Context, report *model.Report) error
+	GetById(ctx context.Context, id uint) (*model.Report, error)
+	List(ctx context.Context, status string, offset int, limit int) ([]*model.Report, int64, error)
+	Claim(ctx context.Context, id uint, moderatorId uint, claimedAt time.Time) (bool, error)
+	Resolve(ctx context.Context, id uint, moderatorId uint, action string, resolvedAt time.Time) (bool, error)
+	CountReporters(ctx context.Context, targetUserId uint, since time.Time) (int64, error)
+
+	CreateAction(ctx context.Context, action *model.ModerationAction) error
+	ListActions(ctx context.Context, reportId uint) ([]*model.ModerationAction, error)
+
+	CreateRestriction(ctx context.Context, restriction *model.UserRestriction) error
+	FindActiveRestriction(ctx context.Context, userId uint, restrictionType string, now time.Time) (*model.UserRestriction, error)
+	ListActiveRestrictions(ctx context.Context, userId uint, now time.Time) ([]*model.UserRestriction, error)
+}
+
+func NewReportRepository(
+	r *Repository,
+) ReportRepository {
+	return &reportRepository{
+		Repository: r,
+	}
+}
+
+type reportRepository struct {
+	*Repository
+}
+
+func (r *reportRepository) Create(ctx context.Context, report *model.Report) error {
+	if err := r.DB(ctx).Create(report).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *reportRepository) GetById(ctx context.Context, id uint) (*model.Report, error) {
+	var report model.Report
+	if err := r.DB(ctx).Where("id = ?", id).First(&report).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, v1.ErrReportNotFound
+		}
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (r *reportRepository) List(ctx context.Context, status string, offset int, limit int) ([]*model.Report, int64, error) {
+	var (
+		reports []*model.Report
+		total   int64
+	)
+	db := r.DB(ctx).Model(&model.Report{})
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	// 先处理最早的举报
+	if err := db.Order("id asc").Offset(offset).Limit(limit).Find(&reports).Error; err != nil {
+		return nil, 0, err
+	}
+	return reports, total, nil
+}
+
+// Claim 认领举报，未被认领或已被自己认领时成功
+func (r *reportRepository) Claim(ctx context.Context, id uint, moderatorId uint, claimedAt time.Time) (bool, error) {
+	tx := r.DB(ctx).Model(&model.Report{}).
+		Where("id = ? AND (status = ? OR (status = ? AND moderator_id = ?))", id, model.ReportStatusPending, model.ReportStatusClaimed, moderatorId).
+		Updates(map[string]interface{}{
+			"status":       model.ReportStatusClaimed,
+			"moderator_id": moderatorId,
+			"claimed_at":   claimedAt,
+		})
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+	return tx.RowsAffected == 1, nil
+}
+
+// Resolve 处理举报，只有认领人可以处理
+func (r *reportRepository) Resolve(ctx context.Context, id uint, moderatorId uint, action string, resolvedAt time.Time) (bool, error) {
+	tx := r.DB(ctx).Model(&model.Report{}).
+		Where("id = ? AND status = ? AND moderator_id = ?", id, model.ReportStatusClaimed, moderatorId).
+		Updates(map[string]interface{}{
+			"status":      model.ReportStatusResolved,
+			"action":      action,
+			"resolved_at": resolvedAt,
+		})
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+	return tx.RowsAffected == 1, nil
+}
+
+// CountReporters 统计 since 之后举报过该用户的不同举报人数量
+func (r *reportRepository) CountReporters(ctx context.Context, targetUserId uint, since time.Time) (int64, error) {
+	var count int64
+	if err := r.DB(ctx).Model(&model.Report{}).
+		Where("target_user_id = ? AND create_at >= ?", targetUserId, since).
+		Distinct("reporter_id").
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *reportRepository) CreateAction(ctx context.Context, action *model.ModerationAction) error {
+	if err := r.DB(ctx).Create(action).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *reportRepository) ListActions(ctx context.Context, reportId uint) ([]*model.ModerationAction, error) {
+	var actions []*model.ModerationAction
+	if err := r.DB(ctx).Where("report_id = ?", reportId).Order("id asc").Find(&actions).Error; err != nil {
+		return nil, err
+	}
+	return actions, nil
+}
+
+func (r *reportRepository) CreateRestriction(ctx context.Context, restriction *model.UserRestriction) error {
+	if err := r.DB(ctx).Create(restriction).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// FindActiveRestriction 查询用户当前生效的处罚，没有时返回 nil
+func (r *reportRepository) FindActiveRestriction(ctx context.Context, userId uint, restrictionType string, now time.Time) (*model.UserRestriction, error) {
+	var restriction model.UserRestriction
+	if err := r.DB(ctx).
+		Where("user_id = ? AND type = ? AND (expires_at IS NULL OR expires_at > ?)", userId, restrictionType, now).
+		Order("id desc").
+		First(&restriction).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &restriction, nil
+}
+
+func (r *reportRepository) ListActiveRestrictions(ctx context.Context, userId uint, now time.Time) ([]*model.UserRestriction, error) {
+	var restrictions []*model.UserRestriction
+	if err := r.DB(ctx).
+		Where("user_id = ? AND (expires_at IS NULL OR expires_at > ?)", userId, now).
+		Order("id desc").
+		Find(&restrictions).Error; err != nil {
+		return nil, err
+	}
+	return restrictions, nil
+}
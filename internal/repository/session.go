@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-chat/internal/model"
+	"gorm.io/gorm"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *model.Session) error
+	// Touch 刷新 token 时更新会话的最近活跃时间、IP 和过期时间
+	Touch(ctx context.Context, sessionId string, clientIp string, lastSeenAt time.Time, expiresAt time.Time) error
+	// ListActive 返回用户未吊销且未过期的会话，最近活跃的在前
+	ListActive(ctx context.Context, userId uint, now time.Time) ([]*model.Session, error)
+	// FindActive 没有时返回 nil
+	FindActive(ctx context.Context, userId uint, sessionId string, now time.Time) (*model.Session, error)
+	Rename(ctx context.Context, id uint, name string) error
+	Revoke(ctx context.Context, sessionIds []string, revokedAt time.Time) error
+	// RevokeUserSessions 返回本次吊销的会话 ID
+	RevokeUserSessions(ctx context.Context, userId uint, revokedAt time.Time) ([]string, error)
+}
+
+func NewSessionRepository(
+	repository *Repository,
+) SessionRepository {
+	return &sessionRepository{
+		Repository: repository,
+	}
+}
+
+type sessionRepository struct {
+	*Repository
+}
+
+func (r *sessionRepository) Create(ctx context.Context, session *model.Session) error {
+	if err := r.DB(ctx).Create(session).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *sessionRepository) Touch(ctx context.Context, sessionId string, clientIp string, lastSeenAt time.Time, expiresAt time.Time) error {
+	updates := map[string]interface{}{
+		"last_seen_at": lastSeenAt,
+		"expires_at":   expiresAt,
+	}
+	if clientIp != "" {
+		updates["client_ip"] = clientIp
+	}
+	if err := r.DB(ctx).Model(&model.Session{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionId).
+		Updates(updates).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *sessionRepository) ListActive(ctx context.Context, userId uint, now time.Time) ([]*model.Session, error) {
+	var sessions []*model.Session
+	if err := r.DB(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userId, now).
+		Order("last_seen_at desc").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *sessionRepository) FindActive(ctx context.Context, userId uint, sessionId string, now time.Time) (*model.Session, error) {
+	var session model.Session
+	if err := r.DB(ctx).
+		Where("user_id = ? AND session_id = ? AND revoked_at IS NULL AND expires_at > ?", userId, sessionId, now).
+		First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sessionRepository) Rename(ctx context.Context, id uint, name string) error {
+	if err := r.DB(ctx).Model(&model.Session{}).Where("id = ?", id).Update("name", name).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *sessionRepository) Revoke(ctx context.Context, sessionIds []string, revokedAt time.Time) error {
+	if len(sessionIds) == 0 {
+		return nil
+	}
+	if err := r.DB(ctx).Model(&model.Session{}).
+		Where("session_id IN ? AND revoked_at IS NULL", sessionIds).
+		Update("revoked_at", revokedAt).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *sessionRepository) RevokeUserSessions(ctx context.Context, userId uint, revokedAt time.Time) ([]string, error) {
+	var sessionIds []string
+	if err := r.DB(ctx).Model(&model.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Pluck("session_id", &sessionIds).Error; err != nil {
+		return nil, err
+	}
+	if len(sessionIds) == 0 {
+		return nil, nil
+	}
+	if err := r.DB(ctx).Model(&model.Session{}).
+		Where("session_id IN ? AND revoked_at IS NULL", sessionIds).
+		Update("revoked_at", revokedAt).Error; err != nil {
+		return nil, err
+	}
+	return sessionIds, nil
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go-chat/internal/model"
+	"gorm.io/gorm"
+)
+
+// tokenRevokeTTL 远大于可配置的 access token 有效期，过期后之前签发的 token 本身已失效
+const tokenRevokeTTL = time.Hour * 24 * 90
+
+type TokenRepository interface {
+	// SetRevokedBefore 使用户在 t 及之前签发的 access token 全部失效，按不会变化的用户ID记录
+	SetRevokedBefore(ctx context.Context, userId uint, t time.Time) error
+	// RevokeAccessToken 吊销单个 access token，ttl 为其剩余有效期
+	RevokeAccessToken(ctx context.Context, tokenId string, ttl time.Duration) error
+	// RevokeSession 吊销会话下所有 access token，ttl 为 access token 的有效期
+	RevokeSession(ctx context.Context, sessionId string, ttl time.Duration) error
+	// GetRevocation 返回用户的 revoked-before 时间（没有时为零值）以及 tokenId 或其所属会话是否被单独吊销
+	GetRevocation(ctx context.Context, userId uint, sessionId string, tokenId string) (time.Time, bool, error)
+
+	CreateRefreshToken(ctx context.Context, token *model.RefreshToken) error
+	FindRefreshTokenByHash(ctx context.Context, hash string) (*model.RefreshToken, error)
+	// MarkRefreshTokenUsed 轮换时标记旧 token，已被使用或吊销时返回 false
+	MarkRefreshTokenUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error)
+	// RevokeFamily 返回家族中在 since 之后签发的 access token 的 jti
+	RevokeFamily(ctx context.Context, familyId string, revokedAt time.Time, since time.Time) ([]string, error)
+	// RevokeUserRefreshTokens 吊销用户所有未失效的 refresh token
+	RevokeUserRefreshTokens(ctx context.Context, userId uint, revokedAt time.Time) error
+	// FindAccessTokenIds 返回用户在 since 之后签发的 access token 的 jti
+	FindAccessTokenIds(ctx context.Context, userId uint, since time.Time) ([]string, error)
+}
+
+func NewTokenRepository(
+	repository *Repository,
+) TokenRepository {
+	return &tokenRepository{
+		Repository: repository,
+	}
+}
+
+type tokenRepository struct {
+	*Repository
+}
+
+func (r *tokenRepository) SetRevokedBefore(ctx context.Context, userId uint, t time.Time) error {
+	return r.rdb.Set(ctx, revokedBeforeKey(userId), t.Unix(), tokenRevokeTTL).Err()
+}
+
+func (r *tokenRepository) RevokeAccessToken(ctx context.Context, tokenId string, ttl time.Duration) error {
+	return r.rdb.Set(ctx, "token:revoked:"+tokenId, 1, ttl).Err()
+}
+
+func (r *tokenRepository) RevokeSession(ctx context.Context, sessionId string, ttl time.Duration) error {
+	return r.rdb.Set(ctx, "session:revoked:"+sessionId, 1, ttl).Err()
+}
+
+func (r *tokenRepository) GetRevocation(ctx context.Context, userId uint, sessionId string, tokenId string) (time.Time, bool, error) {
+	pipe := r.rdb.Pipeline()
+	before := pipe.Get(ctx, revokedBeforeKey(userId))
+	var keys []string
+	if tokenId != "" {
+		keys = append(keys, "token:revoked:"+tokenId)
+	}
+	if sessionId != "" {
+		keys = append(keys, "session:revoked:"+sessionId)
+	}
+	var revoked *redis.IntCmd
+	if len(keys) > 0 {
+		revoked = pipe.Exists(ctx, keys...)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return time.Time{}, false, err
+	}
+
+	var revokedBefore time.Time
+	if unix, err := before.Int64(); err == nil {
+		revokedBefore = time.Unix(unix, 0)
+	} else if !errors.Is(err, redis.Nil) {
+		return time.Time{}, false, err
+	}
+	return revokedBefore, revoked != nil && revoked.Val() > 0, nil
+}
+
+func (r *tokenRepository) CreateRefreshToken(ctx context.Context, token *model.RefreshToken) error {
+	if err := r.DB(ctx).Create(token).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *tokenRepository) FindRefreshTokenByHash(ctx context.Context, hash string) (*model.RefreshToken, error) {
+	var token model.RefreshToken
+	if err := r.DB(ctx).Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *tokenRepository) MarkRefreshTokenUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error) {
+	tx := r.DB(ctx).Model(&model.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update("used_at", usedAt)
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+	return tx.RowsAffected == 1, nil
+}
+
+func (r *tokenRepository) RevokeFamily(ctx context.Context, familyId string, revokedAt time.Time, since time.Time) ([]string, error) {
+	if err := r.DB(ctx).Model(&model.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyId).
+		Update("revoked_at", revokedAt).Error; err != nil {
+		return nil, err
+	}
+	var tokenIds []string
+	if err := r.DB(ctx).Model(&model.RefreshToken{}).
+		Where("family_id = ? AND create_at >= ?", familyId, since).
+		Pluck("access_token_id", &tokenIds).Error; err != nil {
+		return nil, err
+	}
+	return tokenIds, nil
+}
+
+func (r *tokenRepository) RevokeUserRefreshTokens(ctx context.Context, userId uint, revokedAt time.Time) error {
+	if err := r.DB(ctx).Model(&model.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Update("revoked_at", revokedAt).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *tokenRepository) FindAccessTokenIds(ctx context.Context, userId uint, since time.Time) ([]string, error) {
+	var tokenIds []string
+	if err := r.DB(ctx).Model(&model.RefreshToken{}).
+		Where("user_id = ? AND create_at >= ?", userId, since).
+		Pluck("access_token_id", &tokenIds).Error; err != nil {
+		return nil, err
+	}
+	return tokenIds, nil
+}
+
+func revokedBeforeKey(userId uint) string {
+	return "token:revoked_before:" + strconv.FormatUint(uint64(userId), 10)
+}
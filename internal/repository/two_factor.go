@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"go-chat/internal/model"
+	"gorm.io/gorm"
+)
+
+// TwoFactorChallenge 密码或邮箱验证通过、等待二次验证的登录
+type TwoFactorChallenge struct {
+	UserId   uint
+	Method   string
+	Attempts int64
+}
+
+type TwoFactorRepository interface {
+	// FindByUserId 没有时返回 nil
+	FindByUserId(ctx context.Context, userId uint) (*model.TwoFactor, error)
+	// Save 新建或覆盖未启用的配置
+	Save(ctx context.Context, twoFactor *model.TwoFactor) error
+	Enable(ctx context.Context, id uint, enabledAt time.Time, step int64) error
+	Delete(ctx context.Context, userId uint) error
+	// UseStep 记录通过校验的时间步，step 不大于上次使用的时间步时返回 false
+	UseStep(ctx context.Context, id uint, step int64) (bool, error)
+
+	// ReplaceRecoveryCodes 删除旧的恢复码并写入新的
+	ReplaceRecoveryCodes(ctx context.Context, userId uint, hashes []string) error
+	// UseRecoveryCode 恢复码不存在或已使用时返回 false
+	UseRecoveryCode(ctx context.Context, userId uint, hash string, usedAt time.Time) (bool, error)
+	CountRecoveryCodes(ctx context.Context, userId uint) (int64, error)
+
+	SaveChallenge(ctx context.Context, token string, challenge *TwoFactorChallenge, ttl time.Duration) error
+	// GetChallenge 同时增加尝试次数，不存在或已过期时返回 nil
+	GetChallenge(ctx context.Context, token string) (*TwoFactorChallenge, error)
+	DeleteChallenge(ctx context.Context, token string) error
+}
+
+func NewTwoFactorRepository(
+	repository *Repository,
+) TwoFactorRepository {
+	return &twoFactorRepository{
+		Repository: repository,
+	}
+}
+
+type twoFactorRepository struct {
+	*Repository
+}
+
+func (r *twoFactorRepository) FindByUserId(ctx context.Context, userId uint) (*model.TwoFactor, error) {
+	var twoFactor model.TwoFactor
+	if err := r.DB(ctx).Where("user_id = ?", userId).First(&twoFactor).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &twoFactor, nil
+}
+
+func (r *twoFactorRepository) Save(ctx context.Context, twoFactor *model.TwoFactor) error {
+	if err := r.DB(ctx).Save(twoFactor).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *twoFactorRepository) Enable(ctx context.Context, id uint, enabledAt time.Time, step int64) error {
+	if err := r.DB(ctx).Model(&model.TwoFactor{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"enabled_at":     enabledAt,
+		"last_used_step": step,
+	}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *twoFactorRepository) Delete(ctx context.Context, userId uint) error {
+	if err := r.DB(ctx).Unscoped().Where("user_id = ?", userId).Delete(&model.TwoFactor{}).Error; err != nil {
+		return err
+	}
+	if err := r.DB(ctx).Unscoped().Where("user_id = ?", userId).Delete(&model.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *twoFactorRepository) UseStep(ctx context.Context, id uint, step int64) (bool, error) {
+	tx := r.DB(ctx).Model(&model.TwoFactor{}).
+		Where("id = ? AND last_used_step < ?", id, step).
+		Update("last_used_step", step)
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+	return tx.RowsAffected == 1, nil
+}
+
+func (r *twoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userId uint, hashes []string) error {
+	if err := r.DB(ctx).Unscoped().Where("user_id = ?", userId).Delete(&model.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	codes := make([]*model.RecoveryCode, 0, len(hashes))
+	for _, h := range hashes {
+		codes = append(codes, &model.RecoveryCode{UserId: userId, CodeHash: h})
+	}
+	if err := r.DB(ctx).Create(&codes).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *twoFactorRepository) UseRecoveryCode(ctx context.Context, userId uint, hash string, usedAt time.Time) (bool, error) {
+	tx := r.DB(ctx).Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userId, hash).
+		Update("used_at", usedAt)
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+	return tx.RowsAffected == 1, nil
+}
+
+func (r *twoFactorRepository) CountRecoveryCodes(ctx context.Context, userId uint) (int64, error) {
+	var count int64
+	if err := r.DB(ctx).Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userId).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *twoFactorRepository) SaveChallenge(ctx context.Context, token string, challenge *TwoFactorChallenge, ttl time.Duration) error {
+	key := "2fa:challenge:" + token
+	pipe := r.rdb.TxPipeline()
+	pipe.HSet(ctx, key, "user_id", challenge.UserId, "method", challenge.Method, "attempts", 0)
+	pipe.Expire(ctx, key, ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (r *twoFactorRepository) GetChallenge(ctx context.Context, token string) (*TwoFactorChallenge, error) {
+	key := "2fa:challenge:" + token
+	v, err := r.rdb.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	userId, err := strconv.ParseUint(v["user_id"], 10, 64)
+	if err != nil {
+		return nil, nil
+	}
+	// key 在两次调用之间过期时 HIncrBy 会新建一个没有过期时间的 hash，因此只在 key 存在时自增
+	attempts, err := r.rdb.Eval(ctx, `if redis.call("EXISTS", KEYS[1]) == 1 then return redis.call("HINCRBY", KEYS[1], "attempts", 1) end return 0`, []string{key}).Int64()
+	if err != nil {
+		return nil, err
+	}
+	if attempts == 0 {
+		return nil, nil
+	}
+	return &TwoFactorChallenge{UserId: uint(userId), Method: v["method"], Attempts: attempts}, nil
+}
+
+func (r *twoFactorRepository) DeleteChallenge(ctx context.Context, token string) error {
+	return r.rdb.Del(ctx, "2fa:challenge:"+token).Err()
+}
@@ -6,6 +6,8 @@ import (
 	v1 "go-chat/api/v1"
 	"go-chat/internal/model"
 	"gorm.io/gorm"
+	"strings"
+	"time"
 )
 
 type UserRepository interface {
@@ -21,6 +23,52 @@ type UserRepository interface {
 	FindUserInfoByName(ctx context.Context, name string) (*model.UserBasics, error)
 	UpdateUserInfo(ctx context.Context, userInfo *model.UserBasics) error
 	FindUserInfoById(ctx context.Context, id uint) (*model.UserBasics, error)
+	ClearProfileContent(ctx context.Context, id uint) error
+	// FindByVerifiedPhone 只查找验证过的手机号，没有时返回 nil
+	FindByVerifiedPhone(ctx context.Context, phone string) (*model.UserBasics, error)
+	UpdatePhone(ctx context.Context, id uint, phone string, verifiedAt time.Time) error
+	// FindByVerifiedEmail 只查找验证过的邮箱，没有时返回 nil
+	FindByVerifiedEmail(ctx context.Context, email string) (*model.UserBasics, error)
+	// UpdateEmail 写入通过验证码确认的邮箱
+	UpdateEmail(ctx context.Context, id uint, email string, verifiedAt time.Time) error
+	// Discover 用户之间的搜索，只返回允许被对应方式搜索到、未禁用、未封禁且未申请注销的用户
+	Discover(ctx context.Context, filter *DiscoverFilter, offset int, limit int) ([]*model.UserBasics, int64, error)
+	UpdateDiscoverability(ctx context.Context, id uint, byName bool, byEmail bool, byPhone bool) error
+
+	// 后台管理，以下查询包含已注销（软删除）的用户
+	Search(ctx context.Context, filter *UserFilter, offset int, limit int) ([]*model.UserBasics, int64, error)
+	FindUnscopedById(ctx context.Context, id uint) (*model.UserBasics, error)
+	SetDisabledAt(ctx context.Context, id uint, disabledAt *time.Time) error
+	SetLoginOut(ctx context.Context, id uint, loginOutTime time.Time) error
+	UpdatePassword(ctx context.Context, id uint, password string, salt string) error
+	// UpdateLoginInfo 登录成功后记录时间、IP 和设备
+	UpdateLoginInfo(ctx context.Context, id uint, loginTime time.Time, clientIp string, deviceInfo string) error
+	Delete(ctx context.Context, id uint) error
+	Restore(ctx context.Context, id uint) error
+}
+
+// 后台用户列表的账号状态筛选
+const (
+	UserStatusActive   = "active"
+	UserStatusDisabled = "disabled"
+	UserStatusDeleted  = "deleted"
+)
+
+type UserFilter struct {
+	// Keyword 模糊匹配用户名、邮箱、手机号
+	Keyword  string
+	Identity string
+	Status   string
+}
+
+// DiscoverFilter Email、Phone、NamePrefix 三选一
+type DiscoverFilter struct {
+	Email      string
+	Phone      string
+	NamePrefix string
+	// ExcludeId 发起搜索的用户，不出现在结果中
+	ExcludeId uint
+	Now       time.Time
 }
 
 func NewUserRepository(
@@ -115,9 +163,7 @@ func (r *userRepository) UpdateUserInfo(ctx context.Context, userInfo *model.Use
 	if err := r.DB(ctx).Where(" id= ?", userInfo.ID).Updates(model.UserBasics{
 		Avatar:   userInfo.Avatar,
 		ClientIp: userInfo.ClientIp,
-		Email:    userInfo.Email,
 		Motto:    userInfo.Motto,
-		Phone:    userInfo.Phone,
 		Name:     userInfo.Name,
 	}).Error; err != nil {
 		return err
@@ -146,3 +192,196 @@ func (r *userRepository) FindUserByEmailWithLogin(ctx context.Context, email str
 	}
 	return &user, nil
 }
+
+func (r *userRepository) FindByVerifiedPhone(ctx context.Context, phone string) (*model.UserBasics, error) {
+	var user model.UserBasics
+	if err := r.DB(ctx).Where("phone = ? AND phone_verified_at IS NOT NULL", phone).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *userRepository) UpdatePhone(ctx context.Context, id uint, phone string, verifiedAt time.Time) error {
+	if err := r.DB(ctx).Model(&model.UserBasics{}).Where(" id= ?", id).Updates(map[string]interface{}{
+		"phone":             phone,
+		"phone_verified_at": verifiedAt,
+	}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *userRepository) FindByVerifiedEmail(ctx context.Context, email string) (*model.UserBasics, error) {
+	var user model.UserBasics
+	if err := r.DB(ctx).Where("email = ? AND email_verified_at IS NOT NULL", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *userRepository) UpdateEmail(ctx context.Context, id uint, email string, verifiedAt time.Time) error {
+	if err := r.DB(ctx).Model(&model.UserBasics{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"email":             email,
+		"email_verified_at": verifiedAt,
+	}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// ClearProfileContent 清空用户自行填写的资料内容（签名、头像）
+func (r *userRepository) ClearProfileContent(ctx context.Context, id uint) error {
+	if err := r.DB(ctx).Model(&model.UserBasics{}).Where(" id= ?", id).Updates(map[string]interface{}{
+		"motto":  "",
+		"avatar": "",
+	}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *userRepository) Search(ctx context.Context, filter *UserFilter, offset int, limit int) ([]*model.UserBasics, int64, error) {
+	db := r.DB(ctx).Model(&model.UserBasics{})
+	switch filter.Status {
+	case UserStatusActive:
+		db = db.Where("disabled_at IS NULL")
+	case UserStatusDisabled:
+		db = db.Where("disabled_at IS NOT NULL")
+	case UserStatusDeleted:
+		db = db.Unscoped().Where("delete_at IS NOT NULL")
+	}
+	if filter.Keyword != "" {
+		like := "%" + escapeLike(filter.Keyword) + "%"
+		db = db.Where("(name LIKE ? ESCAPE '!' OR email LIKE ? ESCAPE '!' OR phone LIKE ? ESCAPE '!')", like, like, like)
+	}
+	if filter.Identity != "" {
+		db = db.Where("identity = ?", filter.Identity)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var users []*model.UserBasics
+	if err := db.Order("id desc").Offset(offset).Limit(limit).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+func (r *userRepository) Discover(ctx context.Context, filter *DiscoverFilter, offset int, limit int) ([]*model.UserBasics, int64, error) {
+	db := r.DB(ctx).Model(&model.UserBasics{}).
+		Where("id <> ? AND disabled_at IS NULL AND deletion_scheduled_at IS NULL", filter.ExcludeId).
+		Where("NOT EXISTS (?)", r.DB(ctx).Model(&model.UserRestriction{}).
+			Select("1").
+			Where("user_restrictions.user_id = user_basics.id AND user_restrictions.type = ?", model.ModerationActionBan).
+			Where("user_restrictions.expires_at IS NULL OR user_restrictions.expires_at > ?", filter.Now))
+	switch {
+	case filter.Email != "":
+		db = db.Where("email = ? AND email_verified_at IS NOT NULL AND discoverable_by_email = ?", filter.Email, true)
+	case filter.Phone != "":
+		db = db.Where("phone = ? AND phone_verified_at IS NOT NULL AND discoverable_by_phone = ?", filter.Phone, true)
+	case filter.NamePrefix != "":
+		db = db.Where("name LIKE ? ESCAPE '!' AND discoverable_by_name = ?", escapeLike(filter.NamePrefix)+"%", true)
+	default:
+		return nil, 0, nil
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var users []*model.UserBasics
+	if err := db.Order("name").Offset(offset).Limit(limit).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+func (r *userRepository) UpdateDiscoverability(ctx context.Context, id uint, byName bool, byEmail bool, byPhone bool) error {
+	if err := r.DB(ctx).Model(&model.UserBasics{}).Where(" id= ?", id).Updates(map[string]interface{}{
+		"discoverable_by_name":  byName,
+		"discoverable_by_email": byEmail,
+		"discoverable_by_phone": byPhone,
+	}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// escapeLike 转义 LIKE 中的通配符，用户输入按字面匹配。
+// 用 ! 作为转义符，MySQL、PostgreSQL 和 SQLite 中写法一致
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
+func (r *userRepository) FindUnscopedById(ctx context.Context, id uint) (*model.UserBasics, error) {
+	var user model.UserBasics
+	if err := r.DB(ctx).Unscoped().Where(" id= ?", id).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *userRepository) SetDisabledAt(ctx context.Context, id uint, disabledAt *time.Time) error {
+	if err := r.DB(ctx).Model(&model.UserBasics{}).Where(" id= ?", id).Update("disabled_at", disabledAt).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *userRepository) SetLoginOut(ctx context.Context, id uint, loginOutTime time.Time) error {
+	if err := r.DB(ctx).Model(&model.UserBasics{}).Where(" id= ?", id).Updates(map[string]interface{}{
+		"is_login_out":   1,
+		"login_out_time": loginOutTime,
+	}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *userRepository) UpdatePassword(ctx context.Context, id uint, password string, salt string) error {
+	if err := r.DB(ctx).Model(&model.UserBasics{}).Where(" id= ?", id).Updates(map[string]interface{}{
+		"pass_word": password,
+		"salt":      salt,
+	}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// Delete 软删除，写入 delete_at
+func (r *userRepository) Delete(ctx context.Context, id uint) error {
+	if err := r.DB(ctx).Where(" id= ?", id).Delete(&model.UserBasics{}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *userRepository) Restore(ctx context.Context, id uint) error {
+	if err := r.DB(ctx).Unscoped().Model(&model.UserBasics{}).Where(" id= ?", id).Update("delete_at", nil).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *userRepository) UpdateLoginInfo(ctx context.Context, id uint, loginTime time.Time, clientIp string, deviceInfo string) error {
+	if err := r.DB(ctx).Model(&model.UserBasics{}).Where(" id= ?", id).Updates(map[string]interface{}{
+		"login_time":   loginTime,
+		"client_ip":    clientIp,
+		"device_info":  deviceInfo,
+		"is_login_out": 0,
+	}).Error; err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"github.com/spf13/viper"
+	pb "go-chat/api/proto/v1"
+	"go-chat/internal/handler"
+	"go-chat/internal/middleware"
+	"go-chat/internal/service"
+	"go-chat/pkg/jwt"
+	"go-chat/pkg/log"
+	"go-chat/pkg/server/grpc"
+	grpcgo "google.golang.org/grpc"
+)
+
+func NewGRPCServer(
+	logger *log.Logger,
+	conf *viper.Viper,
+	jwt *jwt.JWT,
+	tokenService service.TokenService,
+	userGrpcHandler *handler.UserGrpcHandler,
+	chatGrpcHandler *handler.ChatGrpcHandler,
+) *grpc.Server {
+	s := grpc.NewServer(
+		logger,
+		grpc.WithServerHost(conf.GetString("grpc.host")),
+		grpc.WithServerPort(conf.GetInt("grpc.port")),
+		grpc.WithServerOptions(
+			grpcgo.ChainUnaryInterceptor(
+				middleware.GrpcUnaryLog(logger),
+				middleware.GrpcUnaryAuth(jwt, tokenService, logger,
+					pb.UserService_Register_FullMethodName,
+					pb.UserService_CheckRegisterEmailCode_FullMethodName,
+					pb.UserService_Login_FullMethodName,
+					pb.UserService_EmailLogin_FullMethodName,
+					pb.UserService_EmailLoginCodeCheck_FullMethodName,
+					pb.UserService_Refresh_FullMethodName,
+					pb.UserService_VerifyTwoFactor_FullMethodName,
+				),
+			),
+			grpcgo.ChainStreamInterceptor(
+				middleware.GrpcStreamLog(logger),
+				middleware.GrpcStreamAuth(jwt, tokenService, logger),
+			),
+		),
+	)
+
+	pb.RegisterUserServiceServer(s, userGrpcHandler)
+	pb.RegisterChatServiceServer(s, chatGrpcHandler)
+
+	return s
+}
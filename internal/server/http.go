@@ -9,8 +9,11 @@ import (
 	"go-chat/docs"
 	"go-chat/internal/handler"
 	"go-chat/internal/middleware"
+	"go-chat/internal/model"
+	"go-chat/internal/service"
 	"go-chat/pkg/jwt"
 	"go-chat/pkg/log"
+	"go-chat/pkg/ratelimit"
 	"go-chat/pkg/server/http"
 )
 
@@ -18,12 +21,37 @@ func NewHTTPServer(
 	logger *log.Logger,
 	conf *viper.Viper,
 	jwt *jwt.JWT,
+	limiter ratelimit.Limiter,
 	userHandler *handler.UserHandler,
 	uploadHandler *handler.UploadHandler,
+	eventHandler *handler.EventHandler,
+	reportHandler *handler.ReportHandler,
+	rbacHandler *handler.RBACHandler,
+	adminUserHandler *handler.AdminUserHandler,
+	auditHandler *handler.AuditHandler,
+	authHandler *handler.AuthHandler,
+	sessionHandler *handler.SessionHandler,
+	securityHandler *handler.SecurityHandler,
+	twoFactorHandler *handler.TwoFactorHandler,
+	oidcHandler *handler.OIDCHandler,
+	emailQueueHandler *handler.EmailQueueHandler,
+	accountHandler *handler.AccountHandler,
+	rbacService service.RBACService,
+	tokenService service.TokenService,
 ) *http.Server {
 	gin.SetMode(gin.DebugMode)
+	policies, err := ratelimit.NewPolicies(conf)
+	if err != nil {
+		panic(err)
+	}
+	engine := gin.Default()
+	// 只信任配置中的反向代理转发的 X-Forwarded-For，默认不信任任何代理，
+	// 否则客户端可以伪造来源 IP 绕过按 IP 的限流并污染审计日志
+	if err := engine.SetTrustedProxies(conf.GetStringSlice("http.trusted_proxies")); err != nil {
+		panic(err)
+	}
 	s := http.NewServer(
-		gin.Default(),
+		engine,
 		logger,
 		http.WithServerHost(conf.GetString("http.host")),
 		http.WithServerPort(conf.GetInt("http.port")),
@@ -42,6 +70,7 @@ func NewHTTPServer(
 		middleware.CORSMiddleware(),
 		middleware.ResponseLogMiddleware(logger),
 		middleware.RequestLogMiddleware(logger),
+		middleware.ClientInfo(),
 		//middleware.SignMiddleware(log),
 	)
 	s.GET("/", func(ctx *gin.Context) {
@@ -51,7 +80,9 @@ func NewHTTPServer(
 		})
 	})
 
+	strictAuth := middleware.StrictAuth(jwt, tokenService, logger)
 	v1 := s.Group("/v1")
+	v1.Use(middleware.RateLimit(limiter, jwt, logger, policies))
 
 	user := v1.Group("/user")
 	{
@@ -60,16 +91,100 @@ func NewHTTPServer(
 		user.POST("/login", userHandler.Login)
 		user.POST("email_login_code_check", userHandler.EmailLoginCodeCheck)
 		user.POST("/email_login", userHandler.EmailLogin)
-		auth := user.Group("/").Use(middleware.StrictAuth(jwt, logger))
+		user.POST("/sms_login", userHandler.SmsLogin)
+		user.POST("/sms_login_code_check", userHandler.SmsLoginCodeCheck)
+		user.POST("/forgot_password", userHandler.ForgotPassword)
+		user.POST("/reset_password", userHandler.ResetPassword)
+		auth := user.Group("/").Use(strictAuth)
 		{
 			auth.POST("/user_info_update", userHandler.UserInfoUpdate)
+			auth.GET("/security_activity", auditHandler.SecurityActivity)
+			auth.GET("/search", userHandler.SearchUsers)
+			auth.GET("/discoverability", userHandler.Discoverability)
+			auth.PUT("/discoverability", userHandler.UpdateDiscoverability)
+			auth.POST("/phone/send_code", userHandler.SendPhoneCode)
+			auth.POST("/phone/verify", userHandler.VerifyPhone)
+			auth.POST("/email/send_code", userHandler.SendEmailCode)
+			auth.POST("/email/verify", userHandler.VerifyEmail)
+			auth.GET("/sessions", sessionHandler.ListSessions)
+			auth.PUT("/sessions/:id", sessionHandler.RenameSession)
+			auth.DELETE("/sessions/:id", sessionHandler.RevokeSession)
+			auth.POST("/sessions/revoke_others", sessionHandler.RevokeOtherSessions)
+			auth.GET("/2fa", twoFactorHandler.Status)
+			auth.POST("/2fa/enroll", twoFactorHandler.Enroll)
+			auth.POST("/2fa/confirm", twoFactorHandler.Confirm)
+			auth.POST("/2fa/disable", twoFactorHandler.Disable)
+			auth.POST("/2fa/recovery_codes", twoFactorHandler.RegenerateRecoveryCodes)
+			auth.GET("/account/deletion", accountHandler.DeletionStatus)
+			auth.POST("/account/deletion", accountHandler.RequestDeletion)
+			auth.DELETE("/account/deletion", accountHandler.CancelDeletion)
+			auth.POST("/data_export", accountHandler.RequestExport)
+			auth.GET("/data_export", accountHandler.LatestExport)
+			auth.GET("/data_export/download", accountHandler.DownloadExport)
 		}
 	}
 
-	upload := v1.Group("/upload").Use(middleware.StrictAuth(jwt, logger))
+	security := v1.Group("/security")
+	{
+		security.GET("/public_key", securityHandler.PublicKey)
+	}
+
+	auth := v1.Group("/auth")
+	{
+		auth.POST("/refresh", authHandler.Refresh)
+		auth.POST("/2fa/verify", twoFactorHandler.Verify)
+		auth.GET("/oidc/providers", oidcHandler.Providers)
+		auth.POST("/oidc/:provider/authorize", oidcHandler.Authorize)
+		auth.POST("/oidc/:provider/callback", oidcHandler.Callback)
+	}
+
+	upload := v1.Group("/upload").Use(strictAuth)
 	{
 		upload.POST("/file", uploadHandler.Upload)
 	}
 
+	report := v1.Group("/report").Use(strictAuth)
+	{
+		report.POST("", reportHandler.CreateReport)
+	}
+
+	admin := v1.Group("/admin").Use(strictAuth)
+	{
+		reviewReports := middleware.RequirePermission(rbacService, logger, model.PermissionReportsReview)
+		admin.GET("/reports", reviewReports, reportHandler.ListReports)
+		admin.GET("/reports/:id", reviewReports, reportHandler.GetReport)
+		admin.POST("/reports/:id/claim", reviewReports, reportHandler.ClaimReport)
+		admin.POST("/reports/:id/resolve", reviewReports, reportHandler.ResolveReport)
+
+		manageRoles := middleware.RequirePermission(rbacService, logger, model.PermissionRolesManage)
+		admin.GET("/roles", manageRoles, rbacHandler.ListRoles)
+		admin.POST("/roles", manageRoles, rbacHandler.CreateRole)
+		admin.PUT("/roles/:id/permissions", manageRoles, rbacHandler.SetRolePermissions)
+		admin.GET("/permissions", manageRoles, rbacHandler.ListPermissions)
+		admin.POST("/users/:id/roles", manageRoles, rbacHandler.AssignUserRole)
+		admin.DELETE("/users/:id/roles/:role", manageRoles, rbacHandler.RevokeUserRole)
+
+		readUsers := middleware.RequirePermission(rbacService, logger, model.PermissionUsersRead)
+		manageUsers := middleware.RequirePermission(rbacService, logger, model.PermissionUsersManage)
+		admin.GET("/users", readUsers, adminUserHandler.ListUsers)
+		admin.GET("/users/:id", readUsers, adminUserHandler.GetUser)
+		admin.POST("/users/:id/logout", manageUsers, adminUserHandler.ForceLogout)
+		admin.POST("/users/:id/disable", manageUsers, adminUserHandler.DisableUser)
+		admin.POST("/users/:id/enable", manageUsers, adminUserHandler.EnableUser)
+		admin.POST("/users/:id/reset_password", manageUsers, adminUserHandler.ResetPassword)
+		admin.DELETE("/users/:id", manageUsers, adminUserHandler.DeleteUser)
+		admin.POST("/users/:id/restore", manageUsers, adminUserHandler.RestoreUser)
+
+		admin.GET("/audit_logs", middleware.RequirePermission(rbacService, logger, model.PermissionAuditRead), auditHandler.ListAuditLogs)
+		admin.GET("/email_queue", middleware.RequirePermission(rbacService, logger, model.PermissionMailRead), emailQueueHandler.QueueStatus)
+	}
+
+	// WebSocket 被拦截时的实时事件降级通道
+	events := v1.Group("/events").Use(strictAuth)
+	{
+		events.GET("/stream", eventHandler.Stream)
+		events.GET("/poll", eventHandler.Poll)
+	}
+
 	return s
 }
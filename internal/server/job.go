@@ -2,24 +2,110 @@ package server
 
 import (
 	"context"
+	"sync"
+	"time"
+
+	"go-chat/internal/service"
+	"go-chat/pkg/event"
 	"go-chat/pkg/log"
+	"go.uber.org/zap"
 )
 
+// Job 后台任务：投递邮件队列，生成数据导出，清除到期注销的账号，清理实时事件的历史缓存
 type Job struct {
-	log *log.Logger
+	log               *log.Logger
+	hub               *event.Hub
+	emailQueueService service.EmailQueueService
+	accountService    service.AccountService
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
 }
 
 func NewJob(
 	log *log.Logger,
+	hub *event.Hub,
+	emailQueueService service.EmailQueueService,
+	accountService service.AccountService,
 ) *Job {
 	return &Job{
-		log: log,
+		log:               log,
+		hub:               hub,
+		emailQueueService: emailQueueService,
+		accountService:    accountService,
+		stop:              make(chan struct{}),
+		done:              make(chan struct{}),
 	}
 }
 func (j *Job) Start(ctx context.Context) error {
-	// eg: kafka consumer
-	return nil
+	defer close(j.done)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-j.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	emailTicker := time.NewTicker(j.emailQueueService.PollInterval())
+	defer emailTicker.Stop()
+	accountTicker := time.NewTicker(j.accountService.JobInterval())
+	defer accountTicker.Stop()
+	hubTicker := time.NewTicker(event.PruneInterval)
+	defer hubTicker.Stop()
+	j.deliverEmails(ctx)
+	j.runAccountTasks(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-emailTicker.C:
+			j.deliverEmails(ctx)
+		case <-accountTicker.C:
+			j.runAccountTasks(ctx)
+		case now := <-hubTicker.C:
+			j.hub.Prune(now)
+		}
+	}
 }
+
+func (j *Job) runAccountTasks(ctx context.Context) {
+	if _, err := j.accountService.ProcessExports(ctx); err != nil {
+		j.log.Error("process data exports error", zap.Error(err))
+	}
+	if _, err := j.accountService.PurgeDeletions(ctx); err != nil {
+		j.log.Error("purge deleted accounts error", zap.Error(err))
+	}
+	if _, err := j.accountService.CleanupExports(ctx); err != nil {
+		j.log.Error("cleanup data exports error", zap.Error(err))
+	}
+}
+
+// deliverEmails 一直投递到队列中没有到期的邮件
+func (j *Job) deliverEmails(ctx context.Context) {
+	for ctx.Err() == nil {
+		n, err := j.emailQueueService.DeliverDue(ctx)
+		if err != nil {
+			j.log.Error("deliver emails error", zap.Error(err))
+			return
+		}
+		if n == 0 {
+			return
+		}
+	}
+}
+
 func (j *Job) Stop(ctx context.Context) error {
+	j.stopOnce.Do(func() { close(j.stop) })
+	select {
+	case <-j.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(30 * time.Second):
+		j.log.Warn("job stop timeout")
+	}
 	return nil
 }
@@ -21,14 +21,71 @@ func NewMigrate(db *gorm.DB, log *log.Logger) *Migrate {
 	}
 }
 func (m *Migrate) Start(ctx context.Context) error {
-	if err := m.db.AutoMigrate(&model.User{}); err != nil {
+	if err := m.db.AutoMigrate(
+		&model.User{},
+		&model.UserBasics{},
+		&model.ModerationFlag{},
+		&model.Report{},
+		&model.ModerationAction{},
+		&model.UserRestriction{},
+		&model.Permission{},
+		&model.Role{},
+		&model.UserRole{},
+		&model.AuditLog{},
+		&model.RefreshToken{},
+		&model.Session{},
+		&model.TwoFactor{},
+		&model.RecoveryCode{},
+		&model.UserIdentity{},
+		&model.EmailMessage{},
+		&model.DataExport{},
+	); err != nil {
 		m.log.Error("user migrate error", zap.Error(err))
 		return err
 	}
+	if err := m.seedRoles(); err != nil {
+		m.log.Error("seed roles error", zap.Error(err))
+		return err
+	}
 	m.log.Info("AutoMigrate success")
 	os.Exit(0)
 	return nil
 }
+
+// seedRoles 写入权限目录和内置角色，可重复执行
+func (m *Migrate) seedRoles() error {
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		permissions := make(map[string]*model.Permission, len(model.Permissions))
+		all := make([]*model.Permission, 0, len(model.Permissions))
+		for code, description := range model.Permissions {
+			permission := model.Permission{Code: code}
+			if err := tx.Where(&permission).Assign(model.Permission{Description: description}).
+				FirstOrCreate(&permission).Error; err != nil {
+				return err
+			}
+			permissions[code] = &permission
+			all = append(all, &permission)
+		}
+
+		roles := map[string][]*model.Permission{model.IdentityAdmin: all}
+		for name, codes := range model.DefaultRolePermissions {
+			for _, code := range codes {
+				roles[name] = append(roles[name], permissions[code])
+			}
+		}
+		for name, rolePermissions := range roles {
+			role := model.Role{Name: name}
+			if err := tx.Where(&role).FirstOrCreate(&role).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&role).Association("Permissions").Replace(rolePermissions); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func (m *Migrate) Stop(ctx context.Context) error {
 	m.log.Info("AutoMigrate stop")
 	return nil
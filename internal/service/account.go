@@ -0,0 +1,556 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/viper"
+	v1 "go-chat/api/v1"
+	"go-chat/internal/model"
+	"go-chat/internal/repository"
+	"go-chat/pkg/password"
+	"go-chat/pkg/rsakey"
+	"go.uber.org/zap"
+)
+
+const (
+	// exportLease 生成一个导出文件的最长时间
+	exportLease = 10 * time.Minute
+	// accountJobBatch Job 每轮最多处理的注销或过期导出数量
+	accountJobBatch  = 20
+	notifyTimeFormat = "2006-01-02 15:04"
+)
+
+type AccountService interface {
+	DeletionStatus(ctx context.Context, userName string) (*v1.DeletionStatusData, error)
+	// RequestDeletion 校验密码后申请注销，宽限期结束后由 Job 清除个人信息；重复申请不会推迟注销时间
+	RequestDeletion(ctx context.Context, userName string, req *v1.RequestDeletionRequest) (*v1.DeletionStatusData, error)
+	CancelDeletion(ctx context.Context, userName string) error
+	// RequestExport 申请导出个人数据；已有未完成的导出，或最近一次导出还在冷却期内时返回该导出
+	RequestExport(ctx context.Context, userName string) (*v1.DataExportData, error)
+	LatestExport(ctx context.Context, userName string) (*v1.DataExportData, error)
+	// ExportFile 返回最近一次已生成且未过期的导出文件路径
+	ExportFile(ctx context.Context, userName string) (string, error)
+
+	// JobInterval Job 处理注销和导出任务的间隔
+	JobInterval() time.Duration
+	// ProcessExports 生成待处理的导出文件，返回处理的数量
+	ProcessExports(ctx context.Context) (int, error)
+	// PurgeDeletions 清除宽限期已结束的账号，返回处理的数量
+	PurgeDeletions(ctx context.Context) (int, error)
+	// CleanupExports 删除过期的导出文件
+	CleanupExports(ctx context.Context) (int, error)
+}
+
+func NewAccountService(
+	service *Service,
+	conf *viper.Viper,
+	emailService EmailService,
+	tokenService TokenService,
+	auditService AuditService,
+	hasher *password.Manager,
+	rsaKeys rsakey.Provider,
+	accountRepo repository.AccountRepository,
+	userRepo repository.UserRepository,
+	twoFactorRepo repository.TwoFactorRepository,
+	rbacRepo repository.RBACRepository,
+) AccountService {
+	s := &accountService{
+		Service:           service,
+		gracePeriod:       conf.GetDuration("account.deletion.grace_period"),
+		exportDir:         conf.GetString("account.export.dir"),
+		exportTTL:         conf.GetDuration("account.export.ttl"),
+		exportMinInterval: conf.GetDuration("account.export.min_interval"),
+		jobInterval:       conf.GetDuration("account.job_interval"),
+		emailService:      emailService,
+		tokenService:      tokenService,
+		auditService:      auditService,
+		hasher:            hasher,
+		rsaKeys:           rsaKeys,
+		accountRepo:       accountRepo,
+		userRepo:          userRepo,
+		twoFactorRepo:     twoFactorRepo,
+		rbacRepo:          rbacRepo,
+	}
+	if s.gracePeriod <= 0 {
+		s.gracePeriod = 30 * 24 * time.Hour
+	}
+	if s.exportDir == "" {
+		s.exportDir = "storage/exports"
+	}
+	if s.exportTTL <= 0 {
+		s.exportTTL = 7 * 24 * time.Hour
+	}
+	if s.jobInterval <= 0 {
+		s.jobInterval = time.Minute
+	}
+	return s
+}
+
+type accountService struct {
+	*Service
+	gracePeriod       time.Duration
+	exportDir         string
+	exportTTL         time.Duration
+	exportMinInterval time.Duration
+	jobInterval       time.Duration
+	emailService      EmailService
+	tokenService      TokenService
+	auditService      AuditService
+	hasher            *password.Manager
+	rsaKeys           rsakey.Provider
+	accountRepo       repository.AccountRepository
+	userRepo          repository.UserRepository
+	twoFactorRepo     repository.TwoFactorRepository
+	rbacRepo          repository.RBACRepository
+}
+
+func (s *accountService) DeletionStatus(ctx context.Context, userName string) (*v1.DeletionStatusData, error) {
+	user, err := s.findUser(ctx, userName)
+	if err != nil {
+		return nil, err
+	}
+	return deletionStatus(user), nil
+}
+
+func (s *accountService) RequestDeletion(ctx context.Context, userName string, req *v1.RequestDeletionRequest) (*v1.DeletionStatusData, error) {
+	user, err := s.findUser(ctx, userName)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := rsakey.Decrypt(s.rsaKeys, req.Password)
+	if err != nil {
+		s.logger.WithContext(ctx).Warn("rsa decrypt error", zap.Error(err))
+		return nil, v1.ErrDecryptFailed
+	}
+	ok, _, err := s.hasher.Verify(plain, user.PassWord)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("verify password error", zap.Uint("user_id", user.ID), zap.Error(err))
+		return nil, v1.ErrInternalServerError
+	}
+	if !ok {
+		return nil, v1.ErrUserPasswordError
+	}
+	if user.DeletionScheduledAt != nil {
+		return deletionStatus(user), nil
+	}
+
+	scheduledAt := time.Now().Add(s.gracePeriod)
+	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.accountRepo.ScheduleDeletion(ctx, user.ID, &scheduledAt); err != nil {
+			return err
+		}
+		return s.auditService.Record(ctx, &model.AuditLog{
+			ActorId:    user.ID,
+			ActorName:  user.Name,
+			TargetType: model.AuditTargetUser,
+			TargetId:   auditUserTarget(user.ID),
+			Action:     model.AuditActionDeletionRequest,
+		}, map[string]interface{}{"scheduled_at": scheduledAt})
+	})
+	if err != nil {
+		return nil, err
+	}
+	user.DeletionScheduledAt = &scheduledAt
+	s.notify(ctx, user, mailAccountDeletionScheduled, map[string]interface{}{
+		"Name":        user.Name,
+		"ScheduledAt": scheduledAt.Format(notifyTimeFormat),
+	})
+	return deletionStatus(user), nil
+}
+
+func (s *accountService) CancelDeletion(ctx context.Context, userName string) error {
+	user, err := s.findUser(ctx, userName)
+	if err != nil {
+		return err
+	}
+	if user.DeletionScheduledAt == nil {
+		return v1.ErrDeletionNotScheduled
+	}
+	return s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.accountRepo.ScheduleDeletion(ctx, user.ID, nil); err != nil {
+			return err
+		}
+		return s.auditService.Record(ctx, &model.AuditLog{
+			ActorId:    user.ID,
+			ActorName:  user.Name,
+			TargetType: model.AuditTargetUser,
+			TargetId:   auditUserTarget(user.ID),
+			Action:     model.AuditActionDeletionCancel,
+		}, nil)
+	})
+}
+
+func (s *accountService) RequestExport(ctx context.Context, userName string) (*v1.DataExportData, error) {
+	user, err := s.findUser(ctx, userName)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := s.accountRepo.FindLatestExport(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil {
+		switch latest.Status {
+		case model.DataExportStatusPending, model.DataExportStatusProcessing:
+			return dataExportData(latest), nil
+		case model.DataExportStatusReady:
+			if time.Since(latest.CreateAt) < s.exportMinInterval {
+				return dataExportData(latest), nil
+			}
+		}
+	}
+
+	export := &model.DataExport{UserId: user.ID, Status: model.DataExportStatusPending}
+	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.accountRepo.CreateExport(ctx, export); err != nil {
+			return err
+		}
+		return s.auditService.Record(ctx, &model.AuditLog{
+			ActorId:    user.ID,
+			ActorName:  user.Name,
+			TargetType: model.AuditTargetUser,
+			TargetId:   auditUserTarget(user.ID),
+			Action:     model.AuditActionDataExport,
+		}, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dataExportData(export), nil
+}
+
+func (s *accountService) LatestExport(ctx context.Context, userName string) (*v1.DataExportData, error) {
+	user, err := s.findUser(ctx, userName)
+	if err != nil {
+		return nil, err
+	}
+	export, err := s.accountRepo.FindLatestExport(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if export == nil {
+		return nil, v1.ErrDataExportNotFound
+	}
+	return dataExportData(export), nil
+}
+
+func (s *accountService) ExportFile(ctx context.Context, userName string) (string, error) {
+	user, err := s.findUser(ctx, userName)
+	if err != nil {
+		return "", err
+	}
+	export, err := s.accountRepo.FindLatestExport(ctx, user.ID)
+	if err != nil {
+		return "", err
+	}
+	if export == nil || export.Status == model.DataExportStatusExpired || export.Status == model.DataExportStatusFailed {
+		return "", v1.ErrDataExportNotFound
+	}
+	if export.Status != model.DataExportStatusReady {
+		return "", v1.ErrDataExportNotReady
+	}
+	if export.ExpiresAt != nil && time.Now().After(*export.ExpiresAt) {
+		return "", v1.ErrDataExportNotFound
+	}
+	return filepath.Join(s.exportDir, export.FileName), nil
+}
+
+func (s *accountService) JobInterval() time.Duration {
+	return s.jobInterval
+}
+
+func (s *accountService) ProcessExports(ctx context.Context) (int, error) {
+	n := 0
+	for ctx.Err() == nil {
+		export, err := s.accountRepo.ClaimExport(ctx, time.Now(), exportLease)
+		if err != nil {
+			return n, err
+		}
+		if export == nil {
+			break
+		}
+		n++
+		user, err := s.userRepo.FindUserInfoById(ctx, export.UserId)
+		if err != nil {
+			return n, err
+		}
+		if user == nil {
+			if err = s.accountRepo.MarkExportFailed(ctx, export.ID, "user not found", time.Now()); err != nil {
+				return n, err
+			}
+			continue
+		}
+		fileName, size, err := s.writeExport(ctx, user)
+		if err != nil {
+			s.logger.WithContext(ctx).Error("write data export failed", zap.Uint("export_id", export.ID), zap.Error(err))
+			if err = s.accountRepo.MarkExportFailed(ctx, export.ID, err.Error(), time.Now()); err != nil {
+				return n, err
+			}
+			continue
+		}
+		now := time.Now()
+		expiresAt := now.Add(s.exportTTL)
+		if err = s.accountRepo.MarkExportReady(ctx, export.ID, fileName, size, now, expiresAt); err != nil {
+			_ = os.Remove(filepath.Join(s.exportDir, fileName))
+			return n, err
+		}
+		s.notify(ctx, user, mailDataExportReady, map[string]interface{}{
+			"Name":      user.Name,
+			"ExpiresAt": expiresAt.Format(notifyTimeFormat),
+		})
+	}
+	return n, nil
+}
+
+// writeExport 把用户数据写成 zip 文件，先写临时文件，完成后再改名
+func (s *accountService) writeExport(ctx context.Context, user *model.UserBasics) (string, int64, error) {
+	data, err := s.accountRepo.LoadAccountData(ctx, user.ID)
+	if err != nil {
+		return "", 0, err
+	}
+	twoFactor, err := s.twoFactorRepo.FindByUserId(ctx, user.ID)
+	if err != nil {
+		return "", 0, err
+	}
+	if err = os.MkdirAll(s.exportDir, 0o700); err != nil {
+		return "", 0, err
+	}
+	b := make([]byte, 8)
+	if _, err = rand.Read(b); err != nil {
+		return "", 0, err
+	}
+	fileName := fmt.Sprintf("export-%d-%s.zip", user.ID, hex.EncodeToString(b))
+	path := filepath.Join(s.exportDir, fileName)
+	tmp := path + ".tmp"
+
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp)
+	zw := zip.NewWriter(f)
+	if err = writeExportEntries(zw, user, data, twoFactor != nil && twoFactor.EnabledAt != nil); err != nil {
+		_ = f.Close()
+		return "", 0, err
+	}
+	if err = zw.Close(); err != nil {
+		_ = f.Close()
+		return "", 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return "", 0, err
+	}
+	if err = f.Close(); err != nil {
+		return "", 0, err
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		return "", 0, err
+	}
+	return fileName, info.Size(), nil
+}
+
+const exportReadme = `go-chat 个人数据导出
+
+profile.json            账号资料
+sessions.json           登录设备和会话
+identities.json         绑定的第三方账号
+two_factor.json         二次验证状态（不包含密钥和恢复码）
+security_activity.json  登录、密码修改等安全记录，以及管理员对账号的操作
+reports.json            您提交的举报
+restrictions.json       账号受到的禁言、封禁处罚
+
+聊天消息只在在线连接之间实时转发，服务器不保存消息和联系人；上传的文件保存在对象存储中，不与账号关联，因此不在导出范围内。
+`
+
+func writeExportEntries(zw *zip.Writer, user *model.UserBasics, data *repository.AccountData, twoFactorEnabled bool) error {
+	sessions := make([]*v1.SessionData, 0, len(data.Sessions))
+	for _, session := range data.Sessions {
+		sessions = append(sessions, &v1.SessionData{
+			SessionId:  session.SessionId,
+			Name:       session.Name,
+			DeviceInfo: session.DeviceInfo,
+			ClientIp:   session.ClientIp,
+			UserAgent:  session.UserAgent,
+			LastSeenAt: session.LastSeenAt,
+			CreateAt:   session.CreateAt,
+		})
+	}
+	// 管理员的操作只导出动作和时间，不包含操作人
+	activity := make([]*v1.SecurityActivityData, 0, len(data.AuditLogs))
+	for _, log := range data.AuditLogs {
+		item := &v1.SecurityActivityData{Action: log.Action, CreateAt: log.CreateAt}
+		if log.ActorId == user.ID {
+			item.Ip = log.Ip
+			item.UserAgent = log.UserAgent
+			item.DeviceInfo = log.DeviceInfo
+		}
+		activity = append(activity, item)
+	}
+
+	entries := []struct {
+		name  string
+		value interface{}
+	}{
+		{"profile.json", map[string]interface{}{
+			"id":                  user.ID,
+			"name":                user.Name,
+			"email":               user.Email,
+			"phone":               user.Phone,
+			"phoneVerifiedAt":     user.PhoneVerifiedAt,
+			"avatar":              user.Avatar,
+			"gender":              user.Gender,
+			"motto":               user.Motto,
+			"identity":            user.Identity,
+			"createAt":            user.CreateAt,
+			"loginTime":           user.LoginTime,
+			"clientIp":            user.ClientIp,
+			"deviceInfo":          user.DeviceInfo,
+			"deletionScheduledAt": user.DeletionScheduledAt,
+		}},
+		{"sessions.json", sessions},
+		{"identities.json", data.Identities},
+		{"two_factor.json", map[string]bool{"enabled": twoFactorEnabled}},
+		{"security_activity.json", activity},
+		{"reports.json", data.Reports},
+		{"restrictions.json", data.Restrictions},
+	}
+
+	w, err := zw.Create("README.txt")
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write([]byte(exportReadme)); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		w, err := zw.Create(entry.name)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err = enc.Encode(entry.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *accountService) PurgeDeletions(ctx context.Context) (int, error) {
+	users, err := s.accountRepo.ListDueDeletions(ctx, time.Now(), accountJobBatch)
+	if err != nil {
+		return 0, err
+	}
+	for i, user := range users {
+		if ctx.Err() != nil {
+			return i, nil
+		}
+		if err = s.anonymize(ctx, user); err != nil {
+			return i, err
+		}
+	}
+	if len(users) > 0 {
+		// 用户名可以被重新注册，不能让新用户拿到旧用户缓存的权限
+		if err = s.rbacRepo.InvalidatePermissionCache(ctx); err != nil {
+			s.logger.WithContext(ctx).Error("invalidate permission cache failed", zap.Error(err))
+		}
+	}
+	return len(users), nil
+}
+
+func (s *accountService) anonymize(ctx context.Context, user *model.UserBasics) error {
+	// 先按原用户名吊销 token，清除后就找不到原用户名了
+	if err := s.tokenService.RevokeUserTokens(ctx, user); err != nil {
+		return err
+	}
+	files, err := s.accountRepo.ListExportFiles(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.accountRepo.Anonymize(ctx, user, now); err != nil {
+			return err
+		}
+		return s.auditService.Record(ctx, &model.AuditLog{
+			TargetType: model.AuditTargetUser,
+			TargetId:   auditUserTarget(user.ID),
+			Action:     model.AuditActionAnonymize,
+		}, nil)
+	})
+	if err != nil {
+		return err
+	}
+	for _, name := range files {
+		if err := os.Remove(filepath.Join(s.exportDir, name)); err != nil && !os.IsNotExist(err) {
+			s.logger.WithContext(ctx).Error("remove data export failed", zap.String("file", name), zap.Error(err))
+		}
+	}
+	s.logger.WithContext(ctx).Info("account anonymized", zap.Uint("user_id", user.ID))
+	return nil
+}
+
+func (s *accountService) CleanupExports(ctx context.Context) (int, error) {
+	exports, err := s.accountRepo.ListExpiredExports(ctx, time.Now(), accountJobBatch)
+	if err != nil {
+		return 0, err
+	}
+	for _, export := range exports {
+		if err := os.Remove(filepath.Join(s.exportDir, export.FileName)); err != nil && !os.IsNotExist(err) {
+			return 0, err
+		}
+		if err = s.accountRepo.MarkExportExpired(ctx, export.ID); err != nil {
+			return 0, err
+		}
+	}
+	return len(exports), nil
+}
+
+func (s *accountService) findUser(ctx context.Context, userName string) (*model.UserBasics, error) {
+	user, err := s.userRepo.FindByName(ctx, userName)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, v1.ErrUnauthorized
+	}
+	return user, nil
+}
+
+// notify 通知邮件失败只记录日志
+func (s *accountService) notify(ctx context.Context, user *model.UserBasics, name string, data map[string]interface{}) {
+	if user.Email == "" {
+		return
+	}
+	if err := s.emailService.SendNotification(ctx, user.Email, name, data); err != nil {
+		s.logger.WithContext(ctx).Warn("send notification failed", zap.String("template", name), zap.Uint("user_id", user.ID), zap.Error(err))
+	}
+}
+
+func deletionStatus(user *model.UserBasics) *v1.DeletionStatusData {
+	return &v1.DeletionStatusData{
+		Scheduled:   user.DeletionScheduledAt != nil,
+		ScheduledAt: user.DeletionScheduledAt,
+	}
+}
+
+func dataExportData(export *model.DataExport) *v1.DataExportData {
+	return &v1.DataExportData{
+		Id:          export.ID,
+		Status:      export.Status,
+		Size:        export.Size,
+		CreateAt:    export.CreateAt,
+		CompletedAt: export.CompletedAt,
+		ExpiresAt:   export.ExpiresAt,
+	}
+}
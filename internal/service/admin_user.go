@@ -0,0 +1,291 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"math/big"
+	"time"
+
+	v1 "go-chat/api/v1"
+	"go-chat/internal/model"
+	"go-chat/internal/repository"
+	"go-chat/pkg/password"
+)
+
+// tempPasswordChars 临时密码字符集，去掉了 0/O、1/l/I 等容易看错的字符
+const tempPasswordChars = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnpqrstuvwxyz23456789"
+
+type AdminUserService interface {
+	ListUsers(ctx context.Context, req *v1.AdminListUsersRequest) (*v1.AdminListUsersResponseData, error)
+	GetUser(ctx context.Context, id uint) (*v1.AdminGetUserResponseData, error)
+	ForceLogout(ctx context.Context, operatorName string, id uint) error
+	DisableUser(ctx context.Context, operatorName string, id uint) error
+	EnableUser(ctx context.Context, operatorName string, id uint) error
+	// ResetPassword 生成临时密码并使已登录的设备下线
+	ResetPassword(ctx context.Context, operatorName string, id uint) (*v1.AdminResetPasswordResponseData, error)
+	DeleteUser(ctx context.Context, operatorName string, id uint) error
+	RestoreUser(ctx context.Context, operatorName string, id uint) error
+}
+
+func NewAdminUserService(
+	service *Service,
+	tokenService TokenService,
+	auditService AuditService,
+	hasher *password.Manager,
+	userRepo repository.UserRepository,
+	rbacRepo repository.RBACRepository,
+	reportRepo repository.ReportRepository,
+) AdminUserService {
+	return &adminUserService{
+		Service:      service,
+		tokenService: tokenService,
+		auditService: auditService,
+		hasher:       hasher,
+		userRepo:     userRepo,
+		rbacRepo:     rbacRepo,
+		reportRepo:   reportRepo,
+	}
+}
+
+type adminUserService struct {
+	*Service
+	tokenService TokenService
+	auditService AuditService
+	hasher       *password.Manager
+	userRepo     repository.UserRepository
+	rbacRepo     repository.RBACRepository
+	reportRepo   repository.ReportRepository
+}
+
+func (s *adminUserService) ListUsers(ctx context.Context, req *v1.AdminListUsersRequest) (*v1.AdminListUsersResponseData, error) {
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 || req.PageSize > 100 {
+		req.PageSize = 20
+	}
+	users, total, err := s.userRepo.Search(ctx, &repository.UserFilter{
+		Keyword:  req.Keyword,
+		Identity: req.Identity,
+		Status:   req.Status,
+	}, (req.Page-1)*req.PageSize, req.PageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*v1.AdminUserData, 0, len(users))
+	for _, u := range users {
+		list = append(list, v1.NewAdminUserData(u))
+	}
+	return &v1.AdminListUsersResponseData{List: list, Total: total}, nil
+}
+
+func (s *adminUserService) GetUser(ctx context.Context, id uint) (*v1.AdminGetUserResponseData, error) {
+	user, err := s.findUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := s.rbacRepo.ListUserRoleNames(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	restrictions, err := s.reportRepo.ListActiveRestrictions(ctx, user.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &v1.AdminGetUserResponseData{
+		User:         v1.NewAdminUserData(user),
+		Roles:        roles,
+		Restrictions: restrictions,
+	}, nil
+}
+
+func (s *adminUserService) ForceLogout(ctx context.Context, operatorName string, id uint) error {
+	operator, user, err := s.findOtherUser(ctx, operatorName, id)
+	if err != nil {
+		return err
+	}
+	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.SetLoginOut(ctx, user.ID, time.Now()); err != nil {
+			return err
+		}
+		return s.audit(ctx, operator, user, model.AuditActionUserLogout)
+	})
+	if err != nil {
+		return err
+	}
+	return s.tokenService.RevokeUserTokens(ctx, user)
+}
+
+func (s *adminUserService) DisableUser(ctx context.Context, operatorName string, id uint) error {
+	operator, user, err := s.findOtherUser(ctx, operatorName, id)
+	if err != nil {
+		return err
+	}
+	if user.DisabledAt != nil {
+		return nil
+	}
+	now := time.Now()
+	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.SetDisabledAt(ctx, user.ID, &now); err != nil {
+			return err
+		}
+		if err := s.userRepo.SetLoginOut(ctx, user.ID, now); err != nil {
+			return err
+		}
+		return s.audit(ctx, operator, user, model.AuditActionUserDisable)
+	})
+	if err != nil {
+		return err
+	}
+	return s.tokenService.RevokeUserTokens(ctx, user)
+}
+
+func (s *adminUserService) EnableUser(ctx context.Context, operatorName string, id uint) error {
+	operator, user, err := s.findOtherUser(ctx, operatorName, id)
+	if err != nil {
+		return err
+	}
+	if user.DisabledAt == nil {
+		return nil
+	}
+	return s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.SetDisabledAt(ctx, user.ID, nil); err != nil {
+			return err
+		}
+		return s.audit(ctx, operator, user, model.AuditActionUserEnable)
+	})
+}
+
+func (s *adminUserService) ResetPassword(ctx context.Context, operatorName string, id uint) (*v1.AdminResetPasswordResponseData, error) {
+	operator, user, err := s.findOtherUser(ctx, operatorName, id)
+	if err != nil {
+		return nil, err
+	}
+	tempPassword, err := generateTempPassword(12)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := s.hasher.Hash(tempPassword)
+	if err != nil {
+		return nil, err
+	}
+	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.UpdatePassword(ctx, user.ID, hash, ""); err != nil {
+			return err
+		}
+		if err := s.userRepo.SetLoginOut(ctx, user.ID, time.Now()); err != nil {
+			return err
+		}
+		return s.audit(ctx, operator, user, model.AuditActionUserPasswordReset)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = s.tokenService.RevokeUserTokens(ctx, user); err != nil {
+		return nil, err
+	}
+	return &v1.AdminResetPasswordResponseData{Password: tempPassword}, nil
+}
+
+func (s *adminUserService) DeleteUser(ctx context.Context, operatorName string, id uint) error {
+	operator, user, err := s.findOtherUser(ctx, operatorName, id)
+	if err != nil {
+		return err
+	}
+	if user.DeleteAt.Valid {
+		return nil
+	}
+	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.Delete(ctx, user.ID); err != nil {
+			return err
+		}
+		return s.audit(ctx, operator, user, model.AuditActionUserDelete)
+	})
+	if err != nil {
+		return err
+	}
+	// 注销后用户名可以被重新注册，旧 token 必须失效
+	return s.tokenService.RevokeUserTokens(ctx, user)
+}
+
+func (s *adminUserService) RestoreUser(ctx context.Context, operatorName string, id uint) error {
+	operator, user, err := s.findOtherUser(ctx, operatorName, id)
+	if err != nil {
+		return err
+	}
+	if !user.DeleteAt.Valid {
+		return nil
+	}
+	// 用户自行注销的账号个人信息已清除，不能恢复
+	if user.AnonymizedAt != nil {
+		return v1.ErrUserNotFound
+	}
+	// 注销期间用户名或邮箱可能已被他人使用
+	if _, err = s.userRepo.FindUserByNameWithRegister(ctx, user.Name); err != nil {
+		return v1.ErrUserNameAlreadyUse
+	}
+	if _, err = s.userRepo.FindUserByEmailWithRegister(ctx, user.Email); err != nil {
+		return v1.ErrEmailAlreadyUse
+	}
+	return s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.Restore(ctx, user.ID); err != nil {
+			return err
+		}
+		return s.audit(ctx, operator, user, model.AuditActionUserRestore)
+	})
+}
+
+// findUser 包含已注销的用户
+func (s *adminUserService) findUser(ctx context.Context, id uint) (*model.UserBasics, error) {
+	user, err := s.userRepo.FindUnscopedById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, v1.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// findOtherUser 返回操作人和目标用户，禁止对自己执行管理操作，避免管理员把自己锁在外面
+func (s *adminUserService) findOtherUser(ctx context.Context, operatorName string, id uint) (*model.UserBasics, *model.UserBasics, error) {
+	operator, err := s.userRepo.FindByName(ctx, operatorName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if operator == nil {
+		return nil, nil, v1.ErrUnauthorized
+	}
+	user, err := s.findUser(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.ID == operator.ID {
+		return nil, nil, v1.ErrForbidden
+	}
+	return operator, user, nil
+}
+
+func (s *adminUserService) audit(ctx context.Context, operator *model.UserBasics, user *model.UserBasics, action string) error {
+	return s.auditService.Record(ctx, &model.AuditLog{
+		ActorId:    operator.ID,
+		ActorName:  operator.Name,
+		TargetType: model.AuditTargetUser,
+		TargetId:   auditUserTarget(user.ID),
+		Action:     action,
+	}, map[string]interface{}{"name": user.Name})
+}
+
+func generateTempPassword(n int) (string, error) {
+	size := big.NewInt(int64(len(tempPasswordChars)))
+	b := make([]byte, n)
+	for i := range b {
+		idx, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			return "", err
+		}
+		b[i] = tempPasswordChars[idx.Int64()]
+	}
+	return string(b), nil
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	v1 "go-chat/api/v1"
+	"go-chat/internal/model"
+	"go-chat/internal/repository"
+	"go-chat/pkg/clientinfo"
+	"go-chat/pkg/log"
+)
+
+// securityActions 用户在"最近安全活动"中能看到的动作
+var securityActions = []string{
+	model.AuditActionLogin,
+	model.AuditActionLoginFailed,
+	model.AuditActionRefreshTokenReuse,
+	model.AuditActionPasswordChange,
+	model.AuditActionEmailChange,
+	model.AuditActionPhoneChange,
+	model.AuditActionSessionRevoke,
+	model.AuditActionTwoFactorEnable,
+	model.AuditActionTwoFactorDisable,
+	model.AuditActionRecoveryCodes,
+	model.AuditActionIdentityLink,
+	model.AuditActionUserLogout,
+	model.AuditActionUserDisable,
+	model.AuditActionUserEnable,
+	model.AuditActionUserPasswordReset,
+	model.AuditActionUserRestore,
+}
+
+type AuditService interface {
+	// Record 写入审计日志，IP、设备和 trace 从 ctx 中获取。
+	// 需要与业务修改保持一致时，在 Transaction 的回调中使用回调的 ctx 调用
+	Record(ctx context.Context, entry *model.AuditLog, metadata map[string]interface{}) error
+	ListAuditLogs(ctx context.Context, req *v1.ListAuditLogsRequest) (*v1.ListAuditLogsResponseData, error)
+	ListSecurityActivity(ctx context.Context, userName string, req *v1.SecurityActivityRequest) ([]*v1.SecurityActivityData, error)
+}
+
+func NewAuditService(
+	service *Service,
+	auditRepo repository.AuditRepository,
+	userRepo repository.UserRepository,
+) AuditService {
+	return &auditService{
+		Service:   service,
+		auditRepo: auditRepo,
+		userRepo:  userRepo,
+	}
+}
+
+type auditService struct {
+	*Service
+	auditRepo repository.AuditRepository
+	userRepo  repository.UserRepository
+}
+
+func (s *auditService) Record(ctx context.Context, entry *model.AuditLog, metadata map[string]interface{}) error {
+	if len(metadata) > 0 {
+		b, err := json.Marshal(metadata)
+		if err != nil {
+			return err
+		}
+		entry.Metadata = string(b)
+	}
+	info := clientinfo.FromContext(ctx)
+	entry.Ip = info.IP
+	entry.UserAgent = info.UserAgent
+	entry.DeviceInfo = info.DeviceInfo
+	entry.TraceId = log.TraceFromContext(ctx)
+	return s.auditRepo.Create(ctx, entry)
+}
+
+func (s *auditService) ListAuditLogs(ctx context.Context, req *v1.ListAuditLogsRequest) (*v1.ListAuditLogsResponseData, error) {
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 || req.PageSize > 100 {
+		req.PageSize = 20
+	}
+	filter := &repository.AuditFilter{
+		ActorId:    req.ActorId,
+		TargetType: req.TargetType,
+		TargetId:   req.TargetId,
+		Since:      req.Since,
+		Until:      req.Until,
+	}
+	if req.Action != "" {
+		filter.Actions = []string{req.Action}
+	}
+	logs, total, err := s.auditRepo.List(ctx, filter, (req.Page-1)*req.PageSize, req.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.ListAuditLogsResponseData{List: logs, Total: total}, nil
+}
+
+func (s *auditService) ListSecurityActivity(ctx context.Context, userName string, req *v1.SecurityActivityRequest) ([]*v1.SecurityActivityData, error) {
+	user, err := s.userRepo.FindByName(ctx, userName)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, v1.ErrUnauthorized
+	}
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 20
+	}
+
+	logs, _, err := s.auditRepo.List(ctx, &repository.AuditFilter{
+		TargetType: model.AuditTargetUser,
+		TargetId:   auditUserTarget(user.ID),
+		Actions:    securityActions,
+	}, 0, req.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*v1.SecurityActivityData, 0, len(logs))
+	for _, l := range logs {
+		list = append(list, &v1.SecurityActivityData{
+			Action:     l.Action,
+			Ip:         l.Ip,
+			UserAgent:  l.UserAgent,
+			DeviceInfo: l.DeviceInfo,
+			CreateAt:   l.CreateAt,
+		})
+	}
+	return list, nil
+}
+
+// auditUserTarget 审计对象为用户时的 TargetId
+func auditUserTarget(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
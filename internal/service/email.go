@@ -2,87 +2,109 @@ package service
 
 import (
 	"context"
-	"crypto/tls"
-	"fmt"
-	"github.com/jordan-wright/email"
 	v1 "go-chat/api/v1"
-	"go-chat/internal/repository"
-	"math/rand"
-	"net/smtp"
+	"go-chat/global"
+	"go-chat/pkg/clientinfo"
+	"go-chat/pkg/verifycode"
+	"go.uber.org/zap"
+	"strings"
 	"time"
 )
 
+// 通知类邮件模板
+const (
+	mailPasswordChanged          = "password_changed"
+	mailAccountDeletionScheduled = "account_deletion_scheduled"
+	mailDataExportReady          = "data_export_ready"
+)
+
+// emailCodeTemplates 验证码用途对应的邮件模板，用途可以带 ":用户ID" 后缀，按前缀查找
+var emailCodeTemplates = map[string]string{
+	global.Register:      "register_code",
+	global.Login:         "login_code",
+	global.ResetPassword: "reset_password_code",
+	global.BindEmail:     "bind_email_code",
+}
+
 type EmailService interface {
+	// SendEmail 生成 emailType 用途的验证码并放入邮件队列，冷却期内返回 ErrVerifyCodeCooldown
 	SendEmail(ctx context.Context, email string, emailType string) error
+	// CheckEmailCode 验证通过后验证码立即作废，连续输错后锁定
 	CheckEmailCode(ctx context.Context, email string, code string, emailType string) error
+	// SendNotification 把 name 模板的通知邮件放入队列，语言取自请求的 Accept-Language
+	SendNotification(ctx context.Context, email string, name string, data interface{}) error
 }
 
 func NewEmailService(
 	service *Service,
-	emailRepository repository.EmailRepository,
+	codes *verifycode.Manager,
+	queue EmailQueueService,
 ) EmailService {
 	return &emailService{
-		Service:         service,
-		emailRepository: emailRepository,
+		Service: service,
+		codes:   codes,
+		queue:   queue,
 	}
 }
 
 type emailService struct {
 	*Service
-	emailRepository repository.EmailRepository
+	codes *verifycode.Manager
+	queue EmailQueueService
 }
 
 func (s *emailService) SendEmail(ctx context.Context, emailDetail string, emailType string) error {
-	// 获取六位随机验证码
-	emailCode := GenerateRandomCode(6)
-	// 生成验证码key emil + "类型"
-	emailKey := GenerateEmailKey(emailType, emailDetail)
-	// 存储验证码到redis
-	err := s.emailRepository.SaveVerifyCode(ctx, emailKey, emailCode)
+	name, ok := emailCodeTemplates[strings.SplitN(emailType, ":", 2)[0]]
+	if !ok {
+		return v1.ErrBadRequest
+	}
+	target := emailCodeTarget(emailDetail, emailType)
+	emailCode, err := s.codes.Issue(ctx, target)
 	if err != nil {
-		return err
+		return verifyCodeError(err, v1.ErrEmailCodeError)
 	}
-	// 发送邮件
-	e := email.NewEmail()
-	e.From = "Lxl chat <1497556691@qq.com>"
-	e.To = []string{emailDetail}
-	e.Subject = "登陆验证码"
-	e.HTML = []byte(fmt.Sprintf("<h1>注册验证码: %s</h1>", emailCode))
-	err = e.SendWithTLS("smtp.qq.com:465",
-		smtp.PlainAuth("", "1497556691@qq.com", "svoaswgsjiabgjah", "smtp.qq.com"),
-		&tls.Config{InsecureSkipVerify: true, ServerName: "smtp.qq.com"})
+	// 验证码过期后邮件不再投递
+	expiresAt := time.Now().Add(s.codes.TTL())
+	err = s.queue.Enqueue(ctx, emailDetail, name, clientinfo.FromContext(ctx).Locale, map[string]interface{}{
+		"Code":       emailCode,
+		"TTLMinutes": int(s.codes.TTL().Minutes()),
+	}, &expiresAt)
 	if err != nil {
+		s.logger.WithContext(ctx).Error("enqueue email code failed", zap.String("template", name), zap.Error(err))
+		// 入队失败时作废验证码，用户可以立即重试
+		if err := s.codes.Invalidate(ctx, target); err != nil {
+			s.logger.WithContext(ctx).Error("invalidate email code failed", zap.Error(err))
+		}
 		return v1.ErrSendEmailFailed
 	}
 	return nil
 }
 
-func (s *emailService) CheckEmailCode(ctx context.Context, email string, code string, emailType string) error {
-	emailKey := GenerateEmailKey(emailType, email)
-	// 从redis中获取验证码
-	emailCode, err := s.emailRepository.GetEmailCodeByKey(ctx, emailKey)
-	if err != nil {
-		return err
-	}
-	// 比较验证码
-	if emailCode != code {
-		return v1.ErrEmailCodeError
-	}
+func (s *emailService) SendNotification(ctx context.Context, email string, name string, data interface{}) error {
+	return s.queue.Enqueue(ctx, email, name, clientinfo.FromContext(ctx).Locale, data, nil)
+}
 
-	return nil
+func (s *emailService) CheckEmailCode(ctx context.Context, email string, code string, emailType string) error {
+	err := s.codes.Verify(ctx, emailCodeTarget(email, emailType), code)
+	return verifyCodeError(err, v1.ErrEmailCodeError)
 }
 
-func GenerateRandomCode(codeLen int) string {
-	s := "1234567890"
-	code := ""
-	// import random seed, or that random code will always be the same one
-	rand.Seed(time.Now().UnixNano())
-	for i := 0; i < codeLen; i++ {
-		code += string(s[rand.Intn(len(s))])
-	}
-	return code
+func emailCodeTarget(email string, emailType string) verifycode.Target {
+	return verifycode.Target{Channel: "email", Address: strings.ToLower(strings.TrimSpace(email)), Purpose: emailType}
 }
 
-func GenerateEmailKey(emailType string, email string) string {
-	return fmt.Sprintf("%s-code-%s", email, emailType)
+// verifyCodeError 转换为接口错误，mismatch 为各渠道自己的验证码错误
+func verifyCodeError(err error, mismatch error) error {
+	switch err {
+	case nil:
+		return nil
+	case verifycode.ErrMismatch:
+		return mismatch
+	case verifycode.ErrCooldown:
+		return v1.ErrVerifyCodeCooldown
+	case verifycode.ErrLocked:
+		return v1.ErrVerifyCodeLocked
+	default:
+		return err
+	}
 }
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/spf13/viper"
+	v1 "go-chat/api/v1"
+	"go-chat/internal/model"
+	"go-chat/internal/repository"
+	"go-chat/pkg/mailer"
+	"go.uber.org/zap"
+)
+
+type EmailQueueService interface {
+	// Enqueue 保存待发送的邮件，由 Job 中的 worker 异步投递；expiresAt 不为空时过期后不再发送
+	Enqueue(ctx context.Context, to string, template string, locale string, data interface{}, expiresAt *time.Time) error
+	// DeliverDue 投递一批到期的邮件，返回处理的数量
+	DeliverDue(ctx context.Context) (int, error)
+	// PollInterval worker 两次检查队列的间隔
+	PollInterval() time.Duration
+	QueueStatus(ctx context.Context, req *v1.EmailQueueStatusRequest) (*v1.EmailQueueStatusResponseData, error)
+}
+
+// emailQueueOptions 对应 mail.queue 配置
+type emailQueueOptions struct {
+	pollInterval time.Duration
+	batchSize    int
+	lease        time.Duration
+	maxAttempts  int
+	backoffBase  time.Duration
+	backoffMax   time.Duration
+}
+
+func NewEmailQueueService(
+	service *Service,
+	conf *viper.Viper,
+	mailer *mailer.Mailer,
+	emailRepo repository.EmailRepository,
+) EmailQueueService {
+	opts := emailQueueOptions{
+		pollInterval: conf.GetDuration("mail.queue.poll_interval"),
+		batchSize:    conf.GetInt("mail.queue.batch_size"),
+		lease:        conf.GetDuration("mail.queue.lease"),
+		maxAttempts:  conf.GetInt("mail.queue.max_attempts"),
+		backoffBase:  conf.GetDuration("mail.queue.backoff_base"),
+		backoffMax:   conf.GetDuration("mail.queue.backoff_max"),
+	}
+	if opts.pollInterval <= 0 {
+		opts.pollInterval = 2 * time.Second
+	}
+	if opts.batchSize <= 0 {
+		opts.batchSize = 20
+	}
+	if opts.lease <= 0 {
+		opts.lease = 2 * time.Minute
+	}
+	if opts.maxAttempts <= 0 {
+		opts.maxAttempts = 8
+	}
+	if opts.backoffBase <= 0 {
+		opts.backoffBase = 30 * time.Second
+	}
+	if opts.backoffMax < opts.backoffBase {
+		opts.backoffMax = time.Hour
+	}
+	return &emailQueueService{
+		Service:   service,
+		opts:      opts,
+		mailer:    mailer,
+		emailRepo: emailRepo,
+	}
+}
+
+type emailQueueService struct {
+	*Service
+	opts      emailQueueOptions
+	mailer    *mailer.Mailer
+	emailRepo repository.EmailRepository
+}
+
+func (s *emailQueueService) Enqueue(ctx context.Context, to string, template string, locale string, data interface{}, expiresAt *time.Time) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return s.emailRepo.Enqueue(ctx, &model.EmailMessage{
+		Recipient:     to,
+		Template:      template,
+		Locale:        locale,
+		Data:          string(b),
+		Status:        model.EmailStatusPending,
+		NextAttemptAt: time.Now(),
+		ExpiresAt:     expiresAt,
+	})
+}
+
+func (s *emailQueueService) PollInterval() time.Duration {
+	return s.opts.pollInterval
+}
+
+func (s *emailQueueService) DeliverDue(ctx context.Context) (int, error) {
+	messages, err := s.emailRepo.ClaimDue(ctx, time.Now(), s.opts.lease, s.opts.batchSize)
+	if err != nil {
+		return 0, err
+	}
+	for i, message := range messages {
+		// 停止时剩下的邮件等租约到期后重新投递
+		if ctx.Err() != nil {
+			return i, nil
+		}
+		if err = s.deliver(ctx, message); err != nil {
+			return i, err
+		}
+	}
+	return len(messages), nil
+}
+
+// deliver 发送一封已领取的邮件并记录结果，只有数据库错误才返回
+func (s *emailQueueService) deliver(ctx context.Context, message *model.EmailMessage) error {
+	logger := s.logger.WithContext(ctx).With(
+		zap.Uint("email_id", message.ID),
+		zap.String("template", message.Template),
+		zap.Int("attempts", message.Attempts),
+	)
+	now := time.Now()
+	if message.ExpiresAt != nil && now.After(*message.ExpiresAt) {
+		logger.Warn("email expired before delivery")
+		return s.emailRepo.MarkFailed(ctx, message.ID, "expired before delivery", now)
+	}
+
+	var data map[string]interface{}
+	err := json.Unmarshal([]byte(message.Data), &data)
+	if err == nil {
+		err = s.mailer.Send(ctx, message.Recipient, message.Template, message.Locale, data)
+	}
+	now = time.Now()
+	if err == nil {
+		return s.emailRepo.MarkSent(ctx, message.ID, now)
+	}
+	if mailer.IsPermanent(err) || message.Attempts >= s.opts.maxAttempts {
+		logger.Error("email delivery failed permanently", zap.Error(err))
+		return s.emailRepo.MarkFailed(ctx, message.ID, err.Error(), now)
+	}
+	next := now.Add(mailer.Backoff(message.Attempts, s.opts.backoffBase, s.opts.backoffMax))
+	logger.Warn("email delivery failed, will retry", zap.Time("next_attempt_at", next), zap.Error(err))
+	return s.emailRepo.MarkRetry(ctx, message.ID, err.Error(), next)
+}
+
+func (s *emailQueueService) QueueStatus(ctx context.Context, req *v1.EmailQueueStatusRequest) (*v1.EmailQueueStatusResponseData, error) {
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 || req.PageSize > 100 {
+		req.PageSize = 20
+	}
+	counts, err := s.emailRepo.CountByStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	messages, total, err := s.emailRepo.List(ctx, req.Status, (req.Page-1)*req.PageSize, req.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.EmailQueueStatusResponseData{Counts: counts, List: messages, Total: total}, nil
+}
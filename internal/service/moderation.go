@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	v1 "go-chat/api/v1"
+	"go-chat/internal/model"
+	"go-chat/internal/repository"
+	"go-chat/pkg/moderation"
+	"go.uber.org/zap"
+)
+
+// 审核的字段
+const (
+	ModerationFieldName    = "name"
+	ModerationFieldMotto   = "motto"
+	ModerationFieldMessage = "message"
+)
+
+// ModerationResult Review 的结果，Flagged 时需要在用户创建后调用 RecordFlag
+type ModerationResult struct {
+	// Content 用户提交的原文，Text 为可以保存的文本
+	Content string
+	Text    string
+	Flagged bool
+	Words   []string
+}
+
+type ModerationService interface {
+	// Moderate 审核已有用户提交的文本，返回可以保存的文本；命中 block 词库时返回 ErrContentBlocked
+	Moderate(ctx context.Context, userId uint, field string, text string) (string, error)
+	// Review 同 Moderate 但不记录待复核内容，用于注册、第三方登录等用户尚未创建的场景
+	Review(ctx context.Context, field string, text string) (*ModerationResult, error)
+	// RecordFlag 用户创建后记录 Review 中命中 flag 词库的内容，未命中时不做任何事
+	RecordFlag(ctx context.Context, userId uint, field string, res *ModerationResult)
+}
+
+func NewModerationService(
+	service *Service,
+	filter *moderation.Filter,
+	moderationRepository repository.ModerationRepository,
+) ModerationService {
+	return &moderationService{
+		Service:              service,
+		filter:               filter,
+		moderationRepository: moderationRepository,
+	}
+}
+
+type moderationService struct {
+	*Service
+	filter               *moderation.Filter
+	moderationRepository repository.ModerationRepository
+}
+
+func (s *moderationService) Moderate(ctx context.Context, userId uint, field string, text string) (string, error) {
+	res, err := s.Review(ctx, field, text)
+	if err != nil {
+		return "", err
+	}
+	s.RecordFlag(ctx, userId, field, res)
+	return res.Text, nil
+}
+
+func (s *moderationService) Review(ctx context.Context, field string, text string) (*ModerationResult, error) {
+	if text == "" {
+		return &ModerationResult{}, nil
+	}
+	res := s.filter.Check(text)
+	if res.Action == moderation.ActionBlock {
+		s.logger.WithContext(ctx).Warn("content blocked", zap.String("field", field), zap.Strings("words", res.Words))
+		return nil, v1.ErrContentBlocked
+	}
+	return &ModerationResult{
+		Content: text,
+		Text:    res.Text,
+		Flagged: res.Action == moderation.ActionFlag,
+		Words:   res.Words,
+	}, nil
+}
+
+func (s *moderationService) RecordFlag(ctx context.Context, userId uint, field string, res *ModerationResult) {
+	if res == nil || !res.Flagged {
+		return
+	}
+	// 内容照常保存，记录下来等待人工复核
+	if err := s.moderationRepository.CreateFlag(ctx, &model.ModerationFlag{
+		UserId:  userId,
+		Field:   field,
+		Content: res.Content,
+		Words:   strings.Join(res.Words, ","),
+	}); err != nil {
+		s.logger.WithContext(ctx).Error("save moderation flag failed", zap.Uint("user_id", userId), zap.Error(err))
+	}
+}
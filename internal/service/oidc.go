@@ -0,0 +1,295 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	v1 "go-chat/api/v1"
+	"go-chat/internal/model"
+	"go-chat/internal/repository"
+	"go-chat/pkg/oidc"
+	"go-chat/pkg/password"
+	"go.uber.org/zap"
+)
+
+const (
+	// oidcStateTTL 在提供方登录页停留的时限
+	oidcStateTTL = 10 * time.Minute
+	// oidcNameMaxLen 自动创建账号时用户名的最大长度，不含去重后缀
+	oidcNameMaxLen = 24
+)
+
+var oidcNameInvalidChars = regexp.MustCompile(`[^\p{L}\p{N}_.-]+`)
+
+type OIDCService interface {
+	Providers(ctx context.Context) []*v1.OIDCProviderData
+	// Authorize 生成 state、nonce 和 PKCE verifier，返回提供方的授权地址
+	Authorize(ctx context.Context, provider string) (*v1.OIDCAuthorizeData, error)
+	// Callback 用授权码换取并校验 id_token，按 sub 找到绑定的用户；
+	// 没有绑定时按已验证的邮箱绑定到已有用户，都没有则创建新用户
+	Callback(ctx context.Context, provider string, req *v1.OIDCCallbackRequest) (*v1.LoginResponseData, error)
+}
+
+func NewOIDCService(
+	service *Service,
+	registry *oidc.Registry,
+	userService UserService,
+	moderationService ModerationService,
+	auditService AuditService,
+	hasher *password.Manager,
+	identityRepo repository.IdentityRepository,
+	userRepo repository.UserRepository,
+) OIDCService {
+	return &oidcService{
+		Service:           service,
+		registry:          registry,
+		userService:       userService,
+		moderationService: moderationService,
+		auditService:      auditService,
+		hasher:            hasher,
+		identityRepo:      identityRepo,
+		userRepo:          userRepo,
+	}
+}
+
+type oidcService struct {
+	*Service
+	registry          *oidc.Registry
+	userService       UserService
+	moderationService ModerationService
+	auditService      AuditService
+	hasher            *password.Manager
+	identityRepo      repository.IdentityRepository
+	userRepo          repository.UserRepository
+}
+
+func (s *oidcService) Providers(ctx context.Context) []*v1.OIDCProviderData {
+	clients := s.registry.List()
+	list := make([]*v1.OIDCProviderData, 0, len(clients))
+	for _, c := range clients {
+		list = append(list, &v1.OIDCProviderData{Name: c.Config().Name, DisplayName: c.Config().DisplayName})
+	}
+	return list
+}
+
+func (s *oidcService) Authorize(ctx context.Context, provider string) (*v1.OIDCAuthorizeData, error) {
+	client, ok := s.registry.Get(provider)
+	if !ok {
+		return nil, v1.ErrOIDCProviderNotFound
+	}
+	state, err := oidc.RandomString(24)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := oidc.RandomString(24)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := oidc.NewVerifier()
+	if err != nil {
+		return nil, err
+	}
+	authURL, err := client.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("oidc authorize error", zap.String("provider", provider), zap.Error(err))
+		return nil, v1.ErrOIDCLoginFailed
+	}
+	if err = s.identityRepo.SaveState(ctx, state, &repository.OIDCState{
+		Provider: provider,
+		Verifier: verifier,
+		Nonce:    nonce,
+	}, oidcStateTTL); err != nil {
+		return nil, err
+	}
+	return &v1.OIDCAuthorizeData{AuthorizationUrl: authURL, State: state}, nil
+}
+
+func (s *oidcService) Callback(ctx context.Context, provider string, req *v1.OIDCCallbackRequest) (*v1.LoginResponseData, error) {
+	client, ok := s.registry.Get(provider)
+	if !ok {
+		return nil, v1.ErrOIDCProviderNotFound
+	}
+	state, err := s.identityRepo.TakeState(ctx, req.State)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil || state.Provider != provider {
+		return nil, v1.ErrOIDCStateInvalid
+	}
+
+	token, err := client.Exchange(ctx, req.Code, state.Verifier)
+	if err != nil {
+		s.logger.WithContext(ctx).Warn("oidc exchange error", zap.String("provider", provider), zap.Error(err))
+		return nil, v1.ErrOIDCLoginFailed
+	}
+	claims, err := client.VerifyIDToken(ctx, token.IDToken, state.Nonce)
+	if err != nil {
+		s.logger.WithContext(ctx).Warn("oidc id token error", zap.String("provider", provider), zap.Error(err))
+		return nil, v1.ErrOIDCLoginFailed
+	}
+
+	user, err := s.resolveUser(ctx, provider, claims)
+	if err != nil {
+		return nil, err
+	}
+	return s.userService.LoginUser(ctx, user, "oidc:"+provider)
+}
+
+// resolveUser 找到或创建 id_token 对应的本地用户
+func (s *oidcService) resolveUser(ctx context.Context, provider string, claims *oidc.Claims) (*model.UserBasics, error) {
+	email := ""
+	if claims.EmailVerified {
+		email = strings.ToLower(strings.TrimSpace(claims.Email))
+	}
+
+	identity, err := s.identityRepo.FindByProviderSubject(ctx, provider, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		user, err := s.userRepo.FindUserInfoById(ctx, identity.UserId)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			// 绑定的用户已注销
+			return nil, v1.ErrUserNotFound
+		}
+		if err = s.identityRepo.UpdateLastLogin(ctx, identity.ID, claims.Email, time.Now()); err != nil {
+			s.logger.WithContext(ctx).Error("update identity last login error", zap.Uint("identity_id", identity.ID), zap.Error(err))
+		}
+		return user, nil
+	}
+
+	// 提供方和本地都确认过的邮箱才能绑定到已有账号。本地邮箱未验证时，
+	// 任何人都可以先把别人的邮箱写到自己名下，再用提供方登录接管账号
+	if email != "" {
+		user, err := s.userRepo.FindByVerifiedEmail(ctx, email)
+		if err != nil {
+			return nil, err
+		}
+		if user != nil {
+			if err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+				return s.link(ctx, user, provider, claims, "email")
+			}); err != nil {
+				return nil, err
+			}
+			return user, nil
+		}
+		// 有账号填写了该邮箱但没有验证，新账号不占用这个邮箱，用户之后可以通过验证码绑定
+		_, err = s.userRepo.FindUserByEmailWithLogin(ctx, email)
+		if err == nil {
+			email = ""
+		} else if err != v1.ErrUserEmailNotFound {
+			return nil, err
+		}
+	}
+	return s.createUser(ctx, provider, claims, email)
+}
+
+// createUser 首次登录时创建账号，密码为随机值，用户之后可以通过找回密码设置
+func (s *oidcService) createUser(ctx context.Context, provider string, claims *oidc.Claims, email string) (*model.UserBasics, error) {
+	name, review, err := s.availableName(ctx, claims, provider)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := oidc.RandomString(32)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := s.hasher.Hash(secret)
+	if err != nil {
+		return nil, err
+	}
+	t := time.Now()
+	user := &model.UserBasics{
+		Name:          name,
+		PassWord:      hash,
+		Email:         email,
+		Avatar:        claims.Picture,
+		LoginTime:     &t,
+		HeartBeatTime: &t,
+		LoginOutTime:  &t,
+	}
+	// 提供方确认过的邮箱视为已验证
+	if email != "" {
+		user.EmailVerifiedAt = &t
+	}
+	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.Create(ctx, user); err != nil {
+			return err
+		}
+		return s.link(ctx, user, provider, claims, "create")
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.moderationService.RecordFlag(ctx, user.ID, ModerationFieldName, review)
+	return user, nil
+}
+
+func (s *oidcService) link(ctx context.Context, user *model.UserBasics, provider string, claims *oidc.Claims, method string) error {
+	now := time.Now()
+	if err := s.identityRepo.Create(ctx, &model.UserIdentity{
+		UserId:      user.ID,
+		Provider:    provider,
+		Subject:     claims.Subject,
+		Email:       claims.Email,
+		LastLoginAt: &now,
+	}); err != nil {
+		return err
+	}
+	return s.auditService.Record(ctx, &model.AuditLog{
+		ActorId:    user.ID,
+		ActorName:  user.Name,
+		TargetType: model.AuditTargetUser,
+		TargetId:   auditUserTarget(user.ID),
+		Action:     model.AuditActionIdentityLink,
+	}, map[string]interface{}{"provider": provider, "method": method})
+}
+
+// availableName 依次尝试 preferred_username、邮箱前缀和 name，经过审核后重名时追加随机数字。
+// 同时返回所选名字的审核结果，用户创建后记录待复核的内容
+func (s *oidcService) availableName(ctx context.Context, claims *oidc.Claims, provider string) (string, *ModerationResult, error) {
+	base := ""
+	var review *ModerationResult
+	candidates := []string{claims.PreferredUsername, strings.SplitN(claims.Email, "@", 2)[0], claims.Name}
+	for _, c := range candidates {
+		c = oidcNameInvalidChars.ReplaceAllString(strings.TrimSpace(c), "")
+		if c == "" {
+			continue
+		}
+		if r := []rune(c); len(r) > oidcNameMaxLen {
+			c = string(r[:oidcNameMaxLen])
+		}
+		res, err := s.moderationService.Review(ctx, ModerationFieldName, c)
+		if err == nil && res.Text == c {
+			base, review = c, res
+			break
+		}
+	}
+	if base == "" {
+		base = provider + "_user"
+	}
+
+	name := base
+	for i := 0; i < 5; i++ {
+		user, err := s.userRepo.FindByName(ctx, name)
+		if err != nil {
+			return "", nil, err
+		}
+		if user == nil {
+			return name, review, nil
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(10000))
+		if err != nil {
+			return "", nil, err
+		}
+		name = base + "_" + n.String()
+	}
+	return "", nil, v1.ErrUserNameAlreadyUse
+}
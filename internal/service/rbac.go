@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"strconv"
+
+	v1 "go-chat/api/v1"
+	"go-chat/internal/model"
+	"go-chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+type RBACService interface {
+	// HasPermission 用户的角色为 UserBasics.Identity 加上额外分配的角色，结果缓存在 redis
+	HasPermission(ctx context.Context, userName string, permission string) (bool, error)
+	ListRoles(ctx context.Context) ([]*model.Role, error)
+	ListPermissions(ctx context.Context) ([]*model.Permission, error)
+	CreateRole(ctx context.Context, operatorName string, req *v1.CreateRoleRequest) (*model.Role, error)
+	SetRolePermissions(ctx context.Context, operatorName string, roleId uint, req *v1.SetRolePermissionsRequest) error
+	AssignUserRole(ctx context.Context, operatorName string, userId uint, roleName string) error
+	RevokeUserRole(ctx context.Context, operatorName string, userId uint, roleName string) error
+}
+
+func NewRBACService(
+	service *Service,
+	auditService AuditService,
+	rbacRepo repository.RBACRepository,
+	userRepo repository.UserRepository,
+) RBACService {
+	return &rbacService{
+		Service:      service,
+		auditService: auditService,
+		rbacRepo:     rbacRepo,
+		userRepo:     userRepo,
+	}
+}
+
+type rbacService struct {
+	*Service
+	auditService AuditService
+	rbacRepo     repository.RBACRepository
+	userRepo     repository.UserRepository
+}
+
+func (s *rbacService) HasPermission(ctx context.Context, userName string, permission string) (bool, error) {
+	codes, err := s.rbacRepo.GetCachedPermissions(ctx, userName)
+	if err != nil {
+		// 缓存不可用时直接查询数据库
+		s.logger.WithContext(ctx).Warn("rbac cache get failed", zap.Error(err))
+	}
+	if codes == nil {
+		if codes, err = s.loadPermissions(ctx, userName); err != nil {
+			return false, err
+		}
+		if err = s.rbacRepo.SetCachedPermissions(ctx, userName, codes); err != nil {
+			s.logger.WithContext(ctx).Warn("rbac cache set failed", zap.Error(err))
+		}
+	}
+
+	for _, c := range codes {
+		if c == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *rbacService) loadPermissions(ctx context.Context, userName string) ([]string, error) {
+	user, err := s.userRepo.FindByName(ctx, userName)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return []string{}, nil
+	}
+	roles, err := s.rbacRepo.ListUserRoleNames(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Identity != "" {
+		roles = append(roles, user.Identity)
+	}
+	return s.rbacRepo.ListPermissionCodesByRoleNames(ctx, roles)
+}
+
+func (s *rbacService) ListRoles(ctx context.Context) ([]*model.Role, error) {
+	return s.rbacRepo.ListRoles(ctx)
+}
+
+func (s *rbacService) ListPermissions(ctx context.Context) ([]*model.Permission, error) {
+	return s.rbacRepo.ListPermissions(ctx)
+}
+
+func (s *rbacService) CreateRole(ctx context.Context, operatorName string, req *v1.CreateRoleRequest) (*model.Role, error) {
+	operator, err := s.operator(ctx, operatorName)
+	if err != nil {
+		return nil, err
+	}
+	exist, err := s.rbacRepo.FindRoleByName(ctx, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if exist != nil {
+		return nil, v1.ErrRoleAlreadyExists
+	}
+	permissions, err := s.findPermissions(ctx, req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role := &model.Role{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: permissions,
+	}
+	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.rbacRepo.CreateRole(ctx, role); err != nil {
+			return err
+		}
+		return s.audit(ctx, operator, model.AuditTargetRole, strconv.FormatUint(uint64(role.ID), 10), model.AuditActionRoleCreate,
+			map[string]interface{}{"role": role.Name, "permissions": req.Permissions})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+func (s *rbacService) SetRolePermissions(ctx context.Context, operatorName string, roleId uint, req *v1.SetRolePermissionsRequest) error {
+	operator, err := s.operator(ctx, operatorName)
+	if err != nil {
+		return err
+	}
+	role, err := s.rbacRepo.FindRoleById(ctx, roleId)
+	if err != nil {
+		return err
+	}
+	if role == nil {
+		return v1.ErrRoleNotFound
+	}
+	permissions, err := s.findPermissions(ctx, req.Permissions)
+	if err != nil {
+		return err
+	}
+	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.rbacRepo.SetRolePermissions(ctx, role, permissions); err != nil {
+			return err
+		}
+		return s.audit(ctx, operator, model.AuditTargetRole, strconv.FormatUint(uint64(role.ID), 10), model.AuditActionRolePermissions,
+			map[string]interface{}{"role": role.Name, "permissions": req.Permissions})
+	})
+	if err != nil {
+		return err
+	}
+	return s.rbacRepo.InvalidatePermissionCache(ctx)
+}
+
+func (s *rbacService) AssignUserRole(ctx context.Context, operatorName string, userId uint, roleName string) error {
+	operator, err := s.operator(ctx, operatorName)
+	if err != nil {
+		return err
+	}
+	role, err := s.findUserAndRole(ctx, userId, roleName)
+	if err != nil {
+		return err
+	}
+	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.rbacRepo.AssignUserRole(ctx, userId, role.ID); err != nil {
+			return err
+		}
+		return s.audit(ctx, operator, model.AuditTargetUser, auditUserTarget(userId), model.AuditActionRoleAssign,
+			map[string]interface{}{"role": role.Name})
+	})
+	if err != nil {
+		return err
+	}
+	return s.rbacRepo.InvalidatePermissionCache(ctx)
+}
+
+func (s *rbacService) RevokeUserRole(ctx context.Context, operatorName string, userId uint, roleName string) error {
+	operator, err := s.operator(ctx, operatorName)
+	if err != nil {
+		return err
+	}
+	role, err := s.findUserAndRole(ctx, userId, roleName)
+	if err != nil {
+		return err
+	}
+	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.rbacRepo.RevokeUserRole(ctx, userId, role.ID); err != nil {
+			return err
+		}
+		return s.audit(ctx, operator, model.AuditTargetUser, auditUserTarget(userId), model.AuditActionRoleRevoke,
+			map[string]interface{}{"role": role.Name})
+	})
+	if err != nil {
+		return err
+	}
+	return s.rbacRepo.InvalidatePermissionCache(ctx)
+}
+
+func (s *rbacService) operator(ctx context.Context, name string) (*model.UserBasics, error) {
+	user, err := s.userRepo.FindByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, v1.ErrUnauthorized
+	}
+	return user, nil
+}
+
+func (s *rbacService) audit(ctx context.Context, operator *model.UserBasics, targetType string, targetId string, action string, metadata map[string]interface{}) error {
+	return s.auditService.Record(ctx, &model.AuditLog{
+		ActorId:    operator.ID,
+		ActorName:  operator.Name,
+		TargetType: targetType,
+		TargetId:   targetId,
+		Action:     action,
+	}, metadata)
+}
+
+func (s *rbacService) findUserAndRole(ctx context.Context, userId uint, roleName string) (*model.Role, error) {
+	user, err := s.userRepo.FindUserInfoById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, v1.ErrUserNotFound
+	}
+	role, err := s.rbacRepo.FindRoleByName(ctx, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, v1.ErrRoleNotFound
+	}
+	return role, nil
+}
+
+// findPermissions 权限码必须都存在
+func (s *rbacService) findPermissions(ctx context.Context, codes []string) ([]*model.Permission, error) {
+	permissions, err := s.rbacRepo.FindPermissionsByCodes(ctx, codes)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]bool, len(permissions))
+	for _, p := range permissions {
+		found[p.Code] = true
+	}
+	for _, c := range codes {
+		if !found[c] {
+			return nil, v1.ErrPermissionNotFound
+		}
+	}
+	return permissions, nil
+}
@@ -0,0 +1,333 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/spf13/viper"
+	v1 "go-chat/api/v1"
+	"go-chat/internal/model"
+	"go-chat/internal/repository"
+	"go-chat/pkg/event"
+	"go.uber.org/zap"
+)
+
+// ReportThreshold 一段时间内被不同用户举报达到次数后自动处罚
+type ReportThreshold struct {
+	Reports     int64         `mapstructure:"reports"`
+	Window      time.Duration `mapstructure:"window"`
+	Restriction string        `mapstructure:"restriction"`
+	Duration    time.Duration `mapstructure:"duration"`
+}
+
+var restrictionSeverity = map[string]int{
+	model.ModerationActionMute: 1,
+	model.ModerationActionBan:  2,
+}
+
+type ReportService interface {
+	CreateReport(ctx context.Context, reporterName string, req *v1.CreateReportRequest) (*model.Report, error)
+	ListReports(ctx context.Context, moderatorName string, req *v1.ListReportsRequest) (*v1.ListReportsResponseData, error)
+	GetReport(ctx context.Context, moderatorName string, id uint) (*v1.GetReportResponseData, error)
+	ClaimReport(ctx context.Context, moderatorName string, id uint) error
+	ResolveReport(ctx context.Context, moderatorName string, id uint, req *v1.ResolveReportRequest) error
+	// CheckRestriction 用户处于 restrictionType 处罚中时返回 ErrUserBanned/ErrUserMuted
+	CheckRestriction(ctx context.Context, userId uint, restrictionType string) error
+}
+
+func NewReportService(
+	service *Service,
+	conf *viper.Viper,
+	hub *event.Hub,
+	auditService AuditService,
+	reportRepo repository.ReportRepository,
+	userRepo repository.UserRepository,
+) ReportService {
+	var thresholds []ReportThreshold
+	if err := conf.UnmarshalKey("report.thresholds", &thresholds); err != nil {
+		panic(err)
+	}
+	return &reportService{
+		Service:      service,
+		hub:          hub,
+		auditService: auditService,
+		thresholds:   thresholds,
+		reportRepo:   reportRepo,
+		userRepo:     userRepo,
+	}
+}
+
+type reportService struct {
+	*Service
+	hub          *event.Hub
+	auditService AuditService
+	thresholds   []ReportThreshold
+	reportRepo   repository.ReportRepository
+	userRepo     repository.UserRepository
+}
+
+func (s *reportService) CreateReport(ctx context.Context, reporterName string, req *v1.CreateReportRequest) (*model.Report, error) {
+	reporter, err := s.currentUser(ctx, reporterName)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &model.Report{
+		ReporterId: reporter.ID,
+		TargetType: req.TargetType,
+		TargetId:   req.TargetId,
+		Reason:     req.Reason,
+		Detail:     req.Detail,
+		Status:     model.ReportStatusPending,
+	}
+	// 举报用户时校验用户存在，消息和群组暂无法定位到所属用户
+	if req.TargetType == model.ReportTargetUser {
+		targetId, err := strconv.ParseUint(req.TargetId, 10, 64)
+		if err != nil || uint(targetId) == reporter.ID {
+			return nil, v1.ErrBadRequest
+		}
+		target, err := s.userRepo.FindUserInfoById(ctx, uint(targetId))
+		if err != nil {
+			return nil, err
+		}
+		if target == nil {
+			return nil, v1.ErrUserNotFound
+		}
+		report.TargetUserId = target.ID
+	}
+
+	if err = s.reportRepo.Create(ctx, report); err != nil {
+		return nil, err
+	}
+
+	if report.TargetUserId != 0 {
+		if err = s.autoRestrict(ctx, report.TargetUserId); err != nil {
+			// 举报已保存，自动处罚失败不影响举报结果
+			s.logger.WithContext(ctx).Error("auto restrict failed", zap.Uint("user_id", report.TargetUserId), zap.Error(err))
+		}
+	}
+	return report, nil
+}
+
+// autoRestrict 达到举报阈值时自动处罚，多个阈值同时满足时取最严重的
+func (s *reportService) autoRestrict(ctx context.Context, userId uint) error {
+	now := time.Now()
+	var matched *ReportThreshold
+	var reporters int64
+	for i := range s.thresholds {
+		t := &s.thresholds[i]
+		count, err := s.reportRepo.CountReporters(ctx, userId, now.Add(-t.Window))
+		if err != nil {
+			return err
+		}
+		if count >= t.Reports && (matched == nil || restrictionSeverity[t.Restriction] > restrictionSeverity[matched.Restriction]) {
+			matched = t
+			reporters = count
+		}
+	}
+	if matched == nil {
+		return nil
+	}
+
+	active, err := s.reportRepo.FindActiveRestriction(ctx, userId, matched.Restriction, now)
+	if err != nil {
+		return err
+	}
+	if active != nil {
+		return nil
+	}
+
+	expiresAt := now.Add(matched.Duration)
+	note := fmt.Sprintf("auto: reported by %d users within %s", reporters, matched.Window)
+	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.reportRepo.CreateRestriction(ctx, &model.UserRestriction{
+			UserId:    userId,
+			Type:      matched.Restriction,
+			Reason:    note,
+			ExpiresAt: &expiresAt,
+		}); err != nil {
+			return err
+		}
+		return s.reportRepo.CreateAction(ctx, &model.ModerationAction{
+			TargetUserId: userId,
+			Action:       matched.Restriction,
+			Note:         note,
+			ExpiresAt:    &expiresAt,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	s.logger.WithContext(ctx).Warn("user auto restricted", zap.Uint("user_id", userId), zap.String("restriction", matched.Restriction))
+	return nil
+}
+
+func (s *reportService) ListReports(ctx context.Context, moderatorName string, req *v1.ListReportsRequest) (*v1.ListReportsResponseData, error) {
+	if _, err := s.currentUser(ctx, moderatorName); err != nil {
+		return nil, err
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 || req.PageSize > 100 {
+		req.PageSize = 20
+	}
+	reports, total, err := s.reportRepo.List(ctx, req.Status, (req.Page-1)*req.PageSize, req.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.ListReportsResponseData{List: reports, Total: total}, nil
+}
+
+func (s *reportService) GetReport(ctx context.Context, moderatorName string, id uint) (*v1.GetReportResponseData, error) {
+	if _, err := s.currentUser(ctx, moderatorName); err != nil {
+		return nil, err
+	}
+	report, err := s.reportRepo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	actions, err := s.reportRepo.ListActions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.GetReportResponseData{Report: report, Actions: actions}, nil
+}
+
+func (s *reportService) ClaimReport(ctx context.Context, moderatorName string, id uint) error {
+	moderator, err := s.currentUser(ctx, moderatorName)
+	if err != nil {
+		return err
+	}
+	report, err := s.reportRepo.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if report.Status == model.ReportStatusResolved {
+		return v1.ErrReportResolved
+	}
+	ok, err := s.reportRepo.Claim(ctx, id, moderator.ID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return v1.ErrReportAlreadyClaimed
+	}
+	return nil
+}
+
+func (s *reportService) ResolveReport(ctx context.Context, moderatorName string, id uint, req *v1.ResolveReportRequest) error {
+	moderator, err := s.currentUser(ctx, moderatorName)
+	if err != nil {
+		return err
+	}
+	report, err := s.reportRepo.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if report.Status == model.ReportStatusResolved {
+		return v1.ErrReportResolved
+	}
+	// 处罚类动作需要能定位到被举报用户
+	if req.Action != model.ModerationActionDismiss && report.TargetUserId == 0 {
+		return v1.ErrBadRequest
+	}
+
+	now := time.Now()
+	var expiresAt *time.Time
+	if req.Duration > 0 {
+		t := now.Add(time.Duration(req.Duration) * time.Hour)
+		expiresAt = &t
+	}
+
+	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+		ok, err := s.reportRepo.Resolve(ctx, id, moderator.ID, req.Action, now)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return v1.ErrReportNotClaimed
+		}
+		if err = s.reportRepo.CreateAction(ctx, &model.ModerationAction{
+			ReportId:     id,
+			ModeratorId:  moderator.ID,
+			TargetUserId: report.TargetUserId,
+			Action:       req.Action,
+			Note:         req.Note,
+			ExpiresAt:    expiresAt,
+		}); err != nil {
+			return err
+		}
+		if err = s.auditService.Record(ctx, &model.AuditLog{
+			ActorId:    moderator.ID,
+			ActorName:  moderator.Name,
+			TargetType: model.AuditTargetReport,
+			TargetId:   strconv.FormatUint(uint64(id), 10),
+			Action:     model.AuditActionReportResolve,
+		}, map[string]interface{}{
+			"action":         req.Action,
+			"target_user_id": report.TargetUserId,
+			"duration":       req.Duration,
+		}); err != nil {
+			return err
+		}
+
+		switch req.Action {
+		case model.ModerationActionMute, model.ModerationActionBan:
+			return s.reportRepo.CreateRestriction(ctx, &model.UserRestriction{
+				UserId:    report.TargetUserId,
+				Type:      req.Action,
+				Reason:    req.Note,
+				ExpiresAt: expiresAt,
+			})
+		case model.ModerationActionDelete:
+			if report.TargetType == model.ReportTargetUser {
+				return s.userRepo.ClearProfileContent(ctx, report.TargetUserId)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if req.Action == model.ModerationActionWarn {
+		s.notifyWarning(report.TargetUserId, req.Note)
+	}
+	return nil
+}
+
+// notifyWarning 通过实时通道提醒被警告的用户
+func (s *reportService) notifyWarning(userId uint, note string) {
+	payload, _ := json.Marshal(map[string]string{"note": note})
+	s.hub.Publish(userId, &event.Event{Type: "moderation.warning", Payload: payload})
+}
+
+func (s *reportService) CheckRestriction(ctx context.Context, userId uint, restrictionType string) error {
+	restriction, err := s.reportRepo.FindActiveRestriction(ctx, userId, restrictionType, time.Now())
+	if err != nil {
+		return err
+	}
+	if restriction == nil {
+		return nil
+	}
+	if restrictionType == model.ModerationActionBan {
+		return v1.ErrUserBanned
+	}
+	return v1.ErrUserMuted
+}
+
+// currentUser token 中的 UserId 为用户名，管理员权限由路由上的 RequirePermission 校验
+func (s *reportService) currentUser(ctx context.Context, name string) (*model.UserBasics, error) {
+	user, err := s.userRepo.FindByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, v1.ErrUnauthorized
+	}
+	return user, nil
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	v1 "go-chat/api/v1"
+	"go-chat/internal/model"
+	"go-chat/internal/repository"
+)
+
+type SessionService interface {
+	// ListSessions 返回用户当前有效的设备会话，currentSessionId 为发起请求的会话
+	ListSessions(ctx context.Context, userName string, currentSessionId string) ([]*v1.SessionData, error)
+	RenameSession(ctx context.Context, userName string, sessionId string, req *v1.RenameSessionRequest) error
+	// RevokeSession 注销单个会话，注销当前会话相当于退出登录
+	RevokeSession(ctx context.Context, userName string, sessionId string) error
+	// RevokeOtherSessions 注销除 currentSessionId 外的所有会话，返回注销的数量
+	RevokeOtherSessions(ctx context.Context, userName string, currentSessionId string) (int, error)
+}
+
+func NewSessionService(
+	service *Service,
+	tokenService TokenService,
+	auditService AuditService,
+	sessionRepo repository.SessionRepository,
+	userRepo repository.UserRepository,
+) SessionService {
+	return &sessionService{
+		Service:      service,
+		tokenService: tokenService,
+		auditService: auditService,
+		sessionRepo:  sessionRepo,
+		userRepo:     userRepo,
+	}
+}
+
+type sessionService struct {
+	*Service
+	tokenService TokenService
+	auditService AuditService
+	sessionRepo  repository.SessionRepository
+	userRepo     repository.UserRepository
+}
+
+func (s *sessionService) ListSessions(ctx context.Context, userName string, currentSessionId string) ([]*v1.SessionData, error) {
+	user, err := s.findUser(ctx, userName)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := s.sessionRepo.ListActive(ctx, user.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*v1.SessionData, 0, len(sessions))
+	for _, session := range sessions {
+		list = append(list, &v1.SessionData{
+			SessionId:  session.SessionId,
+			Name:       session.Name,
+			DeviceInfo: session.DeviceInfo,
+			ClientIp:   session.ClientIp,
+			UserAgent:  session.UserAgent,
+			LastSeenAt: session.LastSeenAt,
+			CreateAt:   session.CreateAt,
+			Current:    session.SessionId == currentSessionId,
+		})
+	}
+	return list, nil
+}
+
+func (s *sessionService) RenameSession(ctx context.Context, userName string, sessionId string, req *v1.RenameSessionRequest) error {
+	user, err := s.findUser(ctx, userName)
+	if err != nil {
+		return err
+	}
+	session, err := s.sessionRepo.FindActive(ctx, user.ID, sessionId, time.Now())
+	if err != nil {
+		return err
+	}
+	if session == nil {
+		return v1.ErrSessionNotFound
+	}
+	return s.sessionRepo.Rename(ctx, session.ID, req.Name)
+}
+
+func (s *sessionService) RevokeSession(ctx context.Context, userName string, sessionId string) error {
+	user, err := s.findUser(ctx, userName)
+	if err != nil {
+		return err
+	}
+	session, err := s.sessionRepo.FindActive(ctx, user.ID, sessionId, time.Now())
+	if err != nil {
+		return err
+	}
+	if session == nil {
+		return v1.ErrSessionNotFound
+	}
+	return s.revoke(ctx, user, []*model.Session{session})
+}
+
+func (s *sessionService) RevokeOtherSessions(ctx context.Context, userName string, currentSessionId string) (int, error) {
+	user, err := s.findUser(ctx, userName)
+	if err != nil {
+		return 0, err
+	}
+	sessions, err := s.sessionRepo.ListActive(ctx, user.ID, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	others := make([]*model.Session, 0, len(sessions))
+	for _, session := range sessions {
+		if session.SessionId != currentSessionId {
+			others = append(others, session)
+		}
+	}
+	if len(others) == 0 {
+		return 0, nil
+	}
+	if err = s.revoke(ctx, user, others); err != nil {
+		return 0, err
+	}
+	return len(others), nil
+}
+
+func (s *sessionService) revoke(ctx context.Context, user *model.UserBasics, sessions []*model.Session) error {
+	sessionIds := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		sessionIds = append(sessionIds, session.SessionId)
+	}
+	// 事务提交后才断开连接，回滚时客户端保持在线
+	err := s.tm.Transaction(ctx, func(ctx context.Context) error {
+		for _, session := range sessions {
+			if err := s.auditService.Record(ctx, &model.AuditLog{
+				ActorId:    user.ID,
+				ActorName:  user.Name,
+				TargetType: model.AuditTargetUser,
+				TargetId:   auditUserTarget(user.ID),
+				Action:     model.AuditActionSessionRevoke,
+			}, map[string]interface{}{
+				"session_id":  session.SessionId,
+				"name":        session.Name,
+				"device_info": session.DeviceInfo,
+			}); err != nil {
+				return err
+			}
+		}
+		return s.tokenService.RevokeSessions(ctx, user, sessionIds)
+	})
+	if err != nil {
+		return err
+	}
+	s.tokenService.CloseSessions(ctx, user, sessionIds)
+	return nil
+}
+
+func (s *sessionService) findUser(ctx context.Context, userName string) (*model.UserBasics, error) {
+	user, err := s.userRepo.FindByName(ctx, userName)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, v1.ErrUnauthorized
+	}
+	return user, nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/viper"
+	v1 "go-chat/api/v1"
+	"go-chat/pkg/clientinfo"
+	"go-chat/pkg/ratelimit"
+	"go-chat/pkg/sms"
+	"go-chat/pkg/verifycode"
+	"go.uber.org/zap"
+)
+
+type SmsService interface {
+	// SendCode 向 Normalize 后的号码发送验证码，purpose 区分登录、绑定等用途
+	SendCode(ctx context.Context, phone string, purpose string) error
+	// CheckCode 验证通过后验证码立即作废，连续输错后锁定
+	CheckCode(ctx context.Context, phone string, code string, purpose string) error
+}
+
+func NewSmsService(
+	service *Service,
+	conf *viper.Viper,
+	sender sms.Sender,
+	limiter ratelimit.Limiter,
+	codes *verifycode.Manager,
+) SmsService {
+	return &smsService{
+		Service: service,
+		sender:  sender,
+		limiter: limiter,
+		codes:   codes,
+		phoneLimit: ratelimit.Limit{
+			Rate:   conf.GetInt("sms.limits.phone.rate"),
+			Period: conf.GetDuration("sms.limits.phone.period"),
+		},
+		ipLimit: ratelimit.Limit{
+			Rate:   conf.GetInt("sms.limits.ip.rate"),
+			Period: conf.GetDuration("sms.limits.ip.period"),
+		},
+	}
+}
+
+type smsService struct {
+	*Service
+	sender  sms.Sender
+	limiter ratelimit.Limiter
+	codes   *verifycode.Manager
+	// phoneLimit、ipLimit 限制每个号码和每个 IP 的发送量，防止短信轰炸和刷量
+	phoneLimit ratelimit.Limit
+	ipLimit    ratelimit.Limit
+}
+
+func (s *smsService) SendCode(ctx context.Context, phone string, purpose string) error {
+	if err := s.allow(ctx, "sms:phone:"+phone, s.phoneLimit); err != nil {
+		return err
+	}
+	if ip := clientinfo.FromContext(ctx).IP; ip != "" {
+		if err := s.allow(ctx, "sms:ip:"+ip, s.ipLimit); err != nil {
+			return err
+		}
+	}
+
+	target := smsCodeTarget(phone, purpose)
+	code, err := s.codes.Issue(ctx, target)
+	if err != nil {
+		return verifyCodeError(err, v1.ErrSmsCodeError)
+	}
+	text := fmt.Sprintf("【go-chat】您的验证码是 %s，%d 分钟内有效，请勿泄露给他人。", code, int(s.codes.TTL().Minutes()))
+	if err = s.sender.Send(ctx, phone, text); err != nil {
+		s.logger.WithContext(ctx).Error("send sms failed", zap.String("phone", sms.Mask(phone)), zap.Error(err))
+		if err := s.codes.Invalidate(ctx, target); err != nil {
+			s.logger.WithContext(ctx).Error("invalidate sms code failed", zap.Error(err))
+		}
+		return v1.ErrSendSmsFailed
+	}
+	return nil
+}
+
+func (s *smsService) CheckCode(ctx context.Context, phone string, code string, purpose string) error {
+	err := s.codes.Verify(ctx, smsCodeTarget(phone, purpose), code)
+	return verifyCodeError(err, v1.ErrSmsCodeError)
+}
+
+// allow 没有配置的限额不限制；限流后端出错时放行，只记录日志
+func (s *smsService) allow(ctx context.Context, key string, limit ratelimit.Limit) error {
+	if limit.Rate <= 0 || limit.Period <= 0 {
+		return nil
+	}
+	res, err := s.limiter.Allow(ctx, key, limit)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("sms rate limit error", zap.Error(err))
+		return nil
+	}
+	if !res.Allowed {
+		return v1.ErrTooManyRequests
+	}
+	return nil
+}
+
+func smsCodeTarget(phone string, purpose string) verifycode.Target {
+	return verifycode.Target{Channel: "sms", Address: phone, Purpose: purpose}
+}
@@ -0,0 +1,321 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/spf13/viper"
+	v1 "go-chat/api/v1"
+	"go-chat/internal/model"
+	"go-chat/internal/repository"
+	"go-chat/pkg/clientinfo"
+	"go-chat/pkg/event"
+	"go-chat/pkg/jwt"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+type TokenService interface {
+	// IsRevoked 供 StrictAuth 判断 token 是否已被吊销
+	IsRevoked(ctx context.Context, claims *jwt.MyCustomClaims) (bool, error)
+	// IssueTokens 登录成功后签发 access token 和 refresh token，开启新的 token 家族
+	IssueTokens(ctx context.Context, user *model.UserBasics) (*v1.TokenPair, error)
+	// Refresh 轮换 refresh token，已轮换过的 refresh token 再次出现时吊销整个家族
+	Refresh(ctx context.Context, refreshToken string) (*v1.TokenPair, error)
+	// RevokeUserTokens 使用户当前所有 token 失效，并通知、断开在线的连接
+	RevokeUserTokens(ctx context.Context, user *model.UserBasics) error
+	// RevokeAccessTokens 使用户已签发的 access token 失效并断开实时连接，
+	// refresh token 仍然有效。用于改名后让客户端刷新出带新用户名的 token
+	RevokeAccessTokens(ctx context.Context, userId uint) error
+	// RevokeSessions 在数据库中注销指定的设备会话，可在调用方的事务中执行，提交后再调用 CloseSessions
+	RevokeSessions(ctx context.Context, user *model.UserBasics, sessionIds []string) error
+	// CloseSessions 在 Redis 中吊销会话的 access token，并通知、断开会话的实时连接
+	CloseSessions(ctx context.Context, user *model.UserBasics, sessionIds []string)
+}
+
+func NewTokenService(
+	service *Service,
+	conf *viper.Viper,
+	hub *event.Hub,
+	auditService AuditService,
+	reportService ReportService,
+	tokenRepo repository.TokenRepository,
+	sessionRepo repository.SessionRepository,
+	userRepo repository.UserRepository,
+) TokenService {
+	accessTTL := conf.GetDuration("security.jwt.access_token_ttl")
+	if accessTTL <= 0 {
+		accessTTL = defaultAccessTokenTTL
+	}
+	refreshTTL := conf.GetDuration("security.jwt.refresh_token_ttl")
+	if refreshTTL <= 0 {
+		refreshTTL = defaultRefreshTokenTTL
+	}
+	return &tokenService{
+		Service:       service,
+		hub:           hub,
+		auditService:  auditService,
+		reportService: reportService,
+		tokenRepo:     tokenRepo,
+		sessionRepo:   sessionRepo,
+		userRepo:      userRepo,
+		accessTTL:     accessTTL,
+		refreshTTL:    refreshTTL,
+	}
+}
+
+type tokenService struct {
+	*Service
+	hub           *event.Hub
+	auditService  AuditService
+	reportService ReportService
+	tokenRepo     repository.TokenRepository
+	sessionRepo   repository.SessionRepository
+	userRepo      repository.UserRepository
+	accessTTL     time.Duration
+	refreshTTL    time.Duration
+}
+
+func (s *tokenService) IsRevoked(ctx context.Context, claims *jwt.MyCustomClaims) (bool, error) {
+	uid := claims.Uid()
+	if uid == 0 {
+		// 没有 sub 的旧 token 无法按用户吊销，要求重新登录
+		return true, nil
+	}
+	revokedBefore, tokenRevoked, err := s.tokenRepo.GetRevocation(ctx, uid, claims.SessionId, claims.ID)
+	if err != nil {
+		return false, err
+	}
+	if tokenRevoked {
+		return true, nil
+	}
+	if revokedBefore.IsZero() {
+		return false, nil
+	}
+	// IssuedAt 精确到秒，只吊销更早一秒及之前签发的 token，否则吊销后立即重新登录拿到的新 token 也会失效。
+	// 同一秒内、吊销之前签发的 token 由会话吊销覆盖
+	return claims.IssuedAt == nil || claims.IssuedAt.Unix() < revokedBefore.Unix(), nil
+}
+
+func (s *tokenService) IssueTokens(ctx context.Context, user *model.UserBasics) (*v1.TokenPair, error) {
+	familyId, err := s.sid.GenString()
+	if err != nil {
+		return nil, err
+	}
+	// 每次登录开启一个设备会话，会话 ID 即 token 家族 ID
+	info := clientinfo.FromContext(ctx)
+	now := time.Now()
+	if err = s.sessionRepo.Create(ctx, &model.Session{
+		UserId:     user.ID,
+		SessionId:  familyId,
+		DeviceInfo: info.DeviceInfo,
+		ClientIp:   info.IP,
+		UserAgent:  info.UserAgent,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.refreshTTL),
+	}); err != nil {
+		return nil, err
+	}
+	return s.issue(ctx, user, familyId)
+}
+
+func (s *tokenService) Refresh(ctx context.Context, refreshToken string) (*v1.TokenPair, error) {
+	token, err := s.tokenRepo.FindRefreshTokenByHash(ctx, hashRefreshToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if token == nil || token.RevokedAt != nil || now.After(token.ExpiresAt) {
+		return nil, v1.ErrRefreshTokenInvalid
+	}
+	if token.UsedAt != nil {
+		return nil, s.handleReuse(ctx, token)
+	}
+
+	user, err := s.userRepo.FindUserInfoById(ctx, token.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.DisabledAt != nil {
+		return nil, v1.ErrRefreshTokenInvalid
+	}
+	if err = s.reportService.CheckRestriction(ctx, user.ID, model.ModerationActionBan); err != nil {
+		return nil, err
+	}
+
+	var pair *v1.TokenPair
+	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+		ok, err := s.tokenRepo.MarkRefreshTokenUsed(ctx, token.ID, now)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			// 并发请求已经轮换过该 token
+			return v1.ErrRefreshTokenReused
+		}
+		pair, err = s.issue(ctx, user, token.FamilyId)
+		if err != nil {
+			return err
+		}
+		return s.sessionRepo.Touch(ctx, token.FamilyId, clientinfo.FromContext(ctx).IP, now, now.Add(s.refreshTTL))
+	})
+	if err == v1.ErrRefreshTokenReused {
+		return nil, s.handleReuse(ctx, token)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+func (s *tokenService) RevokeUserTokens(ctx context.Context, user *model.UserBasics) error {
+	now := time.Now()
+	if err := s.tokenRepo.RevokeUserRefreshTokens(ctx, user.ID, now); err != nil {
+		return err
+	}
+	sessionIds, err := s.sessionRepo.RevokeUserSessions(ctx, user.ID, now)
+	if err != nil {
+		return err
+	}
+	if err = s.tokenRepo.SetRevokedBefore(ctx, user.ID, now); err != nil {
+		return err
+	}
+	s.closeSessions(ctx, user.ID, sessionIds)
+	s.hub.CloseUser(user.ID)
+	return nil
+}
+
+func (s *tokenService) RevokeAccessTokens(ctx context.Context, userId uint) error {
+	// 只有 accessTTL 内签发的 access token 还可能有效，按 jti 逐个吊销，不影响之后刷新出来的 token
+	tokenIds, err := s.tokenRepo.FindAccessTokenIds(ctx, userId, time.Now().Add(-s.accessTTL))
+	if err != nil {
+		return err
+	}
+	for _, id := range tokenIds {
+		if err = s.tokenRepo.RevokeAccessToken(ctx, id, s.accessTTL); err != nil {
+			return err
+		}
+	}
+	s.hub.Publish(userId, &event.Event{Type: "token.expired"})
+	s.hub.CloseUser(userId)
+	return nil
+}
+
+func (s *tokenService) RevokeSessions(ctx context.Context, user *model.UserBasics, sessionIds []string) error {
+	if len(sessionIds) == 0 {
+		return nil
+	}
+	now := time.Now()
+	if err := s.sessionRepo.Revoke(ctx, sessionIds, now); err != nil {
+		return err
+	}
+	for _, id := range sessionIds {
+		if _, err := s.tokenRepo.RevokeFamily(ctx, id, now, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *tokenService) CloseSessions(ctx context.Context, user *model.UserBasics, sessionIds []string) {
+	if len(sessionIds) == 0 {
+		return
+	}
+	s.closeSessions(ctx, user.ID, sessionIds)
+}
+
+// closeSessions 在 Redis 中吊销会话的 access token，并断开会话的实时连接
+func (s *tokenService) closeSessions(ctx context.Context, userId uint, sessionIds []string) {
+	for _, id := range sessionIds {
+		// 写入失败时仍有 access token 的有效期兜底，不影响已提交的吊销
+		if err := s.tokenRepo.RevokeSession(ctx, id, s.accessTTL); err != nil {
+			s.logger.WithContext(ctx).Error("revoke session error", zap.String("session_id", id), zap.Error(err))
+		}
+	}
+	payload, _ := json.Marshal(map[string]interface{}{"sessionIds": sessionIds})
+	s.hub.Publish(userId, &event.Event{Type: "session.revoked", Payload: payload})
+	for _, id := range sessionIds {
+		s.hub.CloseSession(userId, id)
+	}
+}
+
+// handleReuse 已轮换的 refresh token 被再次使用，说明 token 可能已泄露，吊销整个家族
+func (s *tokenService) handleReuse(ctx context.Context, token *model.RefreshToken) error {
+	now := time.Now()
+	var tokenIds []string
+	err := s.tm.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		// 只有 accessTTL 内签发的 access token 还可能有效
+		tokenIds, err = s.tokenRepo.RevokeFamily(ctx, token.FamilyId, now, now.Add(-s.accessTTL))
+		if err != nil {
+			return err
+		}
+		if err = s.sessionRepo.Revoke(ctx, []string{token.FamilyId}, now); err != nil {
+			return err
+		}
+		return s.auditService.Record(ctx, &model.AuditLog{
+			TargetType: model.AuditTargetUser,
+			TargetId:   auditUserTarget(token.UserId),
+			Action:     model.AuditActionRefreshTokenReuse,
+		}, map[string]interface{}{"family_id": token.FamilyId})
+	})
+	if err != nil {
+		return err
+	}
+	for _, id := range tokenIds {
+		if err = s.tokenRepo.RevokeAccessToken(ctx, id, s.accessTTL); err != nil {
+			return err
+		}
+	}
+	s.closeSessions(ctx, token.UserId, []string{token.FamilyId})
+	s.logger.WithContext(ctx).Warn("refresh token reused, family revoked",
+		zap.Uint("user_id", token.UserId), zap.String("family_id", token.FamilyId))
+	return v1.ErrRefreshTokenReused
+}
+
+func (s *tokenService) issue(ctx context.Context, user *model.UserBasics, familyId string) (*v1.TokenPair, error) {
+	tokenId, err := s.sid.GenString()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	accessToken, err := s.jwt.GenToken(user.Name, user.ID, familyId, tokenId, now.Add(s.accessTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		return nil, err
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(b)
+	if err = s.tokenRepo.CreateRefreshToken(ctx, &model.RefreshToken{
+		UserId:        user.ID,
+		FamilyId:      familyId,
+		TokenHash:     hashRefreshToken(refreshToken),
+		AccessTokenId: tokenId,
+		ExpiresAt:     now.Add(s.refreshTTL),
+	}); err != nil {
+		return nil, err
+	}
+
+	return &v1.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.accessTTL / time.Second),
+	}, nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
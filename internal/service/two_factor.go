@@ -0,0 +1,352 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	v1 "go-chat/api/v1"
+	"go-chat/internal/model"
+	"go-chat/internal/repository"
+	"go-chat/pkg/totp"
+)
+
+const (
+	defaultTotpIssuer = "go-chat"
+	// challengeTTL 输入二次验证码的时限
+	challengeTTL = 5 * time.Minute
+	// challengeMaxAttempts 超过后需要重新登录
+	challengeMaxAttempts = 5
+	recoveryCodeCount    = 10
+	// totpSkew 允许前后各一个时间步的时钟偏差
+	totpSkew = 1
+)
+
+type TwoFactorService interface {
+	Status(ctx context.Context, userName string) (*v1.TwoFactorStatusData, error)
+	// Enroll 生成新的 TOTP 密钥，用验证码确认后才生效
+	Enroll(ctx context.Context, userName string) (*v1.TwoFactorEnrollData, error)
+	// Confirm 用第一个验证码确认密钥并开启二次验证，返回恢复码
+	Confirm(ctx context.Context, userName string, code string) (*v1.RecoveryCodesData, error)
+	Disable(ctx context.Context, userName string, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userName string, code string) (*v1.RecoveryCodesData, error)
+
+	// Enabled 登录时判断是否需要二次验证
+	Enabled(ctx context.Context, userId uint) (bool, error)
+	// CreateChallenge 第一步验证通过后生成 challenge token，method 为第一步的登录方式
+	CreateChallenge(ctx context.Context, user *model.UserBasics, method string) (string, error)
+	// VerifyChallenge 校验 TOTP 或恢复码，成功后 challenge 失效，返回用户 ID 和完整的登录方式
+	VerifyChallenge(ctx context.Context, challengeToken string, code string) (uint, string, error)
+}
+
+func NewTwoFactorService(
+	service *Service,
+	conf *viper.Viper,
+	auditService AuditService,
+	twoFactorRepo repository.TwoFactorRepository,
+	userRepo repository.UserRepository,
+) TwoFactorService {
+	issuer := conf.GetString("security.totp.issuer")
+	if issuer == "" {
+		issuer = defaultTotpIssuer
+	}
+	return &twoFactorService{
+		Service:       service,
+		issuer:        issuer,
+		auditService:  auditService,
+		twoFactorRepo: twoFactorRepo,
+		userRepo:      userRepo,
+	}
+}
+
+type twoFactorService struct {
+	*Service
+	issuer        string
+	auditService  AuditService
+	twoFactorRepo repository.TwoFactorRepository
+	userRepo      repository.UserRepository
+}
+
+func (s *twoFactorService) Status(ctx context.Context, userName string) (*v1.TwoFactorStatusData, error) {
+	user, err := s.findUser(ctx, userName)
+	if err != nil {
+		return nil, err
+	}
+	tf, err := s.twoFactorRepo.FindByUserId(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if tf == nil || tf.EnabledAt == nil {
+		return &v1.TwoFactorStatusData{}, nil
+	}
+	left, err := s.twoFactorRepo.CountRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.TwoFactorStatusData{Enabled: true, RecoveryCodesLeft: left}, nil
+}
+
+func (s *twoFactorService) Enroll(ctx context.Context, userName string) (*v1.TwoFactorEnrollData, error) {
+	user, err := s.findUser(ctx, userName)
+	if err != nil {
+		return nil, err
+	}
+	tf, err := s.twoFactorRepo.FindByUserId(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if tf != nil && tf.EnabledAt != nil {
+		return nil, v1.ErrTwoFactorAlreadyEnabled
+	}
+	if tf == nil {
+		tf = &model.TwoFactor{UserId: user.ID}
+	}
+	if tf.Secret, err = totp.GenerateSecret(); err != nil {
+		return nil, err
+	}
+	tf.LastUsedStep = 0
+	if err = s.twoFactorRepo.Save(ctx, tf); err != nil {
+		return nil, err
+	}
+
+	account := user.Email
+	if account == "" {
+		account = user.Name
+	}
+	return &v1.TwoFactorEnrollData{
+		Secret:          tf.Secret,
+		ProvisioningUri: totp.ProvisioningURI(s.issuer, account, tf.Secret),
+	}, nil
+}
+
+func (s *twoFactorService) Confirm(ctx context.Context, userName string, code string) (*v1.RecoveryCodesData, error) {
+	user, err := s.findUser(ctx, userName)
+	if err != nil {
+		return nil, err
+	}
+	tf, err := s.twoFactorRepo.FindByUserId(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if tf == nil {
+		return nil, v1.ErrTwoFactorNotEnabled
+	}
+	if tf.EnabledAt != nil {
+		return nil, v1.ErrTwoFactorAlreadyEnabled
+	}
+	step, ok := totp.Validate(tf.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, v1.ErrTwoFactorCodeError
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.twoFactorRepo.Enable(ctx, tf.ID, time.Now(), step); err != nil {
+			return err
+		}
+		if err := s.twoFactorRepo.ReplaceRecoveryCodes(ctx, user.ID, hashes); err != nil {
+			return err
+		}
+		return s.audit(ctx, user, model.AuditActionTwoFactorEnable, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &v1.RecoveryCodesData{Codes: codes}, nil
+}
+
+func (s *twoFactorService) Disable(ctx context.Context, userName string, code string) error {
+	user, tf, err := s.findEnabled(ctx, userName)
+	if err != nil {
+		return err
+	}
+	kind, err := s.verifyCode(ctx, tf, code)
+	if err != nil {
+		return err
+	}
+	return s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.twoFactorRepo.Delete(ctx, user.ID); err != nil {
+			return err
+		}
+		return s.audit(ctx, user, model.AuditActionTwoFactorDisable, map[string]interface{}{"code": kind})
+	})
+}
+
+func (s *twoFactorService) RegenerateRecoveryCodes(ctx context.Context, userName string, code string) (*v1.RecoveryCodesData, error) {
+	user, tf, err := s.findEnabled(ctx, userName)
+	if err != nil {
+		return nil, err
+	}
+	kind, err := s.verifyCode(ctx, tf, code)
+	if err != nil {
+		return nil, err
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.twoFactorRepo.ReplaceRecoveryCodes(ctx, user.ID, hashes); err != nil {
+			return err
+		}
+		return s.audit(ctx, user, model.AuditActionRecoveryCodes, map[string]interface{}{"code": kind})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &v1.RecoveryCodesData{Codes: codes}, nil
+}
+
+func (s *twoFactorService) Enabled(ctx context.Context, userId uint) (bool, error) {
+	tf, err := s.twoFactorRepo.FindByUserId(ctx, userId)
+	if err != nil {
+		return false, err
+	}
+	return tf != nil && tf.EnabledAt != nil, nil
+}
+
+func (s *twoFactorService) CreateChallenge(ctx context.Context, user *model.UserBasics, method string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	if err := s.twoFactorRepo.SaveChallenge(ctx, token, &repository.TwoFactorChallenge{
+		UserId: user.ID,
+		Method: method,
+	}, challengeTTL); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *twoFactorService) VerifyChallenge(ctx context.Context, challengeToken string, code string) (uint, string, error) {
+	challenge, err := s.twoFactorRepo.GetChallenge(ctx, challengeToken)
+	if err != nil {
+		return 0, "", err
+	}
+	if challenge == nil {
+		return 0, "", v1.ErrTwoFactorChallengeInvalid
+	}
+	if challenge.Attempts > challengeMaxAttempts {
+		if err = s.twoFactorRepo.DeleteChallenge(ctx, challengeToken); err != nil {
+			return 0, "", err
+		}
+		return 0, "", v1.ErrTwoFactorChallengeInvalid
+	}
+
+	tf, err := s.twoFactorRepo.FindByUserId(ctx, challenge.UserId)
+	if err != nil {
+		return 0, "", err
+	}
+	if tf == nil || tf.EnabledAt == nil {
+		// 第一步验证之后关闭了二次验证
+		return 0, "", v1.ErrTwoFactorChallengeInvalid
+	}
+	kind, err := s.verifyCode(ctx, tf, code)
+	if err != nil {
+		return 0, "", err
+	}
+	if err = s.twoFactorRepo.DeleteChallenge(ctx, challengeToken); err != nil {
+		return 0, "", err
+	}
+	return challenge.UserId, challenge.Method + "+" + kind, nil
+}
+
+// verifyCode 6 位数字按 TOTP 校验，否则按恢复码校验，返回使用的验证方式
+func (s *twoFactorService) verifyCode(ctx context.Context, tf *model.TwoFactor, code string) (string, error) {
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		step, ok := totp.Validate(tf.Secret, code, time.Now(), totpSkew)
+		if !ok {
+			return "", v1.ErrTwoFactorCodeError
+		}
+		ok, err := s.twoFactorRepo.UseStep(ctx, tf.ID, step)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			// 验证码已经用过
+			return "", v1.ErrTwoFactorCodeError
+		}
+		return "totp", nil
+	}
+
+	ok, err := s.twoFactorRepo.UseRecoveryCode(ctx, tf.UserId, hashRecoveryCode(code), time.Now())
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", v1.ErrTwoFactorCodeError
+	}
+	return "recovery_code", nil
+}
+
+func (s *twoFactorService) findUser(ctx context.Context, userName string) (*model.UserBasics, error) {
+	user, err := s.userRepo.FindByName(ctx, userName)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, v1.ErrUnauthorized
+	}
+	return user, nil
+}
+
+func (s *twoFactorService) findEnabled(ctx context.Context, userName string) (*model.UserBasics, *model.TwoFactor, error) {
+	user, err := s.findUser(ctx, userName)
+	if err != nil {
+		return nil, nil, err
+	}
+	tf, err := s.twoFactorRepo.FindByUserId(ctx, user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tf == nil || tf.EnabledAt == nil {
+		return nil, nil, v1.ErrTwoFactorNotEnabled
+	}
+	return user, tf, nil
+}
+
+func (s *twoFactorService) audit(ctx context.Context, user *model.UserBasics, action string, metadata map[string]interface{}) error {
+	return s.auditService.Record(ctx, &model.AuditLog{
+		ActorId:    user.ID,
+		ActorName:  user.Name,
+		TargetType: model.AuditTargetUser,
+		TargetId:   auditUserTarget(user.ID),
+		Action:     action,
+	}, metadata)
+}
+
+// generateRecoveryCodes 返回展示给用户的恢复码（XXXXX-XXXXX）和入库的哈希
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := base32.StdEncoding.EncodeToString(b)[:10]
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode 忽略大小写、空格和分隔符
+func hashRecoveryCode(code string) string {
+	code = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
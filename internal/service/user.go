@@ -3,22 +3,53 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	v1 "go-chat/api/v1"
 	"go-chat/global"
 	"go-chat/internal/model"
 	"go-chat/internal/repository"
-	"go-chat/pkg/common"
+	"go-chat/pkg/clientinfo"
+	"go-chat/pkg/password"
+	"go-chat/pkg/rsakey"
+	"go-chat/pkg/sms"
+	"go.uber.org/zap"
+	"strings"
 	"time"
 )
 
 type UserService interface {
 	Register(ctx context.Context, req *v1.RegisterRequest) error
+	// VerifyRegisterEmailCode 验证码验证后作废，注册时直接调用 CreateNewUser
 	VerifyRegisterEmailCode(ctx context.Context, email string, code string) error
 	CreateNewUser(ctx context.Context, req *v1.CheckRegisterEmailCodeRequest) (*v1.RegisterResponse, error)
-	UpdateUserInfo(ctx context.Context, token string, userId int, req *v1.UpdateUserInfoRequest) error
-	Login(ctx context.Context, req *v1.LoginRequest) (string, *model.UserBasics, error)
-	EmailLoginCodeCheck(ctx context.Context, email string, code string) (string, *model.UserBasics, error)
+	UpdateUserInfo(ctx context.Context, name string, userId uint, req *v1.UpdateUserInfoRequest) error
+	// Login 开启了二次验证的用户只返回 challenge token，由 VerifyTwoFactor 完成登录
+	Login(ctx context.Context, req *v1.LoginRequest) (*v1.LoginResponseData, error)
+	EmailLoginCodeCheck(ctx context.Context, email string, code string) (*v1.LoginResponseData, error)
+	VerifyTwoFactor(ctx context.Context, req *v1.TwoFactorVerifyRequest) (*v1.LoginResponseData, error)
+	// LoginUser 由外部身份提供方（OIDC）验证过的用户登录，同样检查禁用、封禁和二次验证
+	LoginUser(ctx context.Context, user *model.UserBasics, method string) (*v1.LoginResponseData, error)
 	SendEmail(ctx context.Context, email string) error
+	// SendSmsLoginCode 只向验证过的手机号发送登录验证码，号码未绑定时同样返回成功
+	SendSmsLoginCode(ctx context.Context, phone string) error
+	SmsLoginCodeCheck(ctx context.Context, phone string, code string) (*v1.LoginResponseData, error)
+	// SendPhoneCode 向要绑定的新手机号发送验证码
+	SendPhoneCode(ctx context.Context, userName string, phone string) error
+	// VerifyPhone 校验验证码后绑定手机号
+	VerifyPhone(ctx context.Context, userName string, req *v1.VerifyPhoneRequest) error
+	// SendEmailCode 向要更换的新邮箱发送验证码
+	SendEmailCode(ctx context.Context, userName string, email string) error
+	// VerifyEmail 校验验证码后更换邮箱，邮箱同时标记为已验证
+	VerifyEmail(ctx context.Context, userName string, req *v1.VerifyEmailRequest) error
+	// ForgotPassword 向已注册的邮箱发送找回密码验证码，无论邮箱是否注册都返回成功
+	ForgotPassword(ctx context.Context, email string) error
+	// ResetPassword 校验验证码后设置新密码，并使所有设备下线
+	ResetPassword(ctx context.Context, req *v1.ResetPasswordRequest) error
+
+	// SearchUsers 按完整邮箱、手机号或用户名前缀搜索其他用户，遵守对方的可搜索设置
+	SearchUsers(ctx context.Context, userName string, req *v1.SearchUsersRequest) (*v1.SearchUsersResponseData, error)
+	Discoverability(ctx context.Context, userName string) (*v1.DiscoverabilityData, error)
+	UpdateDiscoverability(ctx context.Context, userName string, req *v1.UpdateDiscoverabilityRequest) (*v1.DiscoverabilityData, error)
 
 	GetProfile(ctx context.Context, userId string) (*v1.GetProfileResponseData, error)
 	UpdateProfile(ctx context.Context, userId string, req *v1.UpdateProfileRequest) error
@@ -27,18 +58,42 @@ type UserService interface {
 func NewUserService(
 	service *Service,
 	emailService EmailService,
+	smsService SmsService,
+	moderationService ModerationService,
+	reportService ReportService,
+	auditService AuditService,
+	tokenService TokenService,
+	twoFactorService TwoFactorService,
+	hasher *password.Manager,
+	rsaKeys rsakey.Provider,
 	userRepo repository.UserRepository,
 ) UserService {
 	return &userService{
-		userRepo:     userRepo,
-		emailService: emailService,
-		Service:      service,
+		twoFactorService:  twoFactorService,
+		hasher:            hasher,
+		rsaKeys:           rsaKeys,
+		userRepo:          userRepo,
+		emailService:      emailService,
+		smsService:        smsService,
+		moderationService: moderationService,
+		reportService:     reportService,
+		auditService:      auditService,
+		tokenService:      tokenService,
+		Service:           service,
 	}
 }
 
 type userService struct {
-	userRepo     repository.UserRepository
-	emailService EmailService
+	userRepo          repository.UserRepository
+	emailService      EmailService
+	smsService        SmsService
+	moderationService ModerationService
+	reportService     ReportService
+	auditService      AuditService
+	tokenService      TokenService
+	twoFactorService  TwoFactorService
+	hasher            *password.Manager
+	rsaKeys           rsakey.Provider
 	*Service
 }
 
@@ -50,11 +105,11 @@ func (s *userService) Register(ctx context.Context, req *v1.RegisterRequest) err
 	encryptionPassword := req.Password
 	reEncryptionPassword := req.RePassword
 	// rsa解密
-	password, err := common.RsaDecoder(encryptionPassword)
+	password, err := s.decrypt(ctx, encryptionPassword)
 	if err != nil {
 		return err
 	}
-	rePassword, err := common.RsaDecoder(reEncryptionPassword)
+	rePassword, err := s.decrypt(ctx, reEncryptionPassword)
 	if err != nil {
 		return err
 	}
@@ -89,28 +144,38 @@ func (s *userService) VerifyRegisterEmailCode(ctx context.Context, email string,
 }
 
 func (s *userService) CreateNewUser(ctx context.Context, req *v1.CheckRegisterEmailCodeRequest) (*v1.RegisterResponse, error) {
-	// 验证邮箱验证码
-	err := s.emailService.CheckEmailCode(ctx, req.Email, req.Code, global.Register)
+	// 用户名审核，用户创建后再记录待复核的内容
+	review, err := s.moderationService.Review(ctx, ModerationFieldName, req.Name)
 	if err != nil {
 		return nil, err
 	}
+
 	// 创建用户
 	user := &model.UserBasics{}
 	user.Email = req.Email
-	user.Name = req.Name
+	user.Name = review.Text
+
+	originPassword, err := s.decrypt(ctx, req.Password)
+	if err != nil {
+		return nil, err
+	}
+	// 加密密码存储，盐随机生成并写在哈希串中
+	hash, err := s.hasher.Hash(originPassword)
+	if err != nil {
+		return nil, err
+	}
+	user.PassWord = hash
 
-	originPassword, err := common.RsaDecoder(req.Password)
+	// 验证邮箱验证码，验证码使用后作废，放在其他参数校验之后
+	err = s.emailService.CheckEmailCode(ctx, req.Email, req.Code, global.Register)
 	if err != nil {
 		return nil, err
 	}
-	// 加密密码存储
-	password := common.SaltPassWord(originPassword, "lxl")
-	user.PassWord = password
-	user.Salt = "lxl"
 	t := time.Now()
 	user.LoginTime = &t
 	user.HeartBeatTime = &t
 	user.LoginOutTime = &t
+	user.EmailVerifiedAt = &t
 
 	// 创建用户
 	err = s.userRepo.Create(ctx, user)
@@ -123,114 +188,206 @@ func (s *userService) CreateNewUser(ctx context.Context, req *v1.CheckRegisterEm
 	if err != nil {
 		return nil, err
 	}
+	s.moderationService.RecordFlag(ctx, user.ID, ModerationFieldName, review)
 
-	// jwt校验
-	token, err := s.jwt.GenToken(user.Name, time.Now().Add(time.Hour*24*90))
+	// 签发 token
+	pair, err := s.tokenService.IssueTokens(ctx, user)
 	if err != nil {
 		return nil, err
 	}
 
 	// 返回数据
 	return &v1.RegisterResponse{
-		Token: token,
-		User:  user,
+		Token:        pair.AccessToken,
+		RefreshToken: pair.RefreshToken,
+		ExpiresIn:    pair.ExpiresIn,
+		User:         userProfile(user),
 	}, nil
 }
 
-func (s *userService) UpdateUserInfo(ctx context.Context, token string, userId int, req *v1.UpdateUserInfoRequest) error {
+// UpdateUserInfo name 为 token 中的当前登录用户，userId 不为 0 时必须与之一致
+func (s *userService) UpdateUserInfo(ctx context.Context, name string, userId uint, req *v1.UpdateUserInfoRequest) error {
 	user := &model.UserBasics{}
 
 	// 字段校验
-	if req.UserName == "" || req.Email == "" || req.Phone == "" || req.Avatar == "" {
+	if req.UserName == "" || req.Avatar == "" {
 		return v1.ErrBadRequest
 	}
-	user.ID = uint(userId)
-	userInfo, _ := s.userRepo.FindUserInfoById(ctx, uint(userId))
+	userInfo, err := s.userRepo.FindUserInfoByName(ctx, name)
+	if err != nil {
+		return v1.ErrUnauthorized
+	}
+	if userId != 0 && userId != userInfo.ID {
+		return v1.ErrForbidden
+	}
+	user.ID = userInfo.ID
+	// 邮箱必须通过验证码修改，否则可以把他人的邮箱写到自己名下
+	if req.Email != "" && req.Email != userInfo.Email {
+		return v1.ErrEmailChangeNeedsCode
+	}
+	// 禁言期间不能修改资料
+	if err := s.reportService.CheckRestriction(ctx, user.ID, model.ModerationActionMute); err != nil {
+		return err
+	}
 
 	if userInfo.Avatar != req.Avatar {
 		user.Avatar = req.Avatar
 	}
 
-	if userInfo.Email != req.Email {
-		user.Email = req.Email
-	}
-
 	if userInfo.Motto != req.Motto {
-		user.Motto = req.Motto
-	}
-
-	if userInfo.Phone != req.Phone {
-		user.Phone = req.Phone
+		motto, err := s.moderationService.Moderate(ctx, user.ID, ModerationFieldMotto, req.Motto)
+		if err != nil {
+			return err
+		}
+		user.Motto = motto
 	}
 
 	if userInfo.Name != req.UserName {
+		// 用户名审核
+		name, err := s.moderationService.Moderate(ctx, user.ID, ModerationFieldName, req.UserName)
+		if err != nil {
+			return err
+		}
 		// 检查用户是否已经注册
-		_, err := s.userRepo.FindUserByNameWithRegister(ctx, req.UserName)
+		_, err = s.userRepo.FindUserByNameWithRegister(ctx, name)
 		if err != nil {
 			return v1.ErrUserNameAlreadyUse
 		}
-		user.Name = req.UserName
+		user.Name = name
 	}
 
-	err := s.userRepo.UpdateUserInfo(ctx, user)
-	if err != nil {
+	if err = s.userRepo.UpdateUserInfo(ctx, user); err != nil {
 		return v1.ErrUserInfoUpdateFailed
 	}
+	if user.Name != "" {
+		// token 中携带的是旧用户名，之后可能被别人注册，改名后旧 token 必须失效，客户端刷新后拿到新用户名
+		if err = s.tokenService.RevokeAccessTokens(ctx, userInfo.ID); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (s *userService) Login(ctx context.Context, req *v1.LoginRequest) (string, *model.UserBasics, error) {
+func (s *userService) Login(ctx context.Context, req *v1.LoginRequest) (*v1.LoginResponseData, error) {
 	// 参数校验
 	if req.Name == "" || req.Password == "" {
-		return "", nil, v1.ErrBadRequest
+		return nil, v1.ErrBadRequest
 	}
 	// 获取用户信息
 	user, err := s.userRepo.FindUserInfoByName(ctx, req.Name)
 	if err != nil {
-		return "", nil, v1.ErrUserNotFound
+		s.auditLoginFailed(ctx, nil, "password", "user_not_found", map[string]interface{}{"name": req.Name})
+		return nil, v1.ErrUserNotFound
 	}
 
 	// 校验密码
-	loginPassword, err := common.RsaDecoder(req.Password)
+	loginPassword, err := s.decrypt(ctx, req.Password)
 	if err != nil {
-		return "", nil, err
+		return nil, err
+	}
+	ok, rehash, err := s.hasher.Verify(loginPassword, user.PassWord)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("verify password error", zap.Uint("user_id", user.ID), zap.Error(err))
+		return nil, v1.ErrInternalServerError
 	}
-	if !common.CheckPassWord(loginPassword, user.Salt, user.PassWord) {
-		return "", nil, v1.ErrUserPasswordError
+	if !ok {
+		s.auditLoginFailed(ctx, user, "password", "password_error", nil)
+		return nil, v1.ErrUserPasswordError
+	}
+	if err = s.checkLoginAllowed(ctx, user, "password"); err != nil {
+		return nil, err
+	}
+	if rehash {
+		s.rehashPassword(ctx, user, loginPassword)
 	}
 
-	token, err := s.jwt.GenToken(user.Name, time.Now().Add(time.Hour*24*90))
+	data, err := s.loginOrChallenge(ctx, user, "password")
+	if err != nil {
+		return nil, v1.ErrInternalServerError
+	}
+	return data, nil
+}
 
+// decrypt 解密客户端用 /v1/security/public_key 加密的密码
+func (s *userService) decrypt(ctx context.Context, data string) (string, error) {
+	plain, err := rsakey.Decrypt(s.rsaKeys, data)
 	if err != nil {
-		return "", nil, v1.ErrInternalServerError
+		s.logger.WithContext(ctx).Warn("rsa decrypt error", zap.Error(err))
+		return "", v1.ErrDecryptFailed
 	}
+	return plain, nil
+}
 
-	return token, user, nil
+// rehashPassword 旧格式或参数过期的密码在登录成功后用当前算法重新哈希，失败不影响登录
+func (s *userService) rehashPassword(ctx context.Context, user *model.UserBasics, plain string) {
+	hash, err := s.hasher.Hash(plain)
+	if err == nil {
+		err = s.userRepo.UpdatePassword(ctx, user.ID, hash, "")
+	}
+	if err != nil {
+		s.logger.WithContext(ctx).Error("rehash password error", zap.Uint("user_id", user.ID), zap.Error(err))
+		return
+	}
+	user.PassWord = hash
+	user.Salt = ""
 }
 
-func (s *userService) EmailLoginCodeCheck(ctx context.Context, email string, code string) (string, *model.UserBasics, error) {
+func (s *userService) EmailLoginCodeCheck(ctx context.Context, email string, code string) (*v1.LoginResponseData, error) {
 
 	// 获取用户信息
 	user, err := s.userRepo.FindUserByEmailWithLogin(ctx, email)
 	if err != nil {
 		s.logger.WithContext(ctx).Error("Email not found")
-		return "", nil, err
+		if err == v1.ErrUserEmailNotFound {
+			s.auditLoginFailed(ctx, nil, "email", "user_not_found", map[string]interface{}{"email": email})
+		}
+		return nil, err
 	}
 
 	// 校验邮箱验证码
 	err = s.emailService.CheckEmailCode(ctx, email, code, global.Login)
 	if err != nil {
-		return "", nil, err
+		s.auditCodeFailed(ctx, user, "email", err)
+		return nil, err
+	}
+	s.markEmailVerified(ctx, user)
+	if err = s.checkLoginAllowed(ctx, user, "email"); err != nil {
+		return nil, err
 	}
 
 	// 生成token
-	token, err := s.jwt.GenToken(user.Name, time.Now().Add(time.Hour*24*90))
+	return s.loginOrChallenge(ctx, user, "email")
+
+}
+
+func (s *userService) VerifyTwoFactor(ctx context.Context, req *v1.TwoFactorVerifyRequest) (*v1.LoginResponseData, error) {
+	userId, method, err := s.twoFactorService.VerifyChallenge(ctx, req.ChallengeToken, req.Code)
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
+	user, err := s.userRepo.FindUserInfoById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, v1.ErrTwoFactorChallengeInvalid
+	}
+	// 两步之间账号可能被禁用或封禁
+	if err = s.checkLoginAllowed(ctx, user, method); err != nil {
+		return nil, err
+	}
+	pair, err := s.completeLogin(ctx, user, method)
+	if err != nil {
+		return nil, err
+	}
+	return loginResponseData(pair, user), nil
+}
 
-	return token, user, nil
-
+func (s *userService) LoginUser(ctx context.Context, user *model.UserBasics, method string) (*v1.LoginResponseData, error) {
+	if err := s.checkLoginAllowed(ctx, user, method); err != nil {
+		return nil, err
+	}
+	return s.loginOrChallenge(ctx, user, method)
 }
 
 func (s *userService) SendEmail(ctx context.Context, email string) error {
@@ -254,6 +411,367 @@ func (s *userService) SendEmail(ctx context.Context, email string) error {
 
 }
 
+func (s *userService) SendSmsLoginCode(ctx context.Context, phone string) error {
+	phone, err := normalizePhone(phone)
+	if err != nil {
+		return err
+	}
+	user, err := s.userRepo.FindByVerifiedPhone(ctx, phone)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return nil
+	}
+	return s.smsService.SendCode(ctx, phone, global.Login)
+}
+
+func (s *userService) SmsLoginCodeCheck(ctx context.Context, phone string, code string) (*v1.LoginResponseData, error) {
+	phone, err := normalizePhone(phone)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.FindByVerifiedPhone(ctx, phone)
+	if err != nil {
+		return nil, err
+	}
+	// 号码未绑定时不会收到验证码，与验证码错误返回相同的错误
+	if user == nil {
+		s.auditLoginFailed(ctx, nil, "sms", "user_not_found", map[string]interface{}{"phone": sms.Mask(phone)})
+		return nil, v1.ErrSmsCodeError
+	}
+	if err = s.smsService.CheckCode(ctx, phone, code, global.Login); err != nil {
+		s.auditCodeFailed(ctx, user, "sms", err)
+		return nil, err
+	}
+	if err = s.checkLoginAllowed(ctx, user, "sms"); err != nil {
+		return nil, err
+	}
+	return s.loginOrChallenge(ctx, user, "sms")
+}
+
+func (s *userService) SendPhoneCode(ctx context.Context, userName string, phone string) error {
+	phone, err := normalizePhone(phone)
+	if err != nil {
+		return err
+	}
+	user, err := s.userRepo.FindByName(ctx, userName)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return v1.ErrUserNotFound
+	}
+	if err = s.checkPhoneAvailable(ctx, user, phone); err != nil {
+		return err
+	}
+	return s.smsService.SendCode(ctx, phone, bindPhonePurpose(user.ID))
+}
+
+func (s *userService) VerifyPhone(ctx context.Context, userName string, req *v1.VerifyPhoneRequest) error {
+	phone, err := normalizePhone(req.Phone)
+	if err != nil {
+		return err
+	}
+	user, err := s.userRepo.FindByName(ctx, userName)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return v1.ErrUserNotFound
+	}
+	// 发送验证码之后号码可能已被其他账号绑定，先检查再使用验证码
+	if err = s.checkPhoneAvailable(ctx, user, phone); err != nil {
+		return err
+	}
+	if err = s.smsService.CheckCode(ctx, phone, req.Code, bindPhonePurpose(user.ID)); err != nil {
+		return err
+	}
+
+	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.UpdatePhone(ctx, user.ID, phone, time.Now()); err != nil {
+			return err
+		}
+		return s.auditService.Record(ctx, &model.AuditLog{
+			ActorId:    user.ID,
+			ActorName:  user.Name,
+			TargetType: model.AuditTargetUser,
+			TargetId:   auditUserTarget(user.ID),
+			Action:     model.AuditActionPhoneChange,
+		}, map[string]interface{}{"from": sms.Mask(user.Phone), "to": sms.Mask(phone)})
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkPhoneAvailable 号码已被其他账号验证过时不能绑定
+func (s *userService) checkPhoneAvailable(ctx context.Context, user *model.UserBasics, phone string) error {
+	owner, err := s.userRepo.FindByVerifiedPhone(ctx, phone)
+	if err != nil {
+		return err
+	}
+	if owner != nil && owner.ID != user.ID {
+		return v1.ErrPhoneAlreadyUse
+	}
+	return nil
+}
+
+// bindPhonePurpose 绑定验证码只能由发起的用户使用
+func bindPhonePurpose(userId uint) string {
+	return fmt.Sprintf("%s:%d", global.BindPhone, userId)
+}
+
+func normalizePhone(phone string) (string, error) {
+	normalized, err := sms.Normalize(phone)
+	if err != nil {
+		return "", v1.ErrInvalidPhone
+	}
+	return normalized, nil
+}
+
+func (s *userService) SendEmailCode(ctx context.Context, userName string, email string) error {
+	email = strings.TrimSpace(email)
+	user, err := s.userRepo.FindByName(ctx, userName)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return v1.ErrUserNotFound
+	}
+	if err = s.checkEmailAvailable(ctx, user, email); err != nil {
+		return err
+	}
+	return s.emailService.SendEmail(ctx, email, bindEmailPurpose(user.ID))
+}
+
+func (s *userService) VerifyEmail(ctx context.Context, userName string, req *v1.VerifyEmailRequest) error {
+	email := strings.TrimSpace(req.Email)
+	user, err := s.userRepo.FindByName(ctx, userName)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return v1.ErrUserNotFound
+	}
+	// 发送验证码之后邮箱可能已被其他账号验证，先检查再使用验证码
+	if err = s.checkEmailAvailable(ctx, user, email); err != nil {
+		return err
+	}
+	if err = s.emailService.CheckEmailCode(ctx, email, req.Code, bindEmailPurpose(user.ID)); err != nil {
+		return err
+	}
+
+	return s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.UpdateEmail(ctx, user.ID, email, time.Now()); err != nil {
+			return err
+		}
+		return s.auditService.Record(ctx, &model.AuditLog{
+			ActorId:    user.ID,
+			ActorName:  user.Name,
+			TargetType: model.AuditTargetUser,
+			TargetId:   auditUserTarget(user.ID),
+			Action:     model.AuditActionEmailChange,
+		}, map[string]interface{}{"from": user.Email, "to": email})
+	})
+}
+
+// checkEmailAvailable 邮箱已被其他账号验证过时不能使用
+func (s *userService) checkEmailAvailable(ctx context.Context, user *model.UserBasics, email string) error {
+	owner, err := s.userRepo.FindByVerifiedEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if owner != nil && owner.ID != user.ID {
+		return v1.ErrEmailAlreadyUse
+	}
+	return nil
+}
+
+// markEmailVerified 用户收到了发往当前邮箱的验证码，说明邮箱属于本人
+func (s *userService) markEmailVerified(ctx context.Context, user *model.UserBasics) {
+	if user.EmailVerifiedAt != nil {
+		return
+	}
+	now := time.Now()
+	if err := s.userRepo.UpdateEmail(ctx, user.ID, user.Email, now); err != nil {
+		s.logger.WithContext(ctx).Error("mark email verified error", zap.Uint("user_id", user.ID), zap.Error(err))
+		return
+	}
+	user.EmailVerifiedAt = &now
+}
+
+// bindEmailPurpose 更换邮箱的验证码只能由发起的用户使用
+func bindEmailPurpose(userId uint) string {
+	return fmt.Sprintf("%s:%d", global.BindEmail, userId)
+}
+
+func (s *userService) ForgotPassword(ctx context.Context, email string) error {
+	user, err := s.userRepo.FindUserByEmailWithLogin(ctx, email)
+	if err == v1.ErrUserEmailNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// 发送失败同样返回成功，避免通过错误区分邮箱是否注册
+	if err = s.emailService.SendEmail(ctx, user.Email, global.ResetPassword); err != nil {
+		s.logger.WithContext(ctx).Warn("send reset password email failed", zap.Uint("user_id", user.ID), zap.Error(err))
+	}
+	return nil
+}
+
+func (s *userService) ResetPassword(ctx context.Context, req *v1.ResetPasswordRequest) error {
+	newPassword, err := s.decrypt(ctx, req.Password)
+	if err != nil {
+		return err
+	}
+	rePassword, err := s.decrypt(ctx, req.RePassword)
+	if err != nil {
+		return err
+	}
+	if newPassword != rePassword {
+		return v1.ErrBadRequest
+	}
+
+	// 未注册的邮箱不会有验证码，与验证码错误返回相同的结果
+	if err = s.emailService.CheckEmailCode(ctx, req.Email, req.Code, global.ResetPassword); err != nil {
+		return err
+	}
+	user, err := s.userRepo.FindUserByEmailWithLogin(ctx, req.Email)
+	if err == v1.ErrUserEmailNotFound {
+		return v1.ErrEmailCodeError
+	}
+	if err != nil {
+		return err
+	}
+	s.markEmailVerified(ctx, user)
+
+	hash, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.UpdatePassword(ctx, user.ID, hash, ""); err != nil {
+			return err
+		}
+		if err := s.userRepo.SetLoginOut(ctx, user.ID, time.Now()); err != nil {
+			return err
+		}
+		return s.auditService.Record(ctx, &model.AuditLog{
+			ActorId:    user.ID,
+			ActorName:  user.Name,
+			TargetType: model.AuditTargetUser,
+			TargetId:   auditUserTarget(user.ID),
+			Action:     model.AuditActionPasswordChange,
+		}, map[string]interface{}{"method": "email_reset"})
+	})
+	if err != nil {
+		return err
+	}
+	if err = s.tokenService.RevokeUserTokens(ctx, user); err != nil {
+		return err
+	}
+	// 通知邮件发送失败不影响重置结果
+	err = s.emailService.SendNotification(ctx, user.Email, mailPasswordChanged, map[string]interface{}{
+		"Name": user.Name,
+		"Time": time.Now().Format("2006-01-02 15:04:05"),
+		"IP":   clientinfo.FromContext(ctx).IP,
+	})
+	if err != nil {
+		s.logger.WithContext(ctx).Warn("send password changed email failed", zap.Uint("user_id", user.ID), zap.Error(err))
+	}
+	return nil
+}
+
+func (s *userService) SearchUsers(ctx context.Context, userName string, req *v1.SearchUsersRequest) (*v1.SearchUsersResponseData, error) {
+	user, err := s.userRepo.FindByName(ctx, userName)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, v1.ErrUnauthorized
+	}
+	filter := &repository.DiscoverFilter{ExcludeId: user.ID, Now: time.Now()}
+	n := 0
+	if req.Email != "" {
+		filter.Email = strings.TrimSpace(req.Email)
+		n++
+	}
+	if req.Phone != "" {
+		if filter.Phone, err = normalizePhone(req.Phone); err != nil {
+			return nil, err
+		}
+		n++
+	}
+	if req.Name != "" {
+		filter.NamePrefix = req.Name
+		n++
+	}
+	if n != 1 {
+		return nil, v1.ErrBadRequest
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 || req.PageSize > 50 {
+		req.PageSize = 20
+	}
+
+	users, total, err := s.userRepo.Discover(ctx, filter, (req.Page-1)*req.PageSize, req.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*v1.PublicProfile, 0, len(users))
+	for _, u := range users {
+		list = append(list, publicProfile(u))
+	}
+	return &v1.SearchUsersResponseData{List: list, Total: total}, nil
+}
+
+func (s *userService) Discoverability(ctx context.Context, userName string) (*v1.DiscoverabilityData, error) {
+	user, err := s.userRepo.FindByName(ctx, userName)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, v1.ErrUnauthorized
+	}
+	return discoverabilityData(user), nil
+}
+
+func (s *userService) UpdateDiscoverability(ctx context.Context, userName string, req *v1.UpdateDiscoverabilityRequest) (*v1.DiscoverabilityData, error) {
+	user, err := s.userRepo.FindByName(ctx, userName)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, v1.ErrUnauthorized
+	}
+	if req.ByName != nil {
+		user.DiscoverableByName = *req.ByName
+	}
+	if req.ByEmail != nil {
+		user.DiscoverableByEmail = *req.ByEmail
+	}
+	if req.ByPhone != nil {
+		user.DiscoverableByPhone = *req.ByPhone
+	}
+	if err = s.userRepo.UpdateDiscoverability(ctx, user.ID, user.DiscoverableByName, user.DiscoverableByEmail, user.DiscoverableByPhone); err != nil {
+		return nil, err
+	}
+	return discoverabilityData(user), nil
+}
+
+func discoverabilityData(user *model.UserBasics) *v1.DiscoverabilityData {
+	return &v1.DiscoverabilityData{
+		ByName:  user.DiscoverableByName,
+		ByEmail: user.DiscoverableByEmail,
+		ByPhone: user.DiscoverableByPhone,
+	}
+}
+
 func (s *userService) GetProfile(ctx context.Context, userId string) (*v1.GetProfileResponseData, error) {
 	user, err := s.userRepo.GetByID(ctx, userId)
 	if err != nil {
@@ -272,7 +790,6 @@ func (s *userService) UpdateProfile(ctx context.Context, userId string, req *v1.
 		return err
 	}
 
-	user.Email = req.Email
 	user.Nickname = req.Nickname
 
 	if err = s.userRepo.Update(ctx, user); err != nil {
@@ -281,3 +798,130 @@ func (s *userService) UpdateProfile(ctx context.Context, userId string, req *v1.
 
 	return nil
 }
+
+// checkLoginAllowed 禁用、封禁检查
+func (s *userService) checkLoginAllowed(ctx context.Context, user *model.UserBasics, method string) error {
+	if user.DisabledAt != nil {
+		s.auditLoginFailed(ctx, user, method, "disabled", nil)
+		return v1.ErrUserDisabled
+	}
+	if err := s.reportService.CheckRestriction(ctx, user.ID, model.ModerationActionBan); err != nil {
+		if err == v1.ErrUserBanned {
+			s.auditLoginFailed(ctx, user, method, "banned", nil)
+		}
+		return err
+	}
+	return nil
+}
+
+// loginOrChallenge 第一步验证通过后，开启了二次验证的用户返回 challenge token，否则直接完成登录
+func (s *userService) loginOrChallenge(ctx context.Context, user *model.UserBasics, method string) (*v1.LoginResponseData, error) {
+	enabled, err := s.twoFactorService.Enabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		token, err := s.twoFactorService.CreateChallenge(ctx, user, method)
+		if err != nil {
+			return nil, err
+		}
+		return &v1.LoginResponseData{TwoFactorRequired: true, ChallengeToken: token}, nil
+	}
+
+	pair, err := s.completeLogin(ctx, user, method)
+	if err != nil {
+		return nil, err
+	}
+	return loginResponseData(pair, user), nil
+}
+
+func loginResponseData(pair *v1.TokenPair, user *model.UserBasics) *v1.LoginResponseData {
+	return &v1.LoginResponseData{
+		Token:        pair.AccessToken,
+		RefreshToken: pair.RefreshToken,
+		ExpiresIn:    pair.ExpiresIn,
+		UserInfo:     userProfile(user),
+	}
+}
+
+func userProfile(user *model.UserBasics) *v1.UserProfile {
+	return &v1.UserProfile{
+		Id:            user.ID,
+		Name:          user.Name,
+		Avatar:        user.Avatar,
+		Gender:        user.Gender,
+		Phone:         user.Phone,
+		PhoneVerified: user.PhoneVerifiedAt != nil,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		Motto:         user.Motto,
+		Identity:      user.Identity,
+		CreateAt:      user.CreateAt,
+	}
+}
+
+func publicProfile(user *model.UserBasics) *v1.PublicProfile {
+	return &v1.PublicProfile{
+		Id:     user.ID,
+		Name:   user.Name,
+		Avatar: user.Avatar,
+		Gender: user.Gender,
+		Motto:  user.Motto,
+	}
+}
+
+// completeLogin 更新登录信息、写入审计日志并签发 token
+func (s *userService) completeLogin(ctx context.Context, user *model.UserBasics, method string) (*v1.TokenPair, error) {
+	info := clientinfo.FromContext(ctx)
+	var pair *v1.TokenPair
+	err := s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.UpdateLoginInfo(ctx, user.ID, time.Now(), info.IP, info.DeviceInfo); err != nil {
+			return err
+		}
+		if err := s.auditService.Record(ctx, &model.AuditLog{
+			ActorId:    user.ID,
+			ActorName:  user.Name,
+			TargetType: model.AuditTargetUser,
+			TargetId:   auditUserTarget(user.ID),
+			Action:     model.AuditActionLogin,
+		}, map[string]interface{}{"method": method}); err != nil {
+			return err
+		}
+		var err error
+		pair, err = s.tokenService.IssueTokens(ctx, user)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// auditCodeFailed 记录验证码登录失败，连续输错导致锁定时单独标记
+func (s *userService) auditCodeFailed(ctx context.Context, user *model.UserBasics, method string, err error) {
+	switch err {
+	case v1.ErrEmailCodeError, v1.ErrSmsCodeError:
+		s.auditLoginFailed(ctx, user, method, "code_error", nil)
+	case v1.ErrVerifyCodeLocked:
+		s.auditLoginFailed(ctx, user, method, "code_locked", nil)
+	}
+}
+
+// auditLoginFailed 登录失败没有对应的数据修改，单独写入，失败只记录日志。user 为空表示用户不存在
+func (s *userService) auditLoginFailed(ctx context.Context, user *model.UserBasics, method string, reason string, metadata map[string]interface{}) {
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	metadata["method"] = method
+	metadata["reason"] = reason
+	entry := &model.AuditLog{
+		TargetType: model.AuditTargetUser,
+		Action:     model.AuditActionLoginFailed,
+	}
+	if user != nil {
+		entry.TargetId = auditUserTarget(user.ID)
+	}
+	if err := s.auditService.Record(ctx, entry, metadata); err != nil {
+		s.logger.WithContext(ctx).Error("audit login failed error", zap.Error(err))
+	}
+}
@@ -0,0 +1,53 @@
+package clientinfo
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeviceInfoHeader 客户端上报设备描述的请求头，gRPC 中为同名 metadata
+const DeviceInfoHeader = "x-device-info"
+
+// LocaleHeader 客户端首选语言，gRPC 中为同名 metadata
+const LocaleHeader = "accept-language"
+
+const ctxInfoKey = "clientInfo"
+
+// Info 发起请求的客户端信息，用于审计日志和登录设备记录
+type Info struct {
+	IP         string
+	UserAgent  string
+	DeviceInfo string
+	// Locale 客户端首选语言，如 zh-CN、en，用于选择邮件模板
+	Locale string
+}
+
+// NewContext returns a copy of ctx carrying info; for *gin.Context the info is stored in its keys
+func NewContext(ctx context.Context, info *Info) context.Context {
+	if c, ok := ctx.(*gin.Context); ok {
+		c.Set(ctxInfoKey, info)
+		return c
+	}
+	return context.WithValue(ctx, ctxInfoKey, info)
+}
+
+// FromContext returns the info stored by NewContext, or an empty Info
+func FromContext(ctx context.Context) *Info {
+	if info, ok := ctx.Value(ctxInfoKey).(*Info); ok {
+		return info
+	}
+	return &Info{}
+}
+
+// ParseLocale 取 Accept-Language 中的第一个语言标签，忽略权重
+func ParseLocale(acceptLanguage string) string {
+	tag := strings.SplitN(acceptLanguage, ",", 2)[0]
+	tag = strings.SplitN(tag, ";", 2)[0]
+	tag = strings.TrimSpace(tag)
+	if tag == "*" {
+		return ""
+	}
+	return tag
+}
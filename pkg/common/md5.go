@@ -21,12 +21,17 @@ func Md5StrToUpper(code string) string {
 }
 
 // The SaltPassWord function adds salt to the password.
+//
+// Deprecated: 所有用户共用常量盐的 MD5 不能用于存储密码，使用 pkg/password。
+// 仅保留用于兼容旧数据，登录时旧哈希会被迁移到新算法。
 func SaltPassWord(pw string, salt string) string {
 	saltPW := fmt.Sprintf("%s$%s", Md5encoder(pw), salt)
 	return saltPW
 }
 
 // The CheckPassWord function verifies the password
+//
+// Deprecated: 使用 password.Manager.Verify。
 func CheckPassWord(rpw, salt, pw string) bool {
 	return pw == SaltPassWord(rpw, salt)
 }
@@ -0,0 +1,213 @@
+package event
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// subscriberBuffer 每个订阅者的缓冲区大小，消费过慢时丢弃新事件
+	subscriberBuffer = 64
+	// backlogSize 每个用户保留的最近事件数，用于断线重连后按 Last-Event-ID 补发
+	backlogSize = 100
+	// backlogTTL 补发窗口，超过该时间的事件不再保留
+	backlogTTL = 5 * time.Minute
+	// PruneInterval 建议的 Prune 调用间隔，清理不再订阅的用户留下的历史事件
+	PruneInterval = time.Minute
+)
+
+type Event struct {
+	// ID 在 Hub 内单调递增
+	ID        string
+	Type      string
+	Payload   []byte
+	CreatedAt time.Time
+}
+
+// Hub 进程内按用户分发实时事件，gRPC/SSE/长轮询等长连接通过 Subscribe 接收。
+// 按数字用户ID（token 的 sub）区分用户，用户名会随改名变化，不能作为订阅的键
+type Hub struct {
+	mu      sync.Mutex
+	seq     uint64
+	streams map[uint]*userStream
+}
+
+type userStream struct {
+	backlog []*Event
+	subs    map[*Subscription]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		streams: make(map[uint]*userStream),
+	}
+}
+
+type Subscription struct {
+	C         <-chan *Event
+	c         chan *Event
+	userId    uint
+	sessionId string
+	hub       *Hub
+	once      sync.Once
+}
+
+// Subscribe 订阅用户的事件，使用完毕必须调用 Close
+func (h *Hub) Subscribe(userId uint) *Subscription {
+	sub, _ := h.SubscribeSince(userId, "")
+	return sub
+}
+
+// SubscribeSince 订阅用户的事件，并返回 lastId 之后仍在补发窗口内的历史事件。
+// lastId 为空时不返回历史事件。订阅和读取历史在同一把锁内完成，不会漏发或重复。
+func (h *Hub) SubscribeSince(userId uint, lastId string) (*Subscription, []*Event) {
+	return h.SubscribeSession(userId, "", lastId)
+}
+
+// SubscribeSession 同 SubscribeSince，订阅归属于 sessionId，会话被吊销时可通过 CloseSession 断开
+func (h *Hub) SubscribeSession(userId uint, sessionId string, lastId string) (*Subscription, []*Event) {
+	c := make(chan *Event, subscriberBuffer)
+	sub := &Subscription{C: c, c: c, userId: userId, sessionId: sessionId, hub: h}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.stream(userId)
+	s.subs[sub] = struct{}{}
+	return sub, s.since(lastId)
+}
+
+// Since 返回 lastId 之后仍在补发窗口内的历史事件
+func (h *Hub) Since(userId uint, lastId string) []*Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.streams[userId]
+	if !ok {
+		return nil
+	}
+	return s.since(lastId)
+}
+
+// Head 返回最近一次发布的事件ID，客户端没有 Last-Event-ID 时以此作为起点，之后的事件不会漏掉
+func (h *Hub) Head() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return strconv.FormatUint(h.seq, 10)
+}
+
+// Prune 清理所有用户超出补发窗口的历史事件，并删除没有订阅者也没有历史事件的用户。
+// Publish 和 Close 只清理当前用户，从未订阅的用户需要定时调用本方法回收。
+func (h *Hub) Prune(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for userId, s := range h.streams {
+		s.prune(now)
+		if len(s.subs) == 0 && len(s.backlog) == 0 {
+			delete(h.streams, userId)
+		}
+	}
+}
+
+// Publish 向用户的所有连接推送事件，ID 和 CreatedAt 由 Hub 填充
+func (h *Hub) Publish(userId uint, e *Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.stream(userId)
+
+	h.seq++
+	e.ID = strconv.FormatUint(h.seq, 10)
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	s.backlog = append(s.backlog, e)
+	s.prune(e.CreatedAt)
+
+	for sub := range s.subs {
+		select {
+		case sub.c <- e:
+		default:
+			// 订阅者消费过慢，丢弃该事件，避免阻塞其他连接；客户端可凭 Last-Event-ID 补发
+		}
+	}
+}
+
+// CloseSession 断开会话的所有连接，已缓冲的事件仍可从 C 读出
+func (h *Hub) CloseSession(userId uint, sessionId string) {
+	for _, sub := range h.subscriptions(userId, sessionId) {
+		sub.Close()
+	}
+}
+
+// CloseUser 断开用户的所有连接
+func (h *Hub) CloseUser(userId uint) {
+	for _, sub := range h.subscriptions(userId, "") {
+		sub.Close()
+	}
+}
+
+// subscriptions sessionId 为空时返回用户的全部订阅
+func (h *Hub) subscriptions(userId uint, sessionId string) []*Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.streams[userId]
+	if !ok {
+		return nil
+	}
+	var subs []*Subscription
+	for sub := range s.subs {
+		if sessionId == "" || sub.sessionId == sessionId {
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+// Close 取消订阅并关闭 C
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.hub.mu.Lock()
+		defer s.hub.mu.Unlock()
+		if us, ok := s.hub.streams[s.userId]; ok {
+			delete(us.subs, s)
+			us.prune(time.Now())
+			if len(us.subs) == 0 && len(us.backlog) == 0 {
+				delete(s.hub.streams, s.userId)
+			}
+		}
+		close(s.c)
+	})
+}
+
+func (h *Hub) stream(userId uint) *userStream {
+	s, ok := h.streams[userId]
+	if !ok {
+		s = &userStream{subs: make(map[*Subscription]struct{})}
+		h.streams[userId] = s
+	}
+	return s
+}
+
+func (s *userStream) since(lastId string) []*Event {
+	if lastId == "" {
+		return nil
+	}
+	last, err := strconv.ParseUint(lastId, 10, 64)
+	if err != nil {
+		return nil
+	}
+	var events []*Event
+	for _, e := range s.backlog {
+		if id, _ := strconv.ParseUint(e.ID, 10, 64); id > last {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+func (s *userStream) prune(now time.Time) {
+	i := 0
+	for i < len(s.backlog) && (len(s.backlog)-i > backlogSize || now.Sub(s.backlog[i].CreatedAt) > backlogTTL) {
+		i++
+	}
+	s.backlog = s.backlog[i:]
+}
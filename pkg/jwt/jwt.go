@@ -1,7 +1,9 @@
 package jwt
 
 import (
+	"context"
 	"errors"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,8 +15,11 @@ type JWT struct {
 	key []byte
 }
 
+// MyCustomClaims UserId 为用户名，会随改名变化；不变的数字用户ID放在 sub 中，通过 Uid 读取
 type MyCustomClaims struct {
 	UserId string
+	// SessionId 登录会话（设备）ID，同一会话内刷新出来的 token 共用
+	SessionId string
 	jwt.RegisteredClaims
 }
 
@@ -22,16 +27,18 @@ func NewJwt(conf *viper.Viper) *JWT {
 	return &JWT{key: []byte(conf.GetString("security.jwt.key"))}
 }
 
-func (j *JWT) GenToken(userId string, expiresAt time.Time) (string, error) {
+// GenToken uid 写入 sub，用于按用户吊销；tokenId 写入 jti，用于吊销单个 token；sessionId 用于吊销整个设备会话
+func (j *JWT) GenToken(userId string, uid uint, sessionId string, tokenId string, expiresAt time.Time) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, MyCustomClaims{
-		UserId: userId,
+		UserId:    userId,
+		SessionId: sessionId,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    "",
-			Subject:   "",
-			ID:        "",
+			Subject:   strconv.FormatUint(uint64(uid), 10),
+			ID:        tokenId,
 			Audience:  []string{},
 		},
 	})
@@ -61,3 +68,25 @@ func (j *JWT) ParseToken(tokenString string) (*MyCustomClaims, error) {
 		return nil, err
 	}
 }
+
+// Uid 返回 sub 中的数字用户ID，没有 sub 的旧 token 返回 0
+func (c *MyCustomClaims) Uid() uint {
+	uid, err := strconv.ParseUint(c.Subject, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return uint(uid)
+}
+
+type claimsCtxKey struct{}
+
+// NewContext 将解析后的 claims 放入 ctx，用于没有 gin.Context 的场景（如 gRPC）
+func NewContext(ctx context.Context, claims *MyCustomClaims) context.Context {
+	return context.WithValue(ctx, claimsCtxKey{}, claims)
+}
+
+// FromContext 读取 NewContext 写入的 claims
+func FromContext(ctx context.Context) (*MyCustomClaims, bool) {
+	claims, ok := ctx.Value(claimsCtxKey{}).(*MyCustomClaims)
+	return claims, ok
+}
@@ -11,7 +11,10 @@ import (
 	"time"
 )
 
-const ctxLoggerKey = "zapLogger"
+const (
+	ctxLoggerKey = "zapLogger"
+	ctxTraceKey  = "trace"
+)
 
 type Logger struct {
 	*zap.Logger
@@ -100,6 +103,22 @@ func (l *Logger) WithValue(ctx context.Context, fields ...zapcore.Field) context
 	return context.WithValue(ctx, ctxLoggerKey, l.WithContext(ctx).With(fields...))
 }
 
+// WithTrace Adds the trace field and keeps the trace id in ctx for TraceFromContext
+func (l *Logger) WithTrace(ctx context.Context, trace string) context.Context {
+	if c, ok := ctx.(*gin.Context); ok {
+		c.Set(ctxTraceKey, trace)
+	} else {
+		ctx = context.WithValue(ctx, ctxTraceKey, trace)
+	}
+	return l.WithValue(ctx, zap.String("trace", trace))
+}
+
+// TraceFromContext Returns the trace id set by WithTrace
+func TraceFromContext(ctx context.Context) string {
+	trace, _ := ctx.Value(ctxTraceKey).(string)
+	return trace
+}
+
 // WithContext Returns a zap instance from the specified context
 func (l *Logger) WithContext(ctx context.Context) *Logger {
 	if c, ok := ctx.(*gin.Context); ok {
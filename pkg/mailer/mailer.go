@@ -0,0 +1,109 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"os"
+	"time"
+
+	"github.com/jordan-wright/email"
+	"github.com/spf13/viper"
+)
+
+// 内置的发送方式
+const (
+	TransportSMTP   = "smtp"
+	TransportOutbox = "outbox"
+)
+
+var ErrNoRecipient = errors.New("mailer: no recipient")
+
+// Transport 投递已编码好的 RFC 5322 邮件
+type Transport interface {
+	Send(ctx context.Context, from string, to []string, raw []byte) error
+}
+
+type Mailer struct {
+	transport Transport
+	templates *Templates
+	from      string
+}
+
+func New(transport Transport, templates *Templates, from string) *Mailer {
+	return &Mailer{
+		transport: transport,
+		templates: templates,
+		from:      from,
+	}
+}
+
+// NewMailer 读取 mail 配置，templates_dir 为空时使用内置模板
+func NewMailer(conf *viper.Viper) (*Mailer, error) {
+	from := conf.GetString("mail.from")
+	if _, err := mail.ParseAddress(from); err != nil {
+		return nil, fmt.Errorf("mailer: invalid mail.from %q: %w", from, err)
+	}
+
+	var templates *Templates
+	if dir := conf.GetString("mail.templates_dir"); dir != "" {
+		templates = NewTemplates(os.DirFS(dir), conf.GetString("mail.default_locale"))
+	} else {
+		templates = NewDefaultTemplates(conf.GetString("mail.default_locale"))
+	}
+
+	var transport Transport
+	switch name := conf.GetString("mail.transport"); name {
+	case TransportSMTP:
+		password := conf.GetString("mail.smtp.password")
+		if env := conf.GetString("mail.smtp.password_env"); env != "" {
+			password = os.Getenv(env)
+		}
+		t, err := NewSMTPTransport(SMTPConfig{
+			Host:               conf.GetString("mail.smtp.host"),
+			Port:               conf.GetInt("mail.smtp.port"),
+			Username:           conf.GetString("mail.smtp.username"),
+			Password:           password,
+			TLS:                conf.GetString("mail.smtp.tls"),
+			InsecureSkipVerify: conf.GetBool("mail.smtp.insecure_skip_verify"),
+			Timeout:            conf.GetDuration("mail.smtp.timeout"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		transport = t
+	case TransportOutbox:
+		t, err := NewOutboxTransport(conf.GetString("mail.outbox.dir"))
+		if err != nil {
+			return nil, err
+		}
+		transport = t
+	default:
+		return nil, fmt.Errorf("mailer: unknown transport %q", name)
+	}
+	return New(transport, templates, from), nil
+}
+
+// Send 用 name 对应的模板渲染邮件并发送，locale 没有对应模板时使用默认语言
+func (m *Mailer) Send(ctx context.Context, to string, name string, locale string, data interface{}) error {
+	if to == "" {
+		return ErrNoRecipient
+	}
+	rendered, err := m.templates.Render(name, locale, data)
+	if err != nil {
+		return err
+	}
+	e := email.NewEmail()
+	e.From = m.from
+	e.To = []string{to}
+	e.Subject = rendered.Subject
+	e.Text = []byte(rendered.Text)
+	e.HTML = []byte(rendered.HTML)
+	e.Headers.Set("Date", time.Now().Format(time.RFC1123Z))
+	raw, err := e.Bytes()
+	if err != nil {
+		return err
+	}
+	return m.transport.Send(ctx, m.from, e.To, raw)
+}
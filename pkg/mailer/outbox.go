@@ -0,0 +1,35 @@
+package mailer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// OutboxTransport 把邮件写成 .eml 文件，用于本地开发和测试，可以直接用邮件客户端打开
+type OutboxTransport struct {
+	dir string
+}
+
+func NewOutboxTransport(dir string) (*OutboxTransport, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("mailer: outbox transport requires mail.outbox.dir")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &OutboxTransport{dir: dir}, nil
+}
+
+func (t *OutboxTransport) Send(ctx context.Context, from string, to []string, raw []byte) error {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102T150405.000000000"), hex.EncodeToString(b))
+	return os.WriteFile(filepath.Join(t.dir, name), raw, 0o600)
+}
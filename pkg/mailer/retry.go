@@ -0,0 +1,37 @@
+package mailer
+
+import (
+	"errors"
+	"net/textproto"
+	"time"
+)
+
+// Backoff 第 attempt 次（从 1 开始）失败后的等待时间，每次翻倍，不超过 max
+func Backoff(attempt int, base time.Duration, max time.Duration) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	d := base
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= max || d <= 0 {
+			return max
+		}
+	}
+	if d > max {
+		return max
+	}
+	return d
+}
+
+// IsPermanent 重试也不会成功的错误：收件人为空、模板不存在，或 SMTP 服务器返回 5xx
+func IsPermanent(err error) bool {
+	if errors.Is(err, ErrNoRecipient) || errors.Is(err, ErrTemplateNotFound) {
+		return true
+	}
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return protoErr.Code >= 500 && protoErr.Code < 600
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTP 的 TLS 方式
+const (
+	TLSImplicit = "implicit" // 直接建立 TLS 连接，一般为 465 端口
+	TLSStartTLS = "starttls" // 明文连接后升级，一般为 587 端口，服务器不支持时拒绝发送
+	TLSNone     = "none"     // 只用于本机或内网的中继
+)
+
+const defaultSMTPTimeout = 10 * time.Second
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	TLS      string
+	// InsecureSkipVerify 跳过证书校验，只用于自签名证书的测试服务器
+	InsecureSkipVerify bool
+	Timeout            time.Duration
+}
+
+type SMTPTransport struct {
+	conf SMTPConfig
+}
+
+func NewSMTPTransport(conf SMTPConfig) (*SMTPTransport, error) {
+	if conf.Host == "" || conf.Port == 0 {
+		return nil, fmt.Errorf("mailer: smtp transport requires mail.smtp.host and mail.smtp.port")
+	}
+	switch conf.TLS {
+	case "":
+		conf.TLS = TLSImplicit
+	case TLSImplicit, TLSStartTLS, TLSNone:
+	default:
+		return nil, fmt.Errorf("mailer: unknown smtp tls mode %q", conf.TLS)
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = defaultSMTPTimeout
+	}
+	return &SMTPTransport{conf: conf}, nil
+}
+
+func (t *SMTPTransport) Send(ctx context.Context, from string, to []string, raw []byte) error {
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return err
+	}
+	addr := net.JoinHostPort(t.conf.Host, strconv.Itoa(t.conf.Port))
+	deadline := time.Now().Add(t.conf.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	dialer := &net.Dialer{Deadline: deadline}
+	tlsConfig := &tls.Config{ServerName: t.conf.Host, InsecureSkipVerify: t.conf.InsecureSkipVerify}
+
+	var conn net.Conn
+	if t.conf.TLS == TLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	if err = conn.SetDeadline(deadline); err != nil {
+		_ = conn.Close()
+		return err
+	}
+	c, err := smtp.NewClient(conn, t.conf.Host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if t.conf.TLS == TLSStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("mailer: %s does not support STARTTLS", addr)
+		}
+		if err = c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if t.conf.Username != "" {
+		// PlainAuth 拒绝在未加密的非本机连接上发送密码
+		if err = c.Auth(smtp.PlainAuth("", t.conf.Username, t.conf.Password, t.conf.Host)); err != nil {
+			return err
+		}
+	}
+	if err = c.Mail(sender.Address); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err = c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(raw); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
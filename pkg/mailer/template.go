@@ -0,0 +1,142 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var defaultTemplates embed.FS
+
+// defaultTemplateLocale 没有配置 mail.default_locale 时的默认语言
+const defaultTemplateLocale = "zh"
+
+var ErrTemplateNotFound = errors.New("mailer: template not found")
+
+// Rendered 渲染后的邮件内容
+type Rendered struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Templates 按 <locale>/<name>.tmpl 组织模板，每个文件定义 subject、text、html 三个模板，
+// subject 和 text 用 text/template 渲染，html 用 html/template 渲染以转义数据
+type Templates struct {
+	fsys          fs.FS
+	defaultLocale string
+
+	mu    sync.Mutex
+	cache map[string]*templateSet
+}
+
+type templateSet struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+func NewTemplates(fsys fs.FS, defaultLocale string) *Templates {
+	if defaultLocale == "" {
+		defaultLocale = defaultTemplateLocale
+	}
+	return &Templates{
+		fsys:          fsys,
+		defaultLocale: strings.ToLower(defaultLocale),
+		cache:         map[string]*templateSet{},
+	}
+}
+
+// NewDefaultTemplates 内置模板，支持 zh 和 en
+func NewDefaultTemplates(defaultLocale string) *Templates {
+	sub, err := fs.Sub(defaultTemplates, "templates")
+	if err != nil {
+		panic(err)
+	}
+	return NewTemplates(sub, defaultLocale)
+}
+
+func (t *Templates) Render(name string, locale string, data interface{}) (*Rendered, error) {
+	set, err := t.lookup(name, locale)
+	if err != nil {
+		return nil, err
+	}
+	var subject, text, html bytes.Buffer
+	if err = set.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+	if err = set.text.ExecuteTemplate(&text, "text", data); err != nil {
+		return nil, err
+	}
+	if err = set.html.ExecuteTemplate(&html, "html", data); err != nil {
+		return nil, err
+	}
+	return &Rendered{
+		// 主题不能换行，避免注入邮件头
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    strings.TrimSpace(html.String()) + "\n",
+	}, nil
+}
+
+// lookup 依次尝试 zh-cn、zh 和默认语言
+func (t *Templates) lookup(name string, locale string) (*templateSet, error) {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	candidates := []string{locale}
+	if i := strings.IndexByte(locale, '-'); i > 0 {
+		candidates = append(candidates, locale[:i])
+	}
+	candidates = append(candidates, t.defaultLocale)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, loc := range candidates {
+		if loc == "" {
+			continue
+		}
+		path := loc + "/" + name + ".tmpl"
+		if set, ok := t.cache[path]; ok {
+			return set, nil
+		}
+		b, err := fs.ReadFile(t.fsys, path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		set, err := parseSet(path, string(b))
+		if err != nil {
+			return nil, err
+		}
+		t.cache[path] = set
+		return set, nil
+	}
+	return nil, fmt.Errorf("%w: %s (%s)", ErrTemplateNotFound, name, locale)
+}
+
+func parseSet(path string, content string) (*templateSet, error) {
+	text, err := texttemplate.New(path).Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	html, err := htmltemplate.New(path).Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"subject", "text"} {
+		if text.Lookup(name) == nil {
+			return nil, fmt.Errorf("mailer: %s does not define %q", path, name)
+		}
+	}
+	if html.Lookup("html") == nil {
+		return nil, fmt.Errorf("mailer: %s does not define \"html\"", path)
+	}
+	return &templateSet{text: text, html: html}, nil
+}
@@ -0,0 +1,93 @@
+package moderation
+
+import "unicode"
+
+// Match 命中的敏感词，Start/End 为 rune 下标，左闭右开
+type Match struct {
+	Start   int
+	End     int
+	Pattern int
+}
+
+type acNode struct {
+	next map[rune]int
+	fail int
+	// out 以该节点结尾的模式下标（含 fail 链上的）
+	out []int
+}
+
+// Matcher Aho-Corasick 多模式匹配，忽略大小写，构建后只读、并发安全
+type Matcher struct {
+	nodes   []acNode
+	lengths []int
+}
+
+func NewMatcher(patterns []string) *Matcher {
+	m := &Matcher{nodes: []acNode{{next: map[rune]int{}}}, lengths: make([]int, len(patterns))}
+	for i, p := range patterns {
+		cur := 0
+		runes := []rune(p)
+		m.lengths[i] = len(runes)
+		if len(runes) == 0 {
+			continue
+		}
+		for _, r := range runes {
+			r = unicode.ToLower(r)
+			nxt, ok := m.nodes[cur].next[r]
+			if !ok {
+				m.nodes = append(m.nodes, acNode{next: map[rune]int{}})
+				nxt = len(m.nodes) - 1
+				m.nodes[cur].next[r] = nxt
+			}
+			cur = nxt
+		}
+		m.nodes[cur].out = append(m.nodes[cur].out, i)
+	}
+
+	// BFS 构建 fail 指针
+	queue := make([]int, 0, len(m.nodes))
+	for _, child := range m.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for r, child := range m.nodes[cur].next {
+			f := m.nodes[cur].fail
+			for f != 0 {
+				if _, ok := m.nodes[f].next[r]; ok {
+					break
+				}
+				f = m.nodes[f].fail
+			}
+			if nxt, ok := m.nodes[f].next[r]; ok && nxt != child {
+				m.nodes[child].fail = nxt
+			}
+			m.nodes[child].out = append(m.nodes[child].out, m.nodes[m.nodes[child].fail].out...)
+			queue = append(queue, child)
+		}
+	}
+	return m
+}
+
+// FindAll 返回文本中所有命中（可重叠）
+func (m *Matcher) FindAll(text string) []Match {
+	var matches []Match
+	cur := 0
+	for i, r := range []rune(text) {
+		r = unicode.ToLower(r)
+		for cur != 0 {
+			if _, ok := m.nodes[cur].next[r]; ok {
+				break
+			}
+			cur = m.nodes[cur].fail
+		}
+		if nxt, ok := m.nodes[cur].next[r]; ok {
+			cur = nxt
+		}
+		for _, p := range m.nodes[cur].out {
+			matches = append(matches, Match{Start: i + 1 - m.lengths[p], End: i + 1, Pattern: p})
+		}
+	}
+	return matches
+}
@@ -0,0 +1,207 @@
+package moderation
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"go-chat/pkg/log"
+	"go.uber.org/zap"
+)
+
+type Action string
+
+// 处理动作，按严重程度递增
+const (
+	ActionPass  Action = "pass"
+	ActionMask  Action = "mask"
+	ActionFlag  Action = "flag"
+	ActionBlock Action = "block"
+)
+
+var severity = map[Action]int{
+	ActionPass:  0,
+	ActionMask:  1,
+	ActionFlag:  2,
+	ActionBlock: 3,
+}
+
+// Valid 配置中写错的动作严重程度为 0，会被当作 pass 悄悄放行，加载时必须拒绝
+func (a Action) Valid() bool {
+	_, ok := severity[a]
+	return ok
+}
+
+// Result 审核结果，Text 为处理后的文本（mask 时敏感词被替换为 *）
+type Result struct {
+	Action Action
+	Text   string
+	Words  []string
+}
+
+// WordList 一个词库文件，每行一个词，# 开头为注释
+type WordList struct {
+	File   string `mapstructure:"file"`
+	Action Action `mapstructure:"action"`
+}
+
+// Filter 敏感词过滤器，词库文件修改后在 reload_interval 内自动重新加载
+type Filter struct {
+	logger   *log.Logger
+	lists    []WordList
+	interval time.Duration
+
+	mu        sync.RWMutex
+	matcher   *Matcher
+	words     []string
+	actions   []Action
+	modTimes  map[string]time.Time
+	lastCheck time.Time
+}
+
+// NewFilter 词库配置有误（如动作拼写错误）时返回错误，避免命中的词被静默放行
+func NewFilter(conf *viper.Viper, logger *log.Logger) (*Filter, error) {
+	var lists []WordList
+	if err := conf.UnmarshalKey("moderation.lists", &lists); err != nil {
+		return nil, fmt.Errorf("moderation: invalid moderation.lists: %w", err)
+	}
+	for _, l := range lists {
+		if !l.Action.Valid() {
+			return nil, fmt.Errorf("moderation: list %s: unknown action %q", l.File, l.Action)
+		}
+	}
+	interval := conf.GetDuration("moderation.reload_interval")
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	f := &Filter{
+		logger:   logger,
+		lists:    lists,
+		interval: interval,
+		matcher:  NewMatcher(nil),
+		modTimes: map[string]time.Time{},
+	}
+	if err := f.Reload(); err != nil {
+		logger.Error("moderation word list load failed", zap.Error(err))
+	}
+	return f, nil
+}
+
+// Check 审核文本，同时命中多个动作时取最严重的
+func (f *Filter) Check(text string) *Result {
+	f.maybeReload()
+
+	f.mu.RLock()
+	matcher, words, actions := f.matcher, f.words, f.actions
+	f.mu.RUnlock()
+
+	res := &Result{Action: ActionPass, Text: text}
+	matches := matcher.FindAll(text)
+	if len(matches) == 0 {
+		return res
+	}
+
+	runes := []rune(text)
+	seen := map[int]bool{}
+	for _, m := range matches {
+		action := actions[m.Pattern]
+		if severity[action] > severity[res.Action] {
+			res.Action = action
+		}
+		if !seen[m.Pattern] {
+			seen[m.Pattern] = true
+			res.Words = append(res.Words, words[m.Pattern])
+		}
+		if action == ActionMask {
+			for i := m.Start; i < m.End; i++ {
+				runes[i] = '*'
+			}
+		}
+	}
+	res.Text = string(runes)
+	return res
+}
+
+// Reload 重新读取所有词库并重建自动机
+func (f *Filter) Reload() error {
+	var (
+		words    []string
+		actions  []Action
+		modTimes = map[string]time.Time{}
+	)
+	for _, l := range f.lists {
+		stat, err := os.Stat(l.File)
+		if err != nil {
+			return err
+		}
+		modTimes[l.File] = stat.ModTime()
+		list, err := readWordList(l.File)
+		if err != nil {
+			return err
+		}
+		for _, w := range list {
+			words = append(words, w)
+			actions = append(actions, l.Action)
+		}
+	}
+	matcher := NewMatcher(words)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.matcher, f.words, f.actions, f.modTimes = matcher, words, actions, modTimes
+	f.lastCheck = time.Now()
+	return nil
+}
+
+// maybeReload 距上次检查超过 interval 时比较文件修改时间，有变化则重新加载
+func (f *Filter) maybeReload() {
+	f.mu.Lock()
+	if time.Since(f.lastCheck) < f.interval {
+		f.mu.Unlock()
+		return
+	}
+	f.lastCheck = time.Now()
+	modTimes := f.modTimes
+	f.mu.Unlock()
+
+	changed := false
+	for _, l := range f.lists {
+		stat, err := os.Stat(l.File)
+		if err != nil || !stat.ModTime().Equal(modTimes[l.File]) {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return
+	}
+	if err := f.Reload(); err != nil {
+		// 加载失败时继续使用旧词库
+		f.logger.Error("moderation word list reload failed", zap.Error(err))
+		return
+	}
+	f.logger.Info("moderation word list reloaded")
+}
+
+func readWordList(file string) ([]string, error) {
+	fd, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		w := strings.TrimSpace(scanner.Text())
+		if w == "" || strings.HasPrefix(w, "#") {
+			continue
+		}
+		words = append(words, w)
+	}
+	return words, scanner.Err()
+}
@@ -0,0 +1,84 @@
+package oidc
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/viper"
+)
+
+// ProviderConfig 配置文件中的提供方，client_secret_env 为保存密钥的环境变量名，优先于 client_secret
+type ProviderConfig struct {
+	Name            string   `mapstructure:"name"`
+	DisplayName     string   `mapstructure:"display_name"`
+	Issuer          string   `mapstructure:"issuer"`
+	ClientId        string   `mapstructure:"client_id"`
+	ClientSecret    string   `mapstructure:"client_secret"`
+	ClientSecretEnv string   `mapstructure:"client_secret_env"`
+	RedirectURL     string   `mapstructure:"redirect_url"`
+	Scopes          []string `mapstructure:"scopes"`
+}
+
+// Registry 按名称查找已配置的提供方，顺序与配置文件一致
+type Registry struct {
+	names   []string
+	clients map[string]*Client
+}
+
+// NewRegistry 从 oidc.providers 加载提供方，没有配置时返回空的 Registry
+func NewRegistry(conf *viper.Viper) (*Registry, error) {
+	var configs []ProviderConfig
+	if err := conf.UnmarshalKey("oidc.providers", &configs); err != nil {
+		return nil, err
+	}
+	clients := make([]*Client, 0, len(configs))
+	for _, pc := range configs {
+		if pc.Name == "" || pc.Issuer == "" || pc.ClientId == "" || pc.RedirectURL == "" {
+			return nil, fmt.Errorf("oidc: provider %q requires name, issuer, client_id and redirect_url", pc.Name)
+		}
+		secret := pc.ClientSecret
+		if pc.ClientSecretEnv != "" {
+			secret = os.Getenv(pc.ClientSecretEnv)
+		}
+		displayName := pc.DisplayName
+		if displayName == "" {
+			displayName = pc.Name
+		}
+		clients = append(clients, NewClient(Config{
+			Name:         pc.Name,
+			DisplayName:  displayName,
+			Issuer:       pc.Issuer,
+			ClientId:     pc.ClientId,
+			ClientSecret: secret,
+			RedirectURL:  pc.RedirectURL,
+			Scopes:       pc.Scopes,
+		}, nil))
+	}
+	return NewStaticRegistry(clients...)
+}
+
+func NewStaticRegistry(clients ...*Client) (*Registry, error) {
+	r := &Registry{clients: make(map[string]*Client, len(clients))}
+	for _, c := range clients {
+		name := c.Config().Name
+		if _, ok := r.clients[name]; ok {
+			return nil, fmt.Errorf("oidc: duplicate provider %q", name)
+		}
+		r.names = append(r.names, name)
+		r.clients[name] = c
+	}
+	return r, nil
+}
+
+func (r *Registry) Get(name string) (*Client, bool) {
+	c, ok := r.clients[name]
+	return c, ok
+}
+
+func (r *Registry) List() []*Client {
+	list := make([]*Client, 0, len(r.names))
+	for _, name := range r.names {
+		list = append(list, r.clients[name])
+	}
+	return list
+}
@@ -0,0 +1,18 @@
+package password
+
+import (
+	"crypto/md5"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+)
+
+// verifyLegacy 校验旧版 md5(pw)$salt 格式的密码，只用于登录时迁移到新算法
+func verifyLegacy(password string, encoded string) bool {
+	i := strings.Index(encoded, "$")
+	if i < 0 {
+		return false
+	}
+	sum := md5.Sum([]byte(password))
+	return subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(encoded[:i])) == 1
+}
@@ -0,0 +1,79 @@
+package password
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// 支持的哈希算法，同时作为哈希串的前缀版本号
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+var ErrUnknownHash = errors.New("password: unknown hash format")
+
+// Hasher 一种密码哈希算法，哈希串自带算法前缀、参数和随机盐
+type Hasher interface {
+	Hash(password string) (string, error)
+	// Match 判断哈希串是否由该算法生成
+	Match(encoded string) bool
+	Verify(password string, encoded string) (bool, error)
+	// NeedsRehash 哈希串的参数与当前配置不一致时返回 true
+	NeedsRehash(encoded string) bool
+}
+
+// Manager 用当前算法生成哈希，校验时按前缀选择算法，兼容旧版 md5(pw)$salt 格式
+type Manager struct {
+	current Hasher
+	hashers []Hasher
+}
+
+func NewManager(conf *viper.Viper) *Manager {
+	argon := NewArgon2id(
+		uint32(conf.GetInt("security.password.argon2.memory")),
+		uint32(conf.GetInt("security.password.argon2.iterations")),
+		uint8(conf.GetInt("security.password.argon2.parallelism")),
+	)
+	bc := NewBcrypt(conf.GetInt("security.password.bcrypt.cost"))
+
+	m := &Manager{current: argon, hashers: []Hasher{argon, bc}}
+	if conf.GetString("security.password.algorithm") == AlgorithmBcrypt {
+		m.current = bc
+	}
+	return m
+}
+
+// New 指定当前算法，others 为仅用于校验的其他算法
+func New(current Hasher, others ...Hasher) *Manager {
+	return &Manager{current: current, hashers: append([]Hasher{current}, others...)}
+}
+
+func (m *Manager) Hash(password string) (string, error) {
+	return m.current.Hash(password)
+}
+
+// Verify 校验密码，rehash 为 true 时调用方应使用 Hash 重新生成并保存
+func (m *Manager) Verify(password string, encoded string) (ok bool, rehash bool, err error) {
+	if isLegacy(encoded) {
+		return verifyLegacy(password, encoded), true, nil
+	}
+	for _, h := range m.hashers {
+		if !h.Match(encoded) {
+			continue
+		}
+		ok, err = h.Verify(password, encoded)
+		if err != nil || !ok {
+			return false, false, err
+		}
+		return true, h != m.current || h.NeedsRehash(encoded), nil
+	}
+	return false, false, ErrUnknownHash
+}
+
+// isLegacy 新格式的哈希串都以 $ 开头
+func isLegacy(encoded string) bool {
+	return encoded != "" && !strings.HasPrefix(encoded, "$")
+}
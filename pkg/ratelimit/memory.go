@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval 清理已回满的令牌桶的间隔
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  Limit
+}
+
+// MemoryLimiter 进程内令牌桶，用于测试和单实例部署
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+func (m *MemoryLimiter) Allow(ctx context.Context, key string, limit Limit) (*Result, error) {
+	if !limit.valid() {
+		return &Result{Allowed: true}, nil
+	}
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+
+	burst := float64(limit.burst())
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		m.buckets[key] = b
+	}
+	b.limit = limit
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*limit.perSecond())
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return &Result{Allowed: true, Remaining: int(b.tokens)}, nil
+	}
+	wait := (1 - b.tokens) / limit.perSecond()
+	return &Result{Allowed: false, RetryAfter: time.Duration(wait * float64(time.Second))}, nil
+}
+
+// sweep 删除已经回满的令牌桶，避免 key 无限增长
+func (m *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+	for key, b := range m.buckets {
+		refill := float64(b.limit.burst()) / b.limit.perSecond()
+		if now.Sub(b.last).Seconds() >= refill {
+			delete(m.buckets, key)
+		}
+	}
+}
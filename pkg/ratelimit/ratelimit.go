@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+)
+
+// Limit 令牌桶参数：每 Period 补充 Rate 个令牌，桶容量为 Burst
+type Limit struct {
+	Rate   int
+	Period time.Duration
+	Burst  int
+}
+
+// perSecond 每秒补充的令牌数
+func (l Limit) perSecond() float64 {
+	return float64(l.Rate) / l.Period.Seconds()
+}
+
+func (l Limit) burst() int {
+	if l.Burst <= 0 {
+		return l.Rate
+	}
+	return l.Burst
+}
+
+func (l Limit) valid() bool {
+	return l.Rate > 0 && l.Period > 0
+}
+
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter 被拒绝时距离下一个令牌可用的时间
+	RetryAfter time.Duration
+}
+
+type Limiter interface {
+	// Allow 从 key 对应的令牌桶中取一个令牌
+	Allow(ctx context.Context, key string, limit Limit) (*Result, error)
+}
+
+// Policy 一条限流规则，Route 为空时对所有路由生效
+type Policy struct {
+	Name   string        `mapstructure:"name"`
+	Route  string        `mapstructure:"route"`
+	Key    string        `mapstructure:"key"` // ip、user 或 route
+	Rate   int           `mapstructure:"rate"`
+	Period time.Duration `mapstructure:"period"`
+	Burst  int           `mapstructure:"burst"`
+}
+
+func (p Policy) Limit() Limit {
+	return Limit{Rate: p.Rate, Period: p.Period, Burst: p.Burst}
+}
+
+const (
+	KeyIP    = "ip"
+	KeyUser  = "user"
+	KeyRoute = "route"
+)
+
+// NewLimiter 根据 rate_limit.backend 选择限流后端，默认使用 redis
+func NewLimiter(conf *viper.Viper, rdb *redis.Client) Limiter {
+	if conf.GetString("rate_limit.backend") == "memory" {
+		return NewMemoryLimiter()
+	}
+	return NewRedisLimiter(rdb, conf.GetString("rate_limit.prefix"))
+}
+
+// NewPolicies 读取 rate_limit.policies
+func NewPolicies(conf *viper.Viper) ([]Policy, error) {
+	var policies []Policy
+	if err := conf.UnmarshalKey("rate_limit.policies", &policies); err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
@@ -0,0 +1,197 @@
+package rsakey
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// 密文格式为 <scheme>:<keyId>:<base64>，不带前缀的密文按旧客户端处理：当前密钥 + PKCS1v15
+const (
+	SchemeOAEP     = "oaep" // RSA-OAEP，哈希为 SHA-256
+	SchemePKCS1v15 = "pkcs1"
+)
+
+var (
+	ErrKeyNotFound   = errors.New("rsakey: key not found")
+	ErrInvalidCipher = errors.New("rsakey: invalid ciphertext")
+	ErrDecrypt       = errors.New("rsakey: decryption failed")
+)
+
+type Key struct {
+	ID         string
+	PrivateKey *rsa.PrivateKey
+}
+
+// PublicKeyPEM 返回 PKIX 格式的公钥
+func (k *Key) PublicKeyPEM() (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(&k.PrivateKey.PublicKey)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+// Provider 提供解密用的私钥，可替换为 KMS 等实现
+type Provider interface {
+	// Current 客户端加密时应使用的密钥
+	Current() *Key
+	Get(id string) (*Key, bool)
+}
+
+// KeyConfig 密钥来源三选一：pem 直接写在配置中、env 为保存 PEM 的环境变量名、file 为 PEM 文件路径
+type KeyConfig struct {
+	ID   string `mapstructure:"id"`
+	PEM  string `mapstructure:"pem"`
+	Env  string `mapstructure:"env"`
+	File string `mapstructure:"file"`
+}
+
+// NewProvider 从 security.rsa 加载密钥，启动时解析一次后缓存。
+// 轮换时把新密钥加到 keys 并设为 current，旧密钥保留到客户端都更新公钥后再删除
+func NewProvider(conf *viper.Viper) (Provider, error) {
+	var configs []KeyConfig
+	if err := conf.UnmarshalKey("security.rsa.keys", &configs); err != nil {
+		return nil, err
+	}
+	keys := make([]*Key, 0, len(configs))
+	for _, c := range configs {
+		key, err := loadKey(c)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return NewStaticProvider(conf.GetString("security.rsa.current"), keys...)
+}
+
+type staticProvider struct {
+	current *Key
+	keys    map[string]*Key
+}
+
+// NewStaticProvider currentId 为空时使用第一个密钥
+func NewStaticProvider(currentId string, keys ...*Key) (Provider, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("rsakey: no key configured")
+	}
+	p := &staticProvider{keys: make(map[string]*Key, len(keys))}
+	for _, k := range keys {
+		if _, ok := p.keys[k.ID]; ok {
+			return nil, fmt.Errorf("rsakey: duplicate key id %q", k.ID)
+		}
+		p.keys[k.ID] = k
+	}
+	if currentId == "" {
+		currentId = keys[0].ID
+	}
+	current, ok := p.keys[currentId]
+	if !ok {
+		return nil, fmt.Errorf("rsakey: current key %q not found", currentId)
+	}
+	p.current = current
+	return p, nil
+}
+
+func (p *staticProvider) Current() *Key {
+	return p.current
+}
+
+func (p *staticProvider) Get(id string) (*Key, bool) {
+	k, ok := p.keys[id]
+	return k, ok
+}
+
+// Decrypt 解密客户端用公钥加密的数据
+func Decrypt(p Provider, data string) (string, error) {
+	scheme, key, encoded := SchemePKCS1v15, p.Current(), data
+	if parts := strings.SplitN(data, ":", 3); len(parts) == 3 {
+		var ok bool
+		if key, ok = p.Get(parts[1]); !ok {
+			return "", ErrKeyNotFound
+		}
+		scheme, encoded = parts[0], parts[2]
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrInvalidCipher
+	}
+	var plain []byte
+	switch scheme {
+	case SchemeOAEP:
+		plain, err = rsa.DecryptOAEP(sha256.New(), rand.Reader, key.PrivateKey, ciphertext, nil)
+	case SchemePKCS1v15:
+		plain, err = rsa.DecryptPKCS1v15(rand.Reader, key.PrivateKey, ciphertext)
+	default:
+		return "", ErrInvalidCipher
+	}
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	return string(plain), nil
+}
+
+func loadKey(c KeyConfig) (*Key, error) {
+	if c.ID == "" {
+		return nil, errors.New("rsakey: key id is required")
+	}
+	var (
+		data []byte
+		err  error
+	)
+	switch {
+	case c.PEM != "":
+		data = []byte(c.PEM)
+	case c.Env != "":
+		data = []byte(os.Getenv(c.Env))
+		if len(data) == 0 {
+			return nil, fmt.Errorf("rsakey: env %s of key %q is empty", c.Env, c.ID)
+		}
+	case c.File != "":
+		if data, err = os.ReadFile(c.File); err != nil {
+			return nil, fmt.Errorf("rsakey: read key %q: %w", c.ID, err)
+		}
+	default:
+		return nil, fmt.Errorf("rsakey: key %q has no source", c.ID)
+	}
+
+	privateKey, err := ParsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("rsakey: parse key %q: %w", c.ID, err)
+	}
+	return &Key{ID: c.ID, PrivateKey: privateKey}, nil
+}
+
+// ParsePrivateKey 支持 PKCS8 和 PKCS1 格式的 PEM
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM data")
+	}
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		privateKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("not an RSA private key")
+		}
+		return privateKey, nil
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM type %q", block.Type)
+	}
+}
@@ -11,21 +11,22 @@ import (
 
 type Server struct {
 	*grpc.Server
-	host   string
-	port   int
-	logger *log.Logger
+	host    string
+	port    int
+	options []grpc.ServerOption
+	logger  *log.Logger
 }
 
 type Option func(s *Server)
 
 func NewServer(logger *log.Logger, opts ...Option) *Server {
 	s := &Server{
-		Server: grpc.NewServer(),
 		logger: logger,
 	}
 	for _, opt := range opts {
 		opt(s)
 	}
+	s.Server = grpc.NewServer(s.options...)
 	return s
 }
 func WithServerHost(host string) Option {
@@ -39,6 +40,13 @@ func WithServerPort(port int) Option {
 	}
 }
 
+// WithServerOptions 向底层 grpc.Server 传入拦截器等选项
+func WithServerOptions(options ...grpc.ServerOption) Option {
+	return func(s *Server) {
+		s.options = append(s.options, options...)
+	}
+}
+
 func (s *Server) Start(ctx context.Context) error {
 	lis, err := net.Listen("tcp", fmt.Sprintf("%s:%d", s.host, s.port))
 	if err != nil {
@@ -0,0 +1,49 @@
+package sms
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalidPhone = errors.New("sms: invalid phone number")
+
+// Normalize 统一为 E.164 格式：去掉空格、横线和括号，
+// 不带国家码的 11 位 1 开头号码按中国大陆手机号处理
+func Normalize(phone string) (string, error) {
+	var b strings.Builder
+	for i, r := range strings.TrimSpace(phone) {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '+' && i == 0:
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '(' || r == ')':
+		default:
+			return "", ErrInvalidPhone
+		}
+	}
+	s := b.String()
+	if strings.HasPrefix(s, "00") {
+		s = "+" + s[2:]
+	}
+	if !strings.HasPrefix(s, "+") {
+		if len(s) != 11 || s[0] != '1' {
+			return "", ErrInvalidPhone
+		}
+		s = "+86" + s
+	}
+	// E.164 最多 15 位数字，国家码不以 0 开头
+	digits := s[1:]
+	if len(digits) < 8 || len(digits) > 15 || digits[0] == '0' {
+		return "", ErrInvalidPhone
+	}
+	return s, nil
+}
+
+// Mask 日志和接口中展示的号码，只保留前后几位
+func Mask(phone string) string {
+	if len(phone) <= 7 {
+		return strings.Repeat("*", len(phone))
+	}
+	return phone[:len(phone)-8] + "****" + phone[len(phone)-4:]
+}
@@ -0,0 +1,83 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"go-chat/pkg/log"
+	"go.uber.org/zap"
+)
+
+// 内置的开发用短信通道，接入真实服务商时实现 Sender 并在 NewSender 中注册
+const (
+	ProviderLog  = "log"
+	ProviderFile = "file"
+)
+
+// Sender 短信通道，phone 为 Normalize 后的号码
+type Sender interface {
+	Send(ctx context.Context, phone string, text string) error
+}
+
+// NewSender 根据 sms.provider 选择短信通道，默认只写日志
+func NewSender(conf *viper.Viper, logger *log.Logger) (Sender, error) {
+	switch provider := conf.GetString("sms.provider"); provider {
+	case "", ProviderLog:
+		return NewLogSender(logger), nil
+	case ProviderFile:
+		return NewFileSender(conf.GetString("sms.file.path"))
+	default:
+		return nil, fmt.Errorf("sms: unknown provider %q", provider)
+	}
+}
+
+type logSender struct {
+	logger *log.Logger
+}
+
+// NewLogSender 把短信内容写到日志，只用于开发环境
+func NewLogSender(logger *log.Logger) Sender {
+	return &logSender{logger: logger}
+}
+
+func (s *logSender) Send(ctx context.Context, phone string, text string) error {
+	s.logger.WithContext(ctx).Info("sms sent", zap.String("phone", phone), zap.String("text", text))
+	return nil
+}
+
+type fileSender struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileSender 把短信逐行追加到 path，方便测试环境查看验证码
+func NewFileSender(path string) (Sender, error) {
+	if path == "" {
+		return nil, fmt.Errorf("sms: file provider requires sms.file.path")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	return &fileSender{path: path}, nil
+}
+
+func (s *fileSender) Send(ctx context.Context, phone string, text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	line := fmt.Sprintf("%s\t%s\t%s\n", time.Now().Format(time.RFC3339), phone, strings.ReplaceAll(text, "\n", " "))
+	if _, err = f.WriteString(line); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
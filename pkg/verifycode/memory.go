@@ -0,0 +1,118 @@
+package verifycode
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval 清理过期验证码、冷却和锁定记录的间隔
+const sweepInterval = time.Minute
+
+type memoryEntry struct {
+	hash     string
+	attempts int
+	expireAt time.Time
+}
+
+// MemoryStore 进程内存储，只适用于单实例和测试
+type MemoryStore struct {
+	mu        sync.Mutex
+	codes     map[string]*memoryEntry
+	cooldowns map[string]time.Time
+	locks     map[string]time.Time
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		codes:     map[string]*memoryEntry{},
+		cooldowns: map[string]time.Time{},
+		locks:     map[string]time.Time{},
+		now:       time.Now,
+		lastSweep: time.Now(),
+	}
+}
+
+// SetClock 替换时间来源，用于测试过期
+func (m *MemoryStore) SetClock(now func() time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = now
+}
+
+func (m *MemoryStore) Save(ctx context.Context, key string, hash string, ttl time.Duration, cooldown time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	m.sweep(now)
+	if until, ok := m.locks[key]; ok && now.Before(until) {
+		return ErrLocked
+	}
+	if until, ok := m.cooldowns[key]; ok && now.Before(until) {
+		return ErrCooldown
+	}
+	if cooldown > 0 {
+		m.cooldowns[key] = now.Add(cooldown)
+	}
+	m.codes[key] = &memoryEntry{hash: hash, expireAt: now.Add(ttl)}
+	return nil
+}
+
+func (m *MemoryStore) Check(ctx context.Context, key string, hash string, maxAttempts int, lockout time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	m.sweep(now)
+	if until, ok := m.locks[key]; ok && now.Before(until) {
+		return ErrLocked
+	}
+	entry, ok := m.codes[key]
+	if !ok || !now.Before(entry.expireAt) {
+		delete(m.codes, key)
+		return ErrMismatch
+	}
+	if entry.hash == hash {
+		delete(m.codes, key)
+		return nil
+	}
+	entry.attempts++
+	if entry.attempts >= maxAttempts {
+		delete(m.codes, key)
+		m.locks[key] = now.Add(lockout)
+		return ErrLocked
+	}
+	return ErrMismatch
+}
+
+func (m *MemoryStore) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.codes, key)
+	delete(m.cooldowns, key)
+	return nil
+}
+
+// sweep 删除过期的验证码、冷却和锁定记录，避免从未再次使用的 key 无限增长
+func (m *MemoryStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+	for key, entry := range m.codes {
+		if !now.Before(entry.expireAt) {
+			delete(m.codes, key)
+		}
+	}
+	for key, until := range m.cooldowns {
+		if !now.Before(until) {
+			delete(m.cooldowns, key)
+		}
+	}
+	for key, until := range m.locks {
+		if !now.Before(until) {
+			delete(m.locks, key)
+		}
+	}
+}
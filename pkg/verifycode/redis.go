@@ -0,0 +1,105 @@
+package verifycode
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	resultOK       = 0
+	resultMismatch = 1
+	resultLocked   = 2
+	resultCooldown = 3
+)
+
+// saveScript KEYS: code, cooldown, lock；ARGV: hash, ttl(ms), cooldown(ms)
+var saveScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[3]) == 1 then
+	return 2
+end
+local cooldown = tonumber(ARGV[3])
+if cooldown > 0 and not redis.call("SET", KEYS[2], 1, "PX", cooldown, "NX") then
+	return 3
+end
+redis.call("DEL", KEYS[1])
+redis.call("HSET", KEYS[1], "hash", ARGV[1], "attempts", 0)
+redis.call("PEXPIRE", KEYS[1], ARGV[2])
+return 0
+`)
+
+// checkScript KEYS: code, lock；ARGV: hash, maxAttempts, lockout(ms)
+var checkScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[2]) == 1 then
+	return 2
+end
+local stored = redis.call("HGET", KEYS[1], "hash")
+if not stored then
+	return 1
+end
+if stored == ARGV[1] then
+	redis.call("DEL", KEYS[1])
+	return 0
+end
+local attempts = redis.call("HINCRBY", KEYS[1], "attempts", 1)
+if attempts >= tonumber(ARGV[2]) then
+	redis.call("DEL", KEYS[1])
+	redis.call("SET", KEYS[2], 1, "PX", ARGV[3])
+	return 2
+end
+return 1
+`)
+
+type RedisStore struct {
+	rdb    *redis.Client
+	prefix string
+}
+
+func NewRedisStore(rdb *redis.Client, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "verify_code:"
+	}
+	return &RedisStore{
+		rdb:    rdb,
+		prefix: prefix,
+	}
+}
+
+func (r *RedisStore) Save(ctx context.Context, key string, hash string, ttl time.Duration, cooldown time.Duration) error {
+	k := r.prefix + key
+	res, err := saveScript.Run(ctx, r.rdb, []string{k, k + ":cooldown", k + ":lock"},
+		hash, ttl.Milliseconds(), cooldown.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	return resultError(res)
+}
+
+func (r *RedisStore) Check(ctx context.Context, key string, hash string, maxAttempts int, lockout time.Duration) error {
+	k := r.prefix + key
+	res, err := checkScript.Run(ctx, r.rdb, []string{k, k + ":lock"},
+		hash, maxAttempts, lockout.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	return resultError(res)
+}
+
+func (r *RedisStore) Delete(ctx context.Context, key string) error {
+	k := r.prefix + key
+	return r.rdb.Del(ctx, k, k+":cooldown").Err()
+}
+
+func resultError(res int) error {
+	switch res {
+	case resultOK:
+		return nil
+	case resultLocked:
+		return ErrLocked
+	case resultCooldown:
+		return ErrCooldown
+	default:
+		return ErrMismatch
+	}
+}
@@ -0,0 +1,155 @@
+package verifycode
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+)
+
+var (
+	// ErrCooldown 距离上次发送不足 Cooldown
+	ErrCooldown = errors.New("verifycode: requested too soon")
+	// ErrLocked 连续输错 MaxAttempts 次，Lockout 内不能验证也不能重新发送
+	ErrLocked = errors.New("verifycode: too many failed attempts")
+	// ErrMismatch 验证码错误、已使用或已过期
+	ErrMismatch = errors.New("verifycode: code is incorrect or expired")
+)
+
+const (
+	defaultLength      = 6
+	defaultTTL         = 5 * time.Minute
+	defaultCooldown    = time.Minute
+	defaultMaxAttempts = 5
+	defaultLockout     = 15 * time.Minute
+)
+
+type Options struct {
+	Length      int
+	TTL         time.Duration
+	Cooldown    time.Duration
+	MaxAttempts int
+	Lockout     time.Duration
+}
+
+// Target 验证码发送的对象，同一地址不同用途的验证码互不影响，也不能混用
+type Target struct {
+	// Channel 发送渠道，如 email、sms
+	Channel string
+	Address string
+	// Purpose 用途，如 login、register、reset_password
+	Purpose string
+}
+
+func (t Target) key() string {
+	return t.Channel + ":" + t.Purpose + ":" + t.Address
+}
+
+// Store 保存验证码哈希、失败次数、冷却和锁定状态，Check 必须是原子的
+type Store interface {
+	// Save 锁定期内返回 ErrLocked，冷却期内返回 ErrCooldown，否则覆盖旧验证码并重置失败次数
+	Save(ctx context.Context, key string, hash string, ttl time.Duration, cooldown time.Duration) error
+	// Check 一致时删除验证码；不一致时失败次数加一，达到 maxAttempts 后删除验证码并锁定 lockout
+	Check(ctx context.Context, key string, hash string, maxAttempts int, lockout time.Duration) error
+	// Delete 删除验证码和冷却状态，不解除锁定
+	Delete(ctx context.Context, key string) error
+}
+
+type Manager struct {
+	store Store
+	opts  Options
+}
+
+func New(store Store, opts Options) *Manager {
+	if opts.Length <= 0 {
+		opts.Length = defaultLength
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = defaultTTL
+	}
+	if opts.Cooldown < 0 {
+		opts.Cooldown = 0
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultMaxAttempts
+	}
+	if opts.Lockout <= 0 {
+		opts.Lockout = defaultLockout
+	}
+	return &Manager{store: store, opts: opts}
+}
+
+// NewManager 读取 verify_code 配置，backend 为 memory 时只适用于单实例
+func NewManager(conf *viper.Viper, rdb *redis.Client) *Manager {
+	var store Store
+	if conf.GetString("verify_code.backend") == "memory" {
+		store = NewMemoryStore()
+	} else {
+		store = NewRedisStore(rdb, conf.GetString("verify_code.prefix"))
+	}
+	cooldown := defaultCooldown
+	if conf.IsSet("verify_code.resend_interval") {
+		cooldown = conf.GetDuration("verify_code.resend_interval")
+	}
+	return New(store, Options{
+		Length:      conf.GetInt("verify_code.length"),
+		TTL:         conf.GetDuration("verify_code.ttl"),
+		Cooldown:    cooldown,
+		MaxAttempts: conf.GetInt("verify_code.max_attempts"),
+		Lockout:     conf.GetDuration("verify_code.lockout"),
+	})
+}
+
+func (m *Manager) TTL() time.Duration {
+	return m.opts.TTL
+}
+
+// Issue 生成新的验证码，由调用方发送给用户
+func (m *Manager) Issue(ctx context.Context, target Target) (string, error) {
+	code, err := randomDigits(m.opts.Length)
+	if err != nil {
+		return "", err
+	}
+	if err = m.store.Save(ctx, target.key(), hashCode(code), m.opts.TTL, m.opts.Cooldown); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// Verify 验证通过后验证码立即作废，不能重复使用
+func (m *Manager) Verify(ctx context.Context, target Target, code string) error {
+	if code == "" {
+		return ErrMismatch
+	}
+	return m.store.Check(ctx, target.key(), hashCode(code), m.opts.MaxAttempts, m.opts.Lockout)
+}
+
+// Invalidate 作废未使用的验证码并清除冷却，用于发送失败等情况
+func (m *Manager) Invalidate(ctx context.Context, target Target) error {
+	return m.store.Delete(ctx, target.key())
+}
+
+func randomDigits(n int) (string, error) {
+	b := make([]byte, n)
+	for i := range b {
+		d, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		b[i] = byte('0' + d.Int64())
+	}
+	return string(b), nil
+}
+
+// hashCode 存储中只保存哈希，避免验证码明文出现在 Redis 的查询结果和备份中。
+// 验证码的取值空间很小，哈希可以被穷举还原，安全性仍依赖有效期和失败次数限制
+func hashCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
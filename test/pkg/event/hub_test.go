@@ -0,0 +1,103 @@
+package event
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go-chat/pkg/event"
+)
+
+const (
+	alice uint = 1
+	bob   uint = 2
+)
+
+func TestHub_PublishSubscribe(t *testing.T) {
+	hub := event.NewHub()
+	sub := hub.Subscribe(alice)
+	defer sub.Close()
+	other := hub.Subscribe(bob)
+	defer other.Close()
+
+	hub.Publish(alice, &event.Event{Type: "ping", Payload: []byte(`{}`)})
+
+	e := <-sub.C
+	assert.Equal(t, "ping", e.Type)
+	assert.NotEmpty(t, e.ID)
+	assert.False(t, e.CreatedAt.IsZero())
+	assert.Len(t, other.C, 0)
+}
+
+func TestHub_SubscribeSince(t *testing.T) {
+	hub := event.NewHub()
+	first := &event.Event{Type: "a"}
+	hub.Publish(alice, first)
+	hub.Publish(alice, &event.Event{Type: "b"})
+	hub.Publish(alice, &event.Event{Type: "c"})
+
+	sub, backlog := hub.SubscribeSince(alice, first.ID)
+	defer sub.Close()
+	if assert.Len(t, backlog, 2) {
+		assert.Equal(t, "b", backlog[0].Type)
+		assert.Equal(t, "c", backlog[1].Type)
+	}
+
+	// 不携带 Last-Event-ID 时不补发
+	assert.Empty(t, hub.Since(alice, ""))
+	assert.Empty(t, hub.Since(bob, first.ID))
+}
+
+func TestHub_Close(t *testing.T) {
+	hub := event.NewHub()
+	sub := hub.Subscribe(alice)
+	sub.Close()
+	sub.Close()
+
+	_, ok := <-sub.C
+	assert.False(t, ok)
+	hub.Publish(alice, &event.Event{Type: "a"})
+}
+
+func TestHub_CloseSession(t *testing.T) {
+	hub := event.NewHub()
+	phone, _ := hub.SubscribeSession(alice, "phone", "")
+	defer phone.Close()
+	laptop, _ := hub.SubscribeSession(alice, "laptop", "")
+	defer laptop.Close()
+
+	hub.Publish(alice, &event.Event{Type: "session.revoked"})
+	hub.CloseSession(alice, "phone")
+
+	// 关闭前已投递的事件仍能读到
+	e, ok := <-phone.C
+	if assert.True(t, ok) {
+		assert.Equal(t, "session.revoked", e.Type)
+	}
+	_, ok = <-phone.C
+	assert.False(t, ok)
+
+	hub.Publish(alice, &event.Event{Type: "a"})
+	assert.Len(t, laptop.C, 2)
+
+	hub.CloseUser(alice)
+	for range laptop.C {
+	}
+}
+
+func TestHub_HeadAndPrune(t *testing.T) {
+	hub := event.NewHub()
+	assert.Equal(t, "0", hub.Head())
+	hub.Publish(alice, &event.Event{Type: "a"})
+
+	// 以 Head 为起点，之后发布的事件都能补发
+	head := hub.Head()
+	hub.Publish(alice, &event.Event{Type: "b"})
+	if events := hub.Since(alice, head); assert.Len(t, events, 1) {
+		assert.Equal(t, "b", events[0].Type)
+	}
+
+	// 没有订阅者的用户也会被定时清理
+	hub.Prune(time.Now().Add(10 * time.Minute))
+	assert.Empty(t, hub.Since(alice, "0"))
+}
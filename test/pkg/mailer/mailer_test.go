@@ -0,0 +1,85 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-chat/pkg/mailer"
+)
+
+func TestDefaultTemplatesLocale(t *testing.T) {
+	templates := mailer.NewDefaultTemplates("zh")
+	data := map[string]interface{}{"Code": "123456", "TTLMinutes": 5}
+
+	en, err := templates.Render("login_code", "en-US", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Your go-chat sign-in code", en.Subject)
+	assert.Contains(t, en.Text, "123456")
+	assert.Contains(t, en.HTML, "123456")
+
+	// 没有对应语言时回退到默认语言
+	fallback, err := templates.Render("login_code", "fr", data)
+	require.NoError(t, err)
+	assert.Equal(t, "go-chat 登录验证码", fallback.Subject)
+
+	_, err = templates.Render("missing", "zh", data)
+	assert.ErrorIs(t, err, mailer.ErrTemplateNotFound)
+}
+
+func TestRenderEscaping(t *testing.T) {
+	fsys := fstest.MapFS{
+		"zh/hello.tmpl": {Data: []byte(`{{define "subject"}}你好 {{.Name}}{{end}}{{define "text"}}{{.Name}}{{end}}{{define "html"}}<p>{{.Name}}</p>{{end}}`)},
+	}
+	templates := mailer.NewTemplates(fsys, "zh")
+
+	rendered, err := templates.Render("hello", "", map[string]string{"Name": "<b>x</b>\r\nBcc: a@example.com"})
+	require.NoError(t, err)
+	assert.NotContains(t, rendered.Subject, "\n")
+	assert.NotContains(t, rendered.HTML, "<b>")
+	assert.Contains(t, rendered.HTML, "&lt;b&gt;")
+}
+
+func TestOutboxTransport(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+	transport, err := mailer.NewOutboxTransport(dir)
+	require.NoError(t, err)
+	m := mailer.New(transport, mailer.NewDefaultTemplates("zh"), "go-chat <no-reply@go-chat.local>")
+
+	err = m.Send(context.Background(), "alice@example.com", "register_code", "zh-CN", map[string]interface{}{"Code": "654321", "TTLMinutes": 5})
+	require.NoError(t, err)
+	assert.ErrorIs(t, m.Send(context.Background(), "", "register_code", "zh", nil), mailer.ErrNoRecipient)
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.True(t, strings.HasSuffix(files[0].Name(), ".eml"))
+	raw, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), "To: <alice@example.com>")
+	assert.Contains(t, string(raw), "654321")
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, mailer.Backoff(1, 30*time.Second, time.Hour))
+	assert.Equal(t, 2*time.Minute, mailer.Backoff(3, 30*time.Second, time.Hour))
+	assert.Equal(t, time.Hour, mailer.Backoff(10, 30*time.Second, time.Hour))
+	assert.Equal(t, time.Hour, mailer.Backoff(100, 30*time.Second, time.Hour))
+}
+
+func TestIsPermanent(t *testing.T) {
+	assert.True(t, mailer.IsPermanent(mailer.ErrNoRecipient))
+	assert.True(t, mailer.IsPermanent(fmt.Errorf("render: %w", mailer.ErrTemplateNotFound)))
+	assert.True(t, mailer.IsPermanent(&textproto.Error{Code: 550, Msg: "mailbox unavailable"}))
+	assert.False(t, mailer.IsPermanent(&textproto.Error{Code: 451, Msg: "try again later"}))
+	assert.False(t, mailer.IsPermanent(errors.New("connection reset")))
+}
@@ -0,0 +1,108 @@
+package moderation
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go-chat/pkg/log"
+	"go-chat/pkg/moderation"
+)
+
+func TestMatcher_FindAll(t *testing.T) {
+	m := moderation.NewMatcher([]string{"he", "she", "his", "hers", "敏感"})
+
+	matches := m.FindAll("uSHErs 有敏感词")
+	var found []string
+	patterns := []string{"he", "she", "his", "hers", "敏感"}
+	for _, match := range matches {
+		found = append(found, patterns[match.Pattern])
+	}
+	assert.ElementsMatch(t, []string{"she", "he", "hers", "敏感"}, found)
+
+	for _, match := range matches {
+		if patterns[match.Pattern] == "敏感" {
+			assert.Equal(t, 8, match.Start)
+			assert.Equal(t, 10, match.End)
+		}
+	}
+	assert.Empty(t, m.FindAll("quiet town"))
+}
+
+func newFilter(t *testing.T, interval time.Duration) (*moderation.Filter, string) {
+	dir := t.TempDir()
+	write(t, filepath.Join(dir, "block.txt"), "# comment\nbadword\n")
+	write(t, filepath.Join(dir, "mask.txt"), "darn\n")
+	write(t, filepath.Join(dir, "flag.txt"), "suspicious\n")
+
+	conf := viper.New()
+	conf.Set("log.log_file_name", filepath.Join(dir, "test.log"))
+	conf.Set("moderation.reload_interval", interval)
+	conf.Set("moderation.lists", []map[string]interface{}{
+		{"file": filepath.Join(dir, "block.txt"), "action": "block"},
+		{"file": filepath.Join(dir, "mask.txt"), "action": "mask"},
+		{"file": filepath.Join(dir, "flag.txt"), "action": "flag"},
+	})
+	f, err := moderation.NewFilter(conf, log.NewLog(conf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f, dir
+}
+
+func write(t *testing.T, file string, content string) {
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFilter_Check(t *testing.T) {
+	f, _ := newFilter(t, time.Hour)
+
+	res := f.Check("hello")
+	assert.Equal(t, moderation.ActionPass, res.Action)
+	assert.Equal(t, "hello", res.Text)
+
+	res = f.Check("oh Darn it")
+	assert.Equal(t, moderation.ActionMask, res.Action)
+	assert.Equal(t, "oh **** it", res.Text)
+
+	res = f.Check("darn, suspicious")
+	assert.Equal(t, moderation.ActionFlag, res.Action)
+	assert.ElementsMatch(t, []string{"darn", "suspicious"}, res.Words)
+
+	res = f.Check("darn BADWORD")
+	assert.Equal(t, moderation.ActionBlock, res.Action)
+}
+
+func TestNewFilter_UnknownAction(t *testing.T) {
+	dir := t.TempDir()
+	write(t, filepath.Join(dir, "block.txt"), "badword\n")
+
+	conf := viper.New()
+	conf.Set("log.log_file_name", filepath.Join(dir, "test.log"))
+	conf.Set("moderation.lists", []map[string]interface{}{
+		{"file": filepath.Join(dir, "block.txt"), "action": "blokc"},
+	})
+	f, err := moderation.NewFilter(conf, log.NewLog(conf))
+	assert.Nil(t, f)
+	assert.ErrorContains(t, err, `unknown action "blokc"`)
+}
+
+func TestFilter_HotReload(t *testing.T) {
+	f, dir := newFilter(t, time.Millisecond)
+	assert.Equal(t, moderation.ActionPass, f.Check("newword").Action)
+
+	file := filepath.Join(dir, "block.txt")
+	write(t, file, "newword\n")
+	// 保证修改时间变化
+	later := time.Now().Add(time.Second)
+	assert.NoError(t, os.Chtimes(file, later, later))
+	time.Sleep(5 * time.Millisecond)
+
+	assert.Equal(t, moderation.ActionBlock, f.Check("newword").Action)
+	assert.Equal(t, moderation.ActionPass, f.Check("badword").Action)
+}
@@ -0,0 +1,233 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-chat/pkg/oidc"
+)
+
+const (
+	clientId     = "go-chat"
+	clientSecret = "secret"
+	redirectURL  = "https://chat.example.com/oidc/callback"
+)
+
+// mockProvider 进程内的 OIDC 提供方，authorize 直接签发授权码，不经过登录页
+type mockProvider struct {
+	*httptest.Server
+	t   *testing.T
+	key *rsa.PrivateKey
+	kid string
+
+	mu    sync.Mutex
+	codes map[string]authRequest
+	// claims 签发 id_token 时写入的额外字段
+	claims jwt.MapClaims
+}
+
+type authRequest struct {
+	challenge string
+	nonce     string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	p := &mockProvider{t: t, key: key, kid: "k1", codes: map[string]authRequest{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{
+			"issuer":                 p.URL,
+			"authorization_endpoint": p.URL + "/authorize",
+			"token_endpoint":         p.URL + "/token",
+			"jwks_uri":               p.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": p.kid,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", p.token)
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+// authorize 模拟用户在提供方登录并同意授权，返回回调中的 code
+func (p *mockProvider) authorize(authURL string) string {
+	u, err := url.Parse(authURL)
+	require.NoError(p.t, err)
+	q := u.Query()
+	require.Equal(p.t, clientId, q.Get("client_id"))
+	require.Equal(p.t, "S256", q.Get("code_challenge_method"))
+
+	code := "code-" + q.Get("state")
+	p.mu.Lock()
+	p.codes[code] = authRequest{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	p.mu.Unlock()
+	return code
+}
+
+func (p *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	id, secret, ok := r.BasicAuth()
+	if !ok || id != clientId || secret != clientSecret {
+		http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+		return
+	}
+	p.mu.Lock()
+	req, ok := p.codes[r.PostFormValue("code")]
+	delete(p.codes, r.PostFormValue("code"))
+	p.mu.Unlock()
+	if !ok || oidc.CodeChallenge(r.PostFormValue("code_verifier")) != req.challenge {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     p.sign(jwt.SigningMethodRS256, p.key, req.nonce),
+	})
+}
+
+func (p *mockProvider) sign(method jwt.SigningMethod, key interface{}, nonce string) string {
+	claims := jwt.MapClaims{
+		"iss":            p.URL,
+		"sub":            "user-1",
+		"aud":            clientId,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          nonce,
+		"email":          "alice@example.com",
+		"email_verified": true,
+	}
+	for k, v := range p.claims {
+		claims[k] = v
+	}
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = p.kid
+	signed, err := token.SignedString(key)
+	require.NoError(p.t, err)
+	return signed
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func newClient(p *mockProvider) *oidc.Client {
+	return oidc.NewClient(oidc.Config{
+		Name:         "mock",
+		Issuer:       p.URL,
+		ClientId:     clientId,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+	}, p.Client())
+}
+
+func TestClient_AuthorizationCodeFlow(t *testing.T) {
+	ctx := context.Background()
+	provider := newMockProvider(t)
+	client := newClient(provider)
+
+	verifier, err := oidc.NewVerifier()
+	require.NoError(t, err)
+	authURL, err := client.AuthCodeURL(ctx, "state1", "nonce1", verifier)
+	require.NoError(t, err)
+	q, _ := url.ParseQuery(authURL[len(provider.URL+"/authorize?"):])
+	assert.Equal(t, redirectURL, q.Get("redirect_uri"))
+	assert.Equal(t, "openid email profile", q.Get("scope"))
+
+	code := provider.authorize(authURL)
+	token, err := client.Exchange(ctx, code, verifier)
+	require.NoError(t, err)
+	claims, err := client.VerifyIDToken(ctx, token.IDToken, "nonce1")
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", claims.Subject)
+	assert.Equal(t, "alice@example.com", claims.Email)
+	assert.True(t, claims.EmailVerified)
+
+	// 授权码只能使用一次
+	_, err = client.Exchange(ctx, code, verifier)
+	assert.ErrorIs(t, err, oidc.ErrExchange)
+}
+
+func TestClient_Exchange_WrongVerifier(t *testing.T) {
+	ctx := context.Background()
+	provider := newMockProvider(t)
+	client := newClient(provider)
+
+	verifier, _ := oidc.NewVerifier()
+	authURL, err := client.AuthCodeURL(ctx, "state1", "nonce1", verifier)
+	require.NoError(t, err)
+	other, _ := oidc.NewVerifier()
+	_, err = client.Exchange(ctx, provider.authorize(authURL), other)
+	assert.ErrorIs(t, err, oidc.ErrExchange)
+}
+
+func TestClient_VerifyIDToken_Rejects(t *testing.T) {
+	ctx := context.Background()
+	provider := newMockProvider(t)
+	client := newClient(provider)
+
+	// nonce 不一致
+	_, err := client.VerifyIDToken(ctx, provider.sign(jwt.SigningMethodRS256, provider.key, "nonce1"), "nonce2")
+	assert.ErrorIs(t, err, oidc.ErrInvalidToken)
+
+	// 其他客户端的 token
+	provider.claims = jwt.MapClaims{"aud": "other"}
+	_, err = client.VerifyIDToken(ctx, provider.sign(jwt.SigningMethodRS256, provider.key, "n"), "n")
+	assert.ErrorIs(t, err, oidc.ErrInvalidToken)
+
+	// 已过期
+	provider.claims = jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}
+	_, err = client.VerifyIDToken(ctx, provider.sign(jwt.SigningMethodRS256, provider.key, "n"), "n")
+	assert.ErrorIs(t, err, oidc.ErrInvalidToken)
+	provider.claims = nil
+
+	// 不接受 HS256，防止用公钥当 HMAC 密钥伪造
+	_, err = client.VerifyIDToken(ctx, provider.sign(jwt.SigningMethodHS256, []byte("secret"), "n"), "n")
+	assert.ErrorIs(t, err, oidc.ErrInvalidToken)
+
+	// 不在 JWKS 中的密钥
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	provider.kid = "k2"
+	_, err = client.VerifyIDToken(ctx, provider.sign(jwt.SigningMethodRS256, otherKey, "n"), "n")
+	assert.ErrorIs(t, err, oidc.ErrInvalidToken)
+}
+
+func TestClient_Discovery_NotFound(t *testing.T) {
+	provider := newMockProvider(t)
+	// issuer 下没有发现文档
+	client := oidc.NewClient(oidc.Config{
+		Name:        "mock",
+		Issuer:      provider.URL + "/other",
+		ClientId:    clientId,
+		RedirectURL: redirectURL,
+	}, provider.Client())
+	_, err := client.AuthCodeURL(context.Background(), "s", "n", "v")
+	assert.ErrorIs(t, err, oidc.ErrDiscovery)
+}
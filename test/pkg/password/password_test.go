@@ -0,0 +1,67 @@
+package password
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go-chat/pkg/password"
+)
+
+// 测试使用较小的参数，避免拖慢测试
+func newManager() *password.Manager {
+	return password.New(password.NewArgon2id(1024, 1, 1), password.NewBcrypt(4))
+}
+
+func TestManager_HashVerify(t *testing.T) {
+	m := newManager()
+	hash, err := m.Hash("123456")
+	assert.NoError(t, err)
+	assert.Contains(t, hash, "$argon2id$")
+
+	other, _ := m.Hash("123456")
+	assert.NotEqual(t, hash, other, "每次哈希使用不同的随机盐")
+
+	ok, rehash, err := m.Verify("123456", hash)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, rehash)
+
+	ok, _, err = m.Verify("654321", hash)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestManager_Legacy(t *testing.T) {
+	m := newManager()
+	// 旧版 common.SaltPassWord("123456", "lxl") 的结果
+	legacy := "e10adc3949ba59abbe56e057f20f883e$lxl"
+
+	ok, rehash, err := m.Verify("123456", legacy)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, rehash)
+
+	ok, _, _ = m.Verify("654321", legacy)
+	assert.False(t, ok)
+}
+
+func TestManager_Rehash(t *testing.T) {
+	bc := password.NewBcrypt(4)
+	hash, err := bc.Hash("123456")
+	assert.NoError(t, err)
+
+	// 切换算法后旧算法的哈希仍可校验，并提示重新哈希
+	ok, rehash, err := newManager().Verify("123456", hash)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, rehash)
+
+	// 参数调整后同样需要重新哈希
+	weak, _ := password.NewArgon2id(512, 1, 1).Hash("123456")
+	ok, rehash, _ = newManager().Verify("123456", weak)
+	assert.True(t, ok)
+	assert.True(t, rehash)
+
+	_, _, err = newManager().Verify("123456", "$unknown$abc")
+	assert.ErrorIs(t, err, password.ErrUnknownHash)
+}
@@ -0,0 +1,44 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go-chat/pkg/ratelimit"
+)
+
+func TestMemoryLimiter_Allow(t *testing.T) {
+	limiter := ratelimit.NewMemoryLimiter()
+	ctx := context.Background()
+	limit := ratelimit.Limit{Rate: 10, Period: time.Second, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		res, err := limiter.Allow(ctx, "k", limit)
+		assert.NoError(t, err)
+		assert.True(t, res.Allowed)
+	}
+
+	res, err := limiter.Allow(ctx, "k", limit)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.True(t, res.RetryAfter > 0 && res.RetryAfter <= 100*time.Millisecond)
+
+	// 其他 key 不受影响
+	res, err = limiter.Allow(ctx, "other", limit)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	time.Sleep(120 * time.Millisecond)
+	res, err = limiter.Allow(ctx, "k", limit)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+}
+
+func TestMemoryLimiter_InvalidLimit(t *testing.T) {
+	limiter := ratelimit.NewMemoryLimiter()
+	res, err := limiter.Allow(context.Background(), "k", ratelimit.Limit{})
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+}
@@ -0,0 +1,75 @@
+package rsakey
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-chat/pkg/rsakey"
+)
+
+func newKey(t *testing.T, id string) *rsakey.Key {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return &rsakey.Key{ID: id, PrivateKey: privateKey}
+}
+
+func TestDecrypt(t *testing.T) {
+	oldKey, newKey := newKey(t, "k1"), newKey(t, "k2")
+	provider, err := rsakey.NewStaticProvider("k2", oldKey, newKey)
+	require.NoError(t, err)
+	assert.Equal(t, "k2", provider.Current().ID)
+
+	// OAEP + 指定密钥
+	ciphertext, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, &newKey.PrivateKey.PublicKey, []byte("123456"), nil)
+	require.NoError(t, err)
+	plain, err := rsakey.Decrypt(provider, "oaep:k2:"+base64.StdEncoding.EncodeToString(ciphertext))
+	assert.NoError(t, err)
+	assert.Equal(t, "123456", plain)
+
+	// 轮换后旧密钥仍可解密
+	ciphertext, err = rsa.EncryptPKCS1v15(rand.Reader, &oldKey.PrivateKey.PublicKey, []byte("abc"))
+	require.NoError(t, err)
+	encoded := base64.StdEncoding.EncodeToString(ciphertext)
+	plain, err = rsakey.Decrypt(provider, "pkcs1:k1:"+encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", plain)
+
+	// 不带前缀时使用当前密钥
+	_, err = rsakey.Decrypt(provider, encoded)
+	assert.ErrorIs(t, err, rsakey.ErrDecrypt)
+
+	_, err = rsakey.Decrypt(provider, "oaep:k3:"+encoded)
+	assert.ErrorIs(t, err, rsakey.ErrKeyNotFound)
+	_, err = rsakey.Decrypt(provider, "rsa:k1:"+encoded)
+	assert.ErrorIs(t, err, rsakey.ErrInvalidCipher)
+}
+
+func TestNewProvider(t *testing.T) {
+	key := newKey(t, "")
+	der, err := x509.MarshalPKCS8PrivateKey(key.PrivateKey)
+	require.NoError(t, err)
+	t.Setenv("TEST_RSA_KEY", string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})))
+
+	conf := viper.New()
+	conf.Set("security.rsa.keys", []map[string]interface{}{{"id": "env", "env": "TEST_RSA_KEY"}})
+	provider, err := rsakey.NewProvider(conf)
+	require.NoError(t, err)
+	assert.Equal(t, "env", provider.Current().ID)
+	assert.True(t, provider.Current().PrivateKey.Equal(key.PrivateKey))
+
+	publicKey, err := provider.Current().PublicKeyPEM()
+	assert.NoError(t, err)
+	assert.Contains(t, publicKey, "BEGIN PUBLIC KEY")
+
+	conf.Set("security.rsa.current", "missing")
+	_, err = rsakey.NewProvider(conf)
+	assert.Error(t, err)
+}
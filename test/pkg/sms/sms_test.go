@@ -0,0 +1,54 @@
+package sms
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-chat/pkg/sms"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		"13800138000":       "+8613800138000",
+		"138 0013 8000":     "+8613800138000",
+		"+86 138-0013-8000": "+8613800138000",
+		"0044 20 7946 0958": "+442079460958",
+		"+1 (415) 555-2671": "+14155552671",
+	}
+	for in, want := range cases {
+		got, err := sms.Normalize(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+
+	for _, in := range []string{"", "12345", "23800138000", "+0123456789", "+1234567890123456", "1380013800a", "138+00138000"} {
+		_, err := sms.Normalize(in)
+		assert.ErrorIs(t, err, sms.ErrInvalidPhone, in)
+	}
+}
+
+func TestMask(t *testing.T) {
+	assert.Equal(t, "+86138****8000", sms.Mask("+8613800138000"))
+	assert.Equal(t, "****", sms.Mask("1234"))
+}
+
+func TestFileSender(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sms", "outbox.log")
+	sender, err := sms.NewFileSender(path)
+	require.NoError(t, err)
+
+	require.NoError(t, sender.Send(context.Background(), "+8613800138000", "code 123456"))
+	require.NoError(t, sender.Send(context.Background(), "+8613800138001", "line1\nline2"))
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	require.Len(t, lines, 2)
+	assert.True(t, strings.HasSuffix(lines[0], "\t+8613800138000\tcode 123456"))
+	assert.True(t, strings.HasSuffix(lines[1], "\tline1 line2"))
+}
@@ -0,0 +1,144 @@
+package verifycode
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-chat/pkg/verifycode"
+)
+
+var loginTarget = verifycode.Target{Channel: "email", Address: "a@example.com", Purpose: "login"}
+
+func newManager(store verifycode.Store) *verifycode.Manager {
+	return verifycode.New(store, verifycode.Options{
+		TTL:         5 * time.Minute,
+		Cooldown:    time.Minute,
+		MaxAttempts: 3,
+		Lockout:     10 * time.Minute,
+	})
+}
+
+func TestManager_SingleUse(t *testing.T) {
+	ctx := context.Background()
+	m := newManager(verifycode.NewMemoryStore())
+
+	code, err := m.Issue(ctx, loginTarget)
+	require.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^\d{6}$`), code)
+
+	// 其他用途不能使用
+	reset := loginTarget
+	reset.Purpose = "reset_password"
+	assert.ErrorIs(t, m.Verify(ctx, reset, code), verifycode.ErrMismatch)
+
+	assert.NoError(t, m.Verify(ctx, loginTarget, code))
+	assert.ErrorIs(t, m.Verify(ctx, loginTarget, code), verifycode.ErrMismatch)
+	assert.ErrorIs(t, m.Verify(ctx, loginTarget, ""), verifycode.ErrMismatch)
+}
+
+func TestManager_Cooldown(t *testing.T) {
+	ctx := context.Background()
+	store := verifycode.NewMemoryStore()
+	now := time.Now()
+	store.SetClock(func() time.Time { return now })
+	m := newManager(store)
+
+	first, err := m.Issue(ctx, loginTarget)
+	require.NoError(t, err)
+	_, err = m.Issue(ctx, loginTarget)
+	assert.ErrorIs(t, err, verifycode.ErrCooldown)
+
+	// 冷却结束后重新发送，旧验证码失效
+	now = now.Add(61 * time.Second)
+	second, err := m.Issue(ctx, loginTarget)
+	require.NoError(t, err)
+	if first != second {
+		assert.ErrorIs(t, m.Verify(ctx, loginTarget, first), verifycode.ErrMismatch)
+	}
+	assert.NoError(t, m.Verify(ctx, loginTarget, second))
+
+	// 发送失败时作废，可以立即重发
+	now = now.Add(61 * time.Second)
+	_, err = m.Issue(ctx, loginTarget)
+	require.NoError(t, err)
+	require.NoError(t, m.Invalidate(ctx, loginTarget))
+	_, err = m.Issue(ctx, loginTarget)
+	assert.NoError(t, err)
+}
+
+func TestManager_Lockout(t *testing.T) {
+	ctx := context.Background()
+	store := verifycode.NewMemoryStore()
+	now := time.Now()
+	store.SetClock(func() time.Time { return now })
+	m := newManager(store)
+
+	code, err := m.Issue(ctx, loginTarget)
+	require.NoError(t, err)
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	assert.ErrorIs(t, m.Verify(ctx, loginTarget, wrong), verifycode.ErrMismatch)
+	assert.ErrorIs(t, m.Verify(ctx, loginTarget, wrong), verifycode.ErrMismatch)
+	assert.ErrorIs(t, m.Verify(ctx, loginTarget, wrong), verifycode.ErrLocked)
+
+	// 锁定后正确的验证码也不能使用，也不能重新发送
+	assert.ErrorIs(t, m.Verify(ctx, loginTarget, code), verifycode.ErrLocked)
+	now = now.Add(2 * time.Minute)
+	_, err = m.Issue(ctx, loginTarget)
+	assert.ErrorIs(t, err, verifycode.ErrLocked)
+
+	now = now.Add(10 * time.Minute)
+	code, err = m.Issue(ctx, loginTarget)
+	require.NoError(t, err)
+	assert.NoError(t, m.Verify(ctx, loginTarget, code))
+}
+
+func TestManager_Expired(t *testing.T) {
+	ctx := context.Background()
+	store := verifycode.NewMemoryStore()
+	now := time.Now()
+	store.SetClock(func() time.Time { return now })
+	m := newManager(store)
+
+	code, err := m.Issue(ctx, loginTarget)
+	require.NoError(t, err)
+	now = now.Add(5 * time.Minute)
+	assert.ErrorIs(t, m.Verify(ctx, loginTarget, code), verifycode.ErrMismatch)
+}
+
+func TestMemoryStore_SweepKeepsActiveEntries(t *testing.T) {
+	ctx := context.Background()
+	store := verifycode.NewMemoryStore()
+	now := time.Now()
+	store.SetClock(func() time.Time { return now })
+	m := newManager(store)
+
+	other := loginTarget
+	other.Address = "b@example.com"
+	_, err := m.Issue(ctx, other)
+	require.NoError(t, err)
+	code, err := m.Issue(ctx, loginTarget)
+	require.NoError(t, err)
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	for i := 0; i < 3; i++ {
+		_ = m.Verify(ctx, loginTarget, wrong)
+	}
+
+	// 清理过期的冷却和验证码，仍在锁定期内的记录保留
+	now = now.Add(6 * time.Minute)
+	code, err = m.Issue(ctx, other)
+	require.NoError(t, err)
+	assert.ErrorIs(t, m.Verify(ctx, loginTarget, wrong), verifycode.ErrLocked)
+	_, err = m.Issue(ctx, loginTarget)
+	assert.ErrorIs(t, err, verifycode.ErrLocked)
+	assert.NoError(t, m.Verify(ctx, other, code))
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -32,6 +33,12 @@ var hdl *handler.Handler
 var jwt *jwt2.JWT
 var router *gin.Engine
 
+type noRevocation struct{}
+
+func (noRevocation) IsRevoked(ctx context.Context, claims *jwt2.MyCustomClaims) (bool, error) {
+	return false, nil
+}
+
 func TestMain(m *testing.M) {
 	fmt.Println("begin")
 	err := os.Setenv("APP_CONF", "../../../config/local.yml")
@@ -135,14 +142,13 @@ func TestUserHandler_UpdateProfile(t *testing.T) {
 
 	params := v1.UpdateProfileRequest{
 		Nickname: "alan",
-		Email:    "alan@gmail.com",
 	}
 
 	mockUserService := mock_service.NewMockUserService(ctrl)
 	mockUserService.EXPECT().UpdateProfile(gomock.Any(), userId, &params).Return(nil)
 
 	userHandler := handler.NewUserHandler(hdl, mockUserService)
-	router.Use(middleware.StrictAuth(jwt, logger))
+	router.Use(middleware.StrictAuth(jwt, noRevocation{}, logger))
 	router.PUT("/user", userHandler.UpdateProfile)
 	paramsJson, _ := json.Marshal(params)
 
@@ -164,7 +170,7 @@ func performRequest(r http.Handler, method, path string, body *bytes.Buffer) *ht
 	return resp
 }
 func genToken(t *testing.T) string {
-	token, err := jwt.GenToken(userId, time.Now().Add(time.Hour*24*90))
+	token, err := jwt.GenToken(userId, 1, "", "", time.Now().Add(time.Hour*24*90))
 	if err != nil {
 		t.Error(err)
 		return token
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go-chat/internal/middleware"
+	"go-chat/pkg/clientinfo"
+	"go-chat/pkg/log"
+)
+
+func TestClientInfoAndTrace(t *testing.T) {
+	conf := viper.New()
+	conf.Set("log.log_file_name", filepath.Join(t.TempDir(), "test.log"))
+	logger := log.NewLog(conf)
+
+	var (
+		info  *clientinfo.Info
+		trace string
+	)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.RequestLogMiddleware(logger), middleware.ClientInfo())
+	router.GET("/", func(ctx *gin.Context) {
+		// 模拟 repository.Transaction 在 gin.Context 外再包一层
+		txCtx := context.WithValue(ctx, "TxKey", struct{}{})
+		info = clientinfo.FromContext(txCtx)
+		trace = log.TraceFromContext(txCtx)
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("User-Agent", "go-chat-test")
+	req.Header.Set(clientinfo.DeviceInfoHeader, "iPhone 15")
+	req.Header.Set("Accept-Language", "en-US;q=0.9, zh-CN;q=0.8")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "10.0.0.1", info.IP)
+	assert.Equal(t, "go-chat-test", info.UserAgent)
+	assert.Equal(t, "iPhone 15", info.DeviceInfo)
+	assert.Equal(t, "en-US", info.Locale)
+	assert.Len(t, trace, 32)
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go-chat/internal/middleware"
+	"go-chat/pkg/jwt"
+	"go-chat/pkg/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const grpcPublicMethod = "/api.v1.UserService/Login"
+
+func newGrpcTestDeps(t *testing.T) (*jwt.JWT, *log.Logger) {
+	conf := viper.New()
+	conf.Set("security.jwt.key", "test")
+	conf.Set("log.log_file_name", filepath.Join(t.TempDir(), "test.log"))
+	return jwt.NewJwt(conf), log.NewLog(conf)
+}
+
+func withToken(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+}
+
+func TestGrpcUnaryAuth(t *testing.T) {
+	j, logger := newGrpcTestDeps(t)
+	interceptor := middleware.GrpcUnaryAuth(j, revokedUsers{"bob": true, "t3": true}, logger, grpcPublicMethod)
+
+	call := func(ctx context.Context, method string) (*jwt.MyCustomClaims, error) {
+		var claims *jwt.MyCustomClaims
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			claims, _ = jwt.FromContext(ctx)
+			return nil, nil
+		})
+		return claims, err
+	}
+
+	alice, _ := j.GenToken("alice", 1, "st1", "t1", time.Now().Add(time.Hour))
+	expired, _ := j.GenToken("alice", 1, "st1", "t2", time.Now().Add(-time.Minute))
+	bob, _ := j.GenToken("bob", 2, "st2", "t4", time.Now().Add(time.Hour))
+	revoked, _ := j.GenToken("alice", 1, "st3", "t3", time.Now().Add(time.Hour))
+
+	claims, err := call(withToken(alice), "/api.v1.UserService/Me")
+	if assert.NoError(t, err) && assert.NotNil(t, claims) {
+		assert.Equal(t, "alice", claims.UserId)
+	}
+
+	// 公开接口不需要 token
+	_, err = call(context.Background(), grpcPublicMethod)
+	assert.NoError(t, err)
+
+	for name, ctx := range map[string]context.Context{
+		"missing": context.Background(),
+		"empty":   metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "")),
+		"invalid": withToken("not-a-token"),
+		"expired": withToken(expired),
+		"user":    withToken(bob),
+		"token":   withToken(revoked),
+	} {
+		_, err = call(ctx, "/api.v1.UserService/Me")
+		assert.Equal(t, codes.Unauthenticated, status.Code(err), name)
+	}
+}
+
+// fakeServerStream 只提供 Context，用于直接调用流式拦截器
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestGrpcStreamAuth(t *testing.T) {
+	j, logger := newGrpcTestDeps(t)
+	interceptor := middleware.GrpcStreamAuth(j, revokedUsers{"t3": true}, logger)
+	info := &grpc.StreamServerInfo{FullMethod: "/api.v1.ChatService/Connect", IsClientStream: true, IsServerStream: true}
+
+	call := func(ctx context.Context) (*jwt.MyCustomClaims, error) {
+		var claims *jwt.MyCustomClaims
+		err := interceptor(nil, &fakeServerStream{ctx: ctx}, info, func(srv interface{}, stream grpc.ServerStream) error {
+			claims, _ = jwt.FromContext(stream.Context())
+			return nil
+		})
+		return claims, err
+	}
+
+	alice, _ := j.GenToken("alice", 1, "st1", "t1", time.Now().Add(time.Hour))
+	expired, _ := j.GenToken("alice", 1, "st1", "t2", time.Now().Add(-time.Minute))
+	revoked, _ := j.GenToken("alice", 1, "st3", "t3", time.Now().Add(time.Hour))
+
+	claims, err := call(withToken(alice))
+	if assert.NoError(t, err) && assert.NotNil(t, claims) {
+		assert.Equal(t, "st1", claims.SessionId)
+	}
+	for name, ctx := range map[string]context.Context{
+		"missing": context.Background(),
+		"expired": withToken(expired),
+		"revoked": withToken(revoked),
+	} {
+		_, err = call(ctx)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err), name)
+	}
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	v1 "go-chat/api/v1"
+	"go-chat/internal/middleware"
+	"go-chat/pkg/jwt"
+	"go-chat/pkg/log"
+)
+
+// revokedUsers 中的用户或 token id 视为已吊销
+type revokedUsers map[string]bool
+
+func (r revokedUsers) IsRevoked(ctx context.Context, claims *jwt.MyCustomClaims) (bool, error) {
+	return r[claims.UserId] || r[claims.ID], nil
+}
+
+func TestStrictAuth_Revoked(t *testing.T) {
+	conf := viper.New()
+	conf.Set("security.jwt.key", "test")
+	conf.Set("log.log_file_name", filepath.Join(t.TempDir(), "test.log"))
+	logger := log.NewLog(conf)
+	j := jwt.NewJwt(conf)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/v1/me", middleware.StrictAuth(j, revokedUsers{"bob": true, "t3": true}, logger), func(ctx *gin.Context) {
+		v1.HandleSuccess(ctx, nil)
+	})
+
+	do := func(token string) int {
+		req, _ := http.NewRequest(http.MethodGet, "/v1/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp.Code
+	}
+
+	alice, _ := j.GenToken("alice", 1, "st1", "t1", time.Now().Add(time.Hour))
+	bob, _ := j.GenToken("bob", 2, "st2", "t2", time.Now().Add(time.Hour))
+	revoked, _ := j.GenToken("alice", 1, "st3", "t3", time.Now().Add(time.Hour))
+	assert.Equal(t, http.StatusOK, do(alice))
+	assert.Equal(t, http.StatusUnauthorized, do(bob))
+	assert.Equal(t, http.StatusUnauthorized, do(revoked))
+	assert.Equal(t, http.StatusUnauthorized, do("not-a-token"))
+}
+
+func TestStrictAuth_QueryToken(t *testing.T) {
+	conf := viper.New()
+	conf.Set("security.jwt.key", "test")
+	conf.Set("log.log_file_name", filepath.Join(t.TempDir(), "test.log"))
+	logger := log.NewLog(conf)
+	j := jwt.NewJwt(conf)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	strictAuth := middleware.StrictAuth(j, revokedUsers{}, logger)
+	ok := func(ctx *gin.Context) { v1.HandleSuccess(ctx, nil) }
+	router.GET("/v1/me", strictAuth, ok)
+	router.GET("/v1/events/stream", strictAuth, ok)
+
+	token, _ := j.GenToken("alice", 1, "st1", "t1", time.Now().Add(time.Hour))
+	do := func(path string) int {
+		req, _ := http.NewRequest(http.MethodGet, path+"?accessToken="+token, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp.Code
+	}
+
+	// 只有实时事件通道接受 query 中的 token
+	assert.Equal(t, http.StatusOK, do("/v1/events/stream"))
+	assert.Equal(t, http.StatusUnauthorized, do("/v1/me"))
+}
+
+func TestClaims_Uid(t *testing.T) {
+	conf := viper.New()
+	conf.Set("security.jwt.key", "test")
+	j := jwt.NewJwt(conf)
+
+	token, _ := j.GenToken("alice", 42, "st1", "t1", time.Now().Add(time.Hour))
+	claims, err := j.ParseToken(token)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "alice", claims.UserId)
+		assert.Equal(t, uint(42), claims.Uid())
+	}
+	// 没有 sub 的旧 token
+	assert.Equal(t, uint(0), (&jwt.MyCustomClaims{}).Uid())
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	v1 "go-chat/api/v1"
+	"go-chat/internal/middleware"
+	"go-chat/pkg/jwt"
+	"go-chat/pkg/log"
+	"go-chat/pkg/ratelimit"
+)
+
+func newRouter(t *testing.T, policies []ratelimit.Policy) (*gin.Engine, *jwt.JWT) {
+	conf := viper.New()
+	conf.Set("security.jwt.key", "test")
+	conf.Set("log.log_file_name", filepath.Join(t.TempDir(), "test.log"))
+	logger := log.NewLog(conf)
+	j := jwt.NewJwt(conf)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.RateLimit(ratelimit.NewMemoryLimiter(), j, logger, policies))
+	router.POST("/v1/user/login", func(ctx *gin.Context) { v1.HandleSuccess(ctx, nil) })
+	router.POST("/v1/upload/file", func(ctx *gin.Context) { v1.HandleSuccess(ctx, nil) })
+	return router, j
+}
+
+func doRequest(router *gin.Engine, path string, ip string, token string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodPost, path, nil)
+	req.RemoteAddr = ip + ":1234"
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestRateLimit_PerIP(t *testing.T) {
+	router, _ := newRouter(t, []ratelimit.Policy{
+		{Name: "login", Route: "/v1/user/login", Key: ratelimit.KeyIP, Rate: 2, Period: time.Minute},
+	})
+
+	assert.Equal(t, http.StatusOK, doRequest(router, "/v1/user/login", "10.0.0.1", "").Code)
+	assert.Equal(t, http.StatusOK, doRequest(router, "/v1/user/login", "10.0.0.1", "").Code)
+
+	resp := doRequest(router, "/v1/user/login", "10.0.0.1", "")
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.Equal(t, "30", resp.Header().Get("Retry-After"))
+
+	// 其他 IP 和未配置的路由不受影响
+	assert.Equal(t, http.StatusOK, doRequest(router, "/v1/user/login", "10.0.0.2", "").Code)
+	assert.Equal(t, http.StatusOK, doRequest(router, "/v1/upload/file", "10.0.0.1", "").Code)
+}
+
+func TestRateLimit_PerUser(t *testing.T) {
+	router, j := newRouter(t, []ratelimit.Policy{
+		{Name: "upload", Route: "/v1/upload/file", Key: ratelimit.KeyUser, Rate: 1, Period: time.Minute},
+	})
+	alice, _ := j.GenToken("alice", 1, "st1", "t1", time.Now().Add(time.Hour))
+	bob, _ := j.GenToken("bob", 2, "st2", "t2", time.Now().Add(time.Hour))
+
+	assert.Equal(t, http.StatusOK, doRequest(router, "/v1/upload/file", "10.0.0.1", alice).Code)
+	assert.Equal(t, http.StatusTooManyRequests, doRequest(router, "/v1/upload/file", "10.0.0.1", alice).Code)
+	// 同一 IP 的其他用户单独计数
+	assert.Equal(t, http.StatusOK, doRequest(router, "/v1/upload/file", "10.0.0.1", bob).Code)
+}
+
+func TestRateLimit_PerUserSurvivesRename(t *testing.T) {
+	router, j := newRouter(t, []ratelimit.Policy{
+		{Name: "upload", Route: "/v1/upload/file", Key: ratelimit.KeyUser, Rate: 1, Period: time.Minute},
+	})
+	before, _ := j.GenToken("alice", 1, "st1", "t1", time.Now().Add(time.Hour))
+	renamed, _ := j.GenToken("alice2", 1, "st1", "t2", time.Now().Add(time.Hour))
+	// 另一个用户改名为 alice 后不应共用原来的计数
+	other, _ := j.GenToken("alice", 2, "st2", "t3", time.Now().Add(time.Hour))
+
+	assert.Equal(t, http.StatusOK, doRequest(router, "/v1/upload/file", "10.0.0.1", before).Code)
+	assert.Equal(t, http.StatusTooManyRequests, doRequest(router, "/v1/upload/file", "10.0.0.1", renamed).Code)
+	assert.Equal(t, http.StatusOK, doRequest(router, "/v1/upload/file", "10.0.0.1", other).Code)
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	v1 "go-chat/api/v1"
+	"go-chat/internal/middleware"
+	"go-chat/internal/model"
+	"go-chat/pkg/jwt"
+	"go-chat/pkg/log"
+)
+
+type fakeChecker map[string][]string
+
+func (c fakeChecker) HasPermission(ctx context.Context, userName string, permission string) (bool, error) {
+	for _, p := range c[userName] {
+		if p == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestRequirePermission(t *testing.T) {
+	conf := viper.New()
+	conf.Set("security.jwt.key", "test")
+	conf.Set("log.log_file_name", filepath.Join(t.TempDir(), "test.log"))
+	logger := log.NewLog(conf)
+	j := jwt.NewJwt(conf)
+
+	checker := fakeChecker{"mod": {model.PermissionUsersBan}}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/v1/admin/ban",
+		middleware.StrictAuth(j, revokedUsers{}, logger),
+		middleware.RequirePermission(checker, logger, model.PermissionUsersBan),
+		func(ctx *gin.Context) { v1.HandleSuccess(ctx, nil) },
+	)
+
+	do := func(token string) int {
+		req, _ := http.NewRequest(http.MethodPost, "/v1/admin/ban", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp.Code
+	}
+
+	mod, _ := j.GenToken("mod", 1, "st1", "t1", time.Now().Add(time.Hour))
+	user, _ := j.GenToken("user", 2, "st2", "t2", time.Now().Add(time.Hour))
+	assert.Equal(t, http.StatusOK, do(mod))
+	assert.Equal(t, http.StatusForbidden, do(user))
+	assert.Equal(t, http.StatusUnauthorized, do(""))
+}
@@ -0,0 +1,133 @@
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-chat/internal/model"
+	"go-chat/internal/repository"
+	"gorm.io/gorm"
+)
+
+// test/server/repository 中的旧测试无法编译，仓储层的测试暂时放在这里，直接调用 AccountRepository
+func TestAccountRepository_AnonymizeClearsPersonalData(t *testing.T) {
+	repo, db := newTestRepository(t, newTestConfig(t),
+		&model.UserBasics{}, &model.Session{}, &model.RefreshToken{}, &model.TwoFactor{}, &model.RecoveryCode{},
+		&model.UserIdentity{}, &model.UserRole{}, &model.ModerationFlag{}, &model.Report{}, &model.AuditLog{},
+		&model.EmailMessage{}, &model.DataExport{})
+	now := time.Now()
+	alice := createUser(t, db, &model.UserBasics{
+		Name: "alice", PassWord: "hash", Avatar: "a.png", Gender: "f", Phone: "13800000000", Email: "alice@example.com",
+		Motto: "hi", Identity: "member", ClientIp: "10.0.0.1", ClientPort: "5000", Salt: "salt", DeviceInfo: "iPhone",
+		PhoneVerifiedAt: &now, EmailVerifiedAt: &now, DeletionScheduledAt: &now,
+	})
+	bob := createUser(t, db, &model.UserBasics{Name: "bob", Email: "bob@example.com", Phone: "13900000000"})
+
+	for _, row := range []interface{}{
+		&model.Session{UserId: alice.ID, SessionId: "s1", ClientIp: "10.0.0.1", LastSeenAt: now, ExpiresAt: now},
+		&model.RefreshToken{UserId: alice.ID, FamilyId: "s1", TokenHash: "h1", ExpiresAt: now},
+		&model.TwoFactor{UserId: alice.ID, Secret: "secret"},
+		&model.RecoveryCode{UserId: alice.ID, CodeHash: "c1"},
+		&model.UserIdentity{UserId: alice.ID, Provider: "mock", Subject: "sub", Email: "alice@example.com"},
+		&model.UserRole{UserId: alice.ID, RoleId: 1},
+		&model.ModerationFlag{UserId: alice.ID, Field: "motto", Content: "bad words"},
+		&model.EmailMessage{Recipient: "alice@example.com", Template: "login_code", Data: `{"Code":"123456"}`},
+		&model.DataExport{UserId: alice.ID, FileName: "export.zip"},
+		&model.Report{ReporterId: alice.ID, TargetUserId: bob.ID, Reason: "spam", Detail: "alice's note"},
+		&model.Report{ReporterId: bob.ID, TargetUserId: alice.ID, Reason: "spam", Detail: "bob's note"},
+		&model.Session{UserId: bob.ID, SessionId: "s2", LastSeenAt: now, ExpiresAt: now},
+		&model.EmailMessage{Recipient: "bob@example.com", Template: "login_code"},
+	} {
+		require.NoError(t, db.Create(row).Error)
+	}
+
+	aliceTarget := fmt.Sprint(alice.ID)
+	audits := []*model.AuditLog{
+		// 本人的操作
+		{ActorId: alice.ID, ActorName: "alice", TargetType: model.AuditTargetUser, TargetId: aliceTarget, Action: model.AuditActionEmailChange,
+			Metadata: `{"old":"alice@old.com","new":"alice@example.com"}`, Ip: "10.0.0.1", UserAgent: "Safari", DeviceInfo: "iPhone"},
+		// 管理员以用户为对象的操作
+		{ActorId: bob.ID, ActorName: "bob", TargetType: model.AuditTargetUser, TargetId: aliceTarget, Action: model.AuditActionUserDisable,
+			Metadata: `{"name":"alice"}`, Ip: "10.0.0.2", UserAgent: "Chrome", DeviceInfo: "Mac"},
+		// 用户名输错等找不到用户的登录失败
+		{TargetType: model.AuditTargetUser, Action: model.AuditActionLoginFailed, Metadata: `{"name":"alice","reason":"password"}`, Ip: "10.0.0.3"},
+		{TargetType: model.AuditTargetUser, Action: model.AuditActionLoginFailed, Metadata: `{"email":"alice@example.com"}`, Ip: "10.0.0.4"},
+	}
+	bobLog := &model.AuditLog{ActorId: bob.ID, ActorName: "bob", TargetType: model.AuditTargetUser, TargetId: fmt.Sprint(bob.ID),
+		Action: model.AuditActionLogin, Metadata: `{"method":"password"}`, Ip: "10.0.0.2", UserAgent: "Chrome", DeviceInfo: "Mac"}
+	require.NoError(t, db.Create(append(audits, bobLog)).Error)
+
+	accountRepo := repository.NewAccountRepository(repo)
+	require.NoError(t, repository.NewTransaction(repo).Transaction(context.Background(), func(ctx context.Context) error {
+		return accountRepo.Anonymize(ctx, alice, now)
+	}))
+
+	var user model.UserBasics
+	require.NoError(t, db.Unscoped().First(&user, alice.ID).Error)
+	assert.Equal(t, fmt.Sprintf("deleted_%d", alice.ID), user.Name)
+	for column, value := range map[string]string{
+		"pass_word": user.PassWord, "avatar": user.Avatar, "gender": user.Gender, "phone": user.Phone, "email": user.Email,
+		"motto": user.Motto, "identity": user.Identity, "client_ip": user.ClientIp, "client_port": user.ClientPort,
+		"salt": user.Salt, "device_info": user.DeviceInfo,
+	} {
+		assert.Empty(t, value, column)
+	}
+	assert.Nil(t, user.PhoneVerifiedAt)
+	assert.Nil(t, user.EmailVerifiedAt)
+	assert.Nil(t, user.DeletionScheduledAt)
+	assert.NotNil(t, user.AnonymizedAt)
+	assert.True(t, user.DeleteAt.Valid)
+
+	for _, m := range []interface{}{
+		&model.Session{}, &model.RefreshToken{}, &model.TwoFactor{}, &model.RecoveryCode{},
+		&model.UserIdentity{}, &model.UserRole{}, &model.ModerationFlag{}, &model.DataExport{},
+	} {
+		assert.Equal(t, int64(0), count(t, db.Unscoped().Model(m).Where("user_id = ?", alice.ID)), "%T", m)
+	}
+	assert.Equal(t, int64(0), count(t, db.Unscoped().Model(&model.EmailMessage{}).Where("recipient = ?", "alice@example.com")))
+
+	// 用户提交的举报保留处理所需的信息，只清空补充说明
+	var report model.Report
+	require.NoError(t, db.Where("reporter_id = ?", alice.ID).First(&report).Error)
+	assert.Empty(t, report.Detail)
+	assert.Equal(t, "spam", report.Reason)
+
+	for _, log := range audits {
+		var got model.AuditLog
+		require.NoError(t, db.First(&got, log.ID).Error)
+		assert.Empty(t, got.Metadata, log.Action)
+		assert.Empty(t, got.UserAgent, log.Action)
+		assert.Empty(t, got.DeviceInfo, log.Action)
+		if log.TargetId != "" {
+			assert.Empty(t, got.Ip, log.Action)
+		}
+		if log.ActorId == alice.ID {
+			assert.Equal(t, user.Name, got.ActorName)
+		}
+	}
+
+	// 其他用户的数据不受影响
+	var other model.UserBasics
+	require.NoError(t, db.First(&other, bob.ID).Error)
+	assert.Equal(t, "bob@example.com", other.Email)
+	assert.Equal(t, int64(1), count(t, db.Model(&model.Session{}).Where("user_id = ?", bob.ID)))
+	assert.Equal(t, int64(1), count(t, db.Model(&model.EmailMessage{}).Where("recipient = ?", "bob@example.com")))
+	var bobReport model.Report
+	require.NoError(t, db.Where("reporter_id = ?", bob.ID).First(&bobReport).Error)
+	assert.Equal(t, "bob's note", bobReport.Detail)
+	var kept model.AuditLog
+	require.NoError(t, db.First(&kept, bobLog.ID).Error)
+	assert.Equal(t, bobLog.Metadata, kept.Metadata)
+	assert.Equal(t, bobLog.Ip, kept.Ip)
+}
+
+func count(t *testing.T, db *gorm.DB) int64 {
+	t.Helper()
+	var n int64
+	require.NoError(t, db.Count(&n).Error)
+	return n
+}
@@ -0,0 +1,228 @@
+package service_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "go-chat/api/v1"
+	"go-chat/internal/model"
+	"go-chat/internal/repository"
+	"go-chat/internal/service"
+	"go-chat/pkg/oidc"
+	"go-chat/pkg/password"
+	"gorm.io/gorm"
+)
+
+// mockProvider 只实现发现、JWKS 和令牌端点，id_token 中的 nonce 取自最近一次授权地址
+type mockProvider struct {
+	*httptest.Server
+	t      *testing.T
+	key    *rsa.PrivateKey
+	nonce  string
+	claims jwt.MapClaims
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	p := &mockProvider{t: t, key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{
+			"issuer":                 p.URL,
+			"authorization_endpoint": p.URL + "/authorize",
+			"token_endpoint":         p.URL + "/token",
+			"jwks_uri":               p.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     p.sign(),
+		})
+	})
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+func (p *mockProvider) sign() string {
+	claims := jwt.MapClaims{
+		"iss":   p.URL,
+		"aud":   "go-chat",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": p.nonce,
+	}
+	for k, v := range p.claims {
+		claims[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "k1"
+	signed, err := token.SignedString(p.key)
+	require.NoError(p.t, err)
+	return signed
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// memoryState 用内存代替 Redis 保存授权请求
+type memoryState struct {
+	repository.IdentityRepository
+	states map[string]*repository.OIDCState
+}
+
+func (m *memoryState) SaveState(ctx context.Context, state string, value *repository.OIDCState, ttl time.Duration) error {
+	m.states[state] = value
+	return nil
+}
+
+func (m *memoryState) TakeState(ctx context.Context, state string) (*repository.OIDCState, error) {
+	value := m.states[state]
+	delete(m.states, state)
+	return value, nil
+}
+
+// recordingLogin 记录最终登录的用户，签发 token 由 UserService 的测试覆盖
+type recordingLogin struct {
+	service.UserService
+	user *model.UserBasics
+}
+
+func (r *recordingLogin) LoginUser(ctx context.Context, user *model.UserBasics, method string) (*v1.LoginResponseData, error) {
+	r.user = user
+	return &v1.LoginResponseData{}, nil
+}
+
+type allowAllModeration struct {
+	service.ModerationService
+}
+
+func (allowAllModeration) Review(ctx context.Context, field string, text string) (*service.ModerationResult, error) {
+	return &service.ModerationResult{Content: text, Text: text}, nil
+}
+
+func (allowAllModeration) RecordFlag(ctx context.Context, userId uint, field string, res *service.ModerationResult) {
+}
+
+type oidcFixture struct {
+	oidc     service.OIDCService
+	provider *mockProvider
+	login    *recordingLogin
+	db       *gorm.DB
+}
+
+func newOIDCFixture(t *testing.T) *oidcFixture {
+	conf := newTestConfig(t)
+	repo, db := newTestRepository(t, conf, &model.UserBasics{}, &model.UserIdentity{}, &model.AuditLog{})
+	svc := newTestService(t, conf, repo, nil)
+	provider := newMockProvider(t)
+	registry, err := oidc.NewStaticRegistry(oidc.NewClient(oidc.Config{
+		Name:         "mock",
+		Issuer:       provider.URL,
+		ClientId:     "go-chat",
+		ClientSecret: "secret",
+		RedirectURL:  "https://chat.example.com/oidc/callback",
+	}, provider.Client()))
+	require.NoError(t, err)
+
+	userRepo := repository.NewUserRepository(repo)
+	auditService := service.NewAuditService(svc, repository.NewAuditRepository(repo), userRepo)
+	identityRepo := &memoryState{IdentityRepository: repository.NewIdentityRepository(repo), states: map[string]*repository.OIDCState{}}
+	login := &recordingLogin{}
+	return &oidcFixture{
+		oidc:     service.NewOIDCService(svc, registry, login, allowAllModeration{}, auditService, password.New(password.NewBcrypt(4)), identityRepo, userRepo),
+		provider: provider,
+		login:    login,
+		db:       db,
+	}
+}
+
+// signIn 走完授权和回调，返回登录的用户
+func (f *oidcFixture) signIn(t *testing.T, claims jwt.MapClaims) *model.UserBasics {
+	t.Helper()
+	ctx := context.Background()
+	data, err := f.oidc.Authorize(ctx, "mock")
+	require.NoError(t, err)
+	u, err := url.Parse(data.AuthorizationUrl)
+	require.NoError(t, err)
+	f.provider.nonce = u.Query().Get("nonce")
+	f.provider.claims = claims
+
+	_, err = f.oidc.Callback(ctx, "mock", &v1.OIDCCallbackRequest{Code: "code", State: data.State})
+	require.NoError(t, err)
+	return f.login.user
+}
+
+func (f *oidcFixture) identities(t *testing.T, userId uint) int64 {
+	var count int64
+	require.NoError(t, f.db.Model(&model.UserIdentity{}).Where("user_id = ?", userId).Count(&count).Error)
+	return count
+}
+
+func TestOIDC_UnverifiedLocalEmailNotLinked(t *testing.T) {
+	f := newOIDCFixture(t)
+	// 本地邮箱可能是别人写上去的，未经验证码确认
+	local := createUser(t, f.db, &model.UserBasics{Name: "alice", Email: "alice@example.com"})
+
+	user := f.signIn(t, jwt.MapClaims{"sub": "user-1", "email": "alice@example.com", "email_verified": true})
+	assert.NotEqual(t, local.ID, user.ID)
+	assert.Equal(t, int64(0), f.identities(t, local.ID))
+	assert.Equal(t, int64(1), f.identities(t, user.ID))
+	// 新账号不占用已被填写的邮箱
+	assert.Empty(t, user.Email)
+	assert.Nil(t, user.EmailVerifiedAt)
+}
+
+func TestOIDC_VerifiedLocalEmailLinked(t *testing.T) {
+	f := newOIDCFixture(t)
+	now := time.Now()
+	local := createUser(t, f.db, &model.UserBasics{Name: "alice", Email: "alice@example.com", EmailVerifiedAt: &now})
+
+	user := f.signIn(t, jwt.MapClaims{"sub": "user-1", "email": "Alice@example.com", "email_verified": true})
+	assert.Equal(t, local.ID, user.ID)
+	assert.Equal(t, int64(1), f.identities(t, local.ID))
+
+	// 之后按 sub 找到绑定的用户
+	user = f.signIn(t, jwt.MapClaims{"sub": "user-1", "email": "alice@example.com", "email_verified": true})
+	assert.Equal(t, local.ID, user.ID)
+	assert.Equal(t, int64(1), f.identities(t, local.ID))
+}
+
+func TestOIDC_UnverifiedProviderEmailNotLinked(t *testing.T) {
+	f := newOIDCFixture(t)
+	now := time.Now()
+	local := createUser(t, f.db, &model.UserBasics{Name: "alice", Email: "alice@example.com", EmailVerifiedAt: &now})
+
+	user := f.signIn(t, jwt.MapClaims{"sub": "user-1", "email": "alice@example.com", "email_verified": false})
+	assert.NotEqual(t, local.ID, user.ID)
+	assert.Equal(t, int64(0), f.identities(t, local.ID))
+}
@@ -0,0 +1,181 @@
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "go-chat/api/v1"
+	"go-chat/internal/model"
+	"go-chat/internal/repository"
+	"go-chat/internal/service"
+	"go-chat/pkg/event"
+	"gorm.io/gorm"
+)
+
+func newReportService(t *testing.T, thresholds []map[string]interface{}) (service.ReportService, *gorm.DB) {
+	conf := newTestConfig(t)
+	conf.Set("report.thresholds", thresholds)
+	repo, db := newTestRepository(t, conf,
+		&model.UserBasics{}, &model.Report{}, &model.ModerationAction{}, &model.UserRestriction{}, &model.AuditLog{})
+	svc := newTestService(t, conf, repo, nil)
+	userRepo := repository.NewUserRepository(repo)
+	auditService := service.NewAuditService(svc, repository.NewAuditRepository(repo), userRepo)
+	return service.NewReportService(svc, conf, event.NewHub(), auditService, repository.NewReportRepository(repo), userRepo), db
+}
+
+func reportUser(t *testing.T, s service.ReportService, reporter string, target uint) *model.Report {
+	t.Helper()
+	report, err := s.CreateReport(context.Background(), reporter, &v1.CreateReportRequest{
+		TargetType: model.ReportTargetUser,
+		TargetId:   strconv.FormatUint(uint64(target), 10),
+		Reason:     "spam",
+	})
+	if err != nil {
+		t.Fatalf("failed to create report: %v", err)
+	}
+	return report
+}
+
+func TestReport_DistinctReportersTriggerRestriction(t *testing.T) {
+	s, db := newReportService(t, []map[string]interface{}{
+		{"reports": 3, "window": "1h", "restriction": model.ModerationActionMute, "duration": "24h"},
+	})
+	target := createUser(t, db, &model.UserBasics{Name: "target"})
+	for i := 0; i < 3; i++ {
+		createUser(t, db, &model.UserBasics{Name: fmt.Sprintf("reporter%d", i)})
+	}
+
+	reportUser(t, s, "reporter0", target.ID)
+	reportUser(t, s, "reporter1", target.ID)
+	assert.NoError(t, s.CheckRestriction(context.Background(), target.ID, model.ModerationActionMute))
+
+	reportUser(t, s, "reporter2", target.ID)
+	assert.ErrorIs(t, s.CheckRestriction(context.Background(), target.ID, model.ModerationActionMute), v1.ErrUserMuted)
+	assert.NoError(t, s.CheckRestriction(context.Background(), target.ID, model.ModerationActionBan))
+
+	var actions int64
+	db.Model(&model.ModerationAction{}).Where("target_user_id = ?", target.ID).Count(&actions)
+	assert.Equal(t, int64(1), actions)
+}
+
+func TestReport_RepeatReportsFromOneUserDoNotTrigger(t *testing.T) {
+	s, db := newReportService(t, []map[string]interface{}{
+		{"reports": 3, "window": "1h", "restriction": model.ModerationActionMute, "duration": "24h"},
+	})
+	target := createUser(t, db, &model.UserBasics{Name: "target"})
+	createUser(t, db, &model.UserBasics{Name: "reporter"})
+
+	for i := 0; i < 5; i++ {
+		reportUser(t, s, "reporter", target.ID)
+	}
+	assert.NoError(t, s.CheckRestriction(context.Background(), target.ID, model.ModerationActionMute))
+}
+
+func TestReport_ReportsOutsideWindowDoNotCount(t *testing.T) {
+	s, db := newReportService(t, []map[string]interface{}{
+		{"reports": 2, "window": "1h", "restriction": model.ModerationActionMute, "duration": "24h"},
+	})
+	target := createUser(t, db, &model.UserBasics{Name: "target"})
+	createUser(t, db, &model.UserBasics{Name: "reporter0"})
+	createUser(t, db, &model.UserBasics{Name: "reporter1"})
+
+	old := reportUser(t, s, "reporter0", target.ID)
+	db.Model(old).Update("create_at", time.Now().Add(-2*time.Hour))
+	reportUser(t, s, "reporter1", target.ID)
+	assert.NoError(t, s.CheckRestriction(context.Background(), target.ID, model.ModerationActionMute))
+}
+
+func TestReport_MostSevereThresholdWins(t *testing.T) {
+	s, db := newReportService(t, []map[string]interface{}{
+		{"reports": 2, "window": "1h", "restriction": model.ModerationActionMute, "duration": "24h"},
+		{"reports": 2, "window": "1h", "restriction": model.ModerationActionBan, "duration": "24h"},
+	})
+	target := createUser(t, db, &model.UserBasics{Name: "target"})
+	createUser(t, db, &model.UserBasics{Name: "reporter0"})
+	createUser(t, db, &model.UserBasics{Name: "reporter1"})
+
+	reportUser(t, s, "reporter0", target.ID)
+	reportUser(t, s, "reporter1", target.ID)
+	assert.ErrorIs(t, s.CheckRestriction(context.Background(), target.ID, model.ModerationActionBan), v1.ErrUserBanned)
+	assert.NoError(t, s.CheckRestriction(context.Background(), target.ID, model.ModerationActionMute))
+}
+
+func TestReport_SelfReportRejected(t *testing.T) {
+	s, db := newReportService(t, nil)
+	user := createUser(t, db, &model.UserBasics{Name: "alice"})
+
+	_, err := s.CreateReport(context.Background(), "alice", &v1.CreateReportRequest{
+		TargetType: model.ReportTargetUser,
+		TargetId:   strconv.FormatUint(uint64(user.ID), 10),
+		Reason:     "spam",
+	})
+	assert.ErrorIs(t, err, v1.ErrBadRequest)
+}
+
+func TestReport_ClaimAndResolve(t *testing.T) {
+	s, db := newReportService(t, nil)
+	target := createUser(t, db, &model.UserBasics{Name: "target"})
+	createUser(t, db, &model.UserBasics{Name: "reporter"})
+	createUser(t, db, &model.UserBasics{Name: "mod1"})
+	createUser(t, db, &model.UserBasics{Name: "mod2"})
+	report := reportUser(t, s, "reporter", target.ID)
+	ctx := context.Background()
+
+	// 未领取的举报不能直接处理
+	err := s.ResolveReport(ctx, "mod1", report.ID, &v1.ResolveReportRequest{Action: model.ModerationActionBan, Duration: 24})
+	assert.ErrorIs(t, err, v1.ErrReportNotClaimed)
+
+	assert.NoError(t, s.ClaimReport(ctx, "mod1", report.ID))
+	assert.ErrorIs(t, s.ClaimReport(ctx, "mod2", report.ID), v1.ErrReportAlreadyClaimed)
+
+	// 只有领取人可以处理
+	err = s.ResolveReport(ctx, "mod2", report.ID, &v1.ResolveReportRequest{Action: model.ModerationActionBan, Duration: 24})
+	assert.ErrorIs(t, err, v1.ErrReportNotClaimed)
+
+	err = s.ResolveReport(ctx, "mod1", report.ID, &v1.ResolveReportRequest{Action: model.ModerationActionBan, Duration: 24, Note: "spam"})
+	assert.NoError(t, err)
+	assert.ErrorIs(t, s.CheckRestriction(ctx, target.ID, model.ModerationActionBan), v1.ErrUserBanned)
+
+	detail, err := s.GetReport(ctx, "mod1", report.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.ReportStatusResolved, detail.Report.Status)
+	assert.Equal(t, model.ModerationActionBan, detail.Report.Action)
+	assert.Len(t, detail.Actions, 1)
+
+	var audits int64
+	db.Model(&model.AuditLog{}).Where("action = ?", model.AuditActionReportResolve).Count(&audits)
+	assert.Equal(t, int64(1), audits)
+
+	assert.ErrorIs(t, s.ClaimReport(ctx, "mod2", report.ID), v1.ErrReportResolved)
+	err = s.ResolveReport(ctx, "mod1", report.ID, &v1.ResolveReportRequest{Action: model.ModerationActionDismiss})
+	assert.ErrorIs(t, err, v1.ErrReportResolved)
+}
+
+func TestReport_ListQueueByStatus(t *testing.T) {
+	s, db := newReportService(t, nil)
+	target := createUser(t, db, &model.UserBasics{Name: "target"})
+	createUser(t, db, &model.UserBasics{Name: "reporter"})
+	createUser(t, db, &model.UserBasics{Name: "mod"})
+	first := reportUser(t, s, "reporter", target.ID)
+	reportUser(t, s, "reporter", target.ID)
+	ctx := context.Background()
+	assert.NoError(t, s.ClaimReport(ctx, "mod", first.ID))
+
+	pending, err := s.ListReports(ctx, "mod", &v1.ListReportsRequest{Status: model.ReportStatusPending})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), pending.Total)
+
+	claimed, err := s.ListReports(ctx, "mod", &v1.ListReportsRequest{Status: model.ReportStatusClaimed})
+	assert.NoError(t, err)
+	if assert.Len(t, claimed.List, 1) {
+		assert.Equal(t, first.ID, claimed.List[0].ID)
+	}
+
+	all, err := s.ListReports(ctx, "mod", &v1.ListReportsRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), all.Total)
+}
@@ -0,0 +1,191 @@
+package service_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "go-chat/api/v1"
+	"go-chat/internal/model"
+	"go-chat/internal/repository"
+	"go-chat/internal/service"
+	"go-chat/pkg/password"
+	"go-chat/pkg/rsakey"
+	"go-chat/pkg/verifycode"
+)
+
+// recordingQueue 记录入队的邮件，代替数据库中的邮件队列
+type recordingQueue struct {
+	service.EmailQueueService
+	mu   sync.Mutex
+	sent []queuedEmail
+}
+
+type queuedEmail struct {
+	to       string
+	template string
+	data     interface{}
+}
+
+func (q *recordingQueue) Enqueue(ctx context.Context, to string, template string, locale string, data interface{}, expiresAt *time.Time) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.sent = append(q.sent, queuedEmail{to: to, template: template, data: data})
+	return nil
+}
+
+func (q *recordingQueue) last(template string) (queuedEmail, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i := len(q.sent) - 1; i >= 0; i-- {
+		if q.sent[i].template == template {
+			return q.sent[i], true
+		}
+	}
+	return queuedEmail{}, false
+}
+
+type resetFixture struct {
+	*tokenFixture
+	users  service.UserService
+	queue  *recordingQueue
+	store  *verifycode.MemoryStore
+	key    *rsakey.Key
+	hasher *password.Manager
+}
+
+func newResetFixture(t *testing.T) *resetFixture {
+	f := newTokenFixture(t, nil)
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	key := &rsakey.Key{ID: "k1", PrivateKey: privateKey}
+	provider, err := rsakey.NewStaticProvider("k1", key)
+	require.NoError(t, err)
+
+	store := verifycode.NewMemoryStore()
+	queue := &recordingQueue{}
+	emailService := service.NewEmailService(f.svc, verifycode.New(store, verifycode.Options{TTL: 10 * time.Minute}), queue)
+	hasher := password.New(password.NewBcrypt(4))
+	users := service.NewUserService(f.svc, emailService, nil, nil, nil, f.audit, f.tokens, nil, hasher, provider, repository.NewUserRepository(f.repo))
+	return &resetFixture{tokenFixture: f, users: users, queue: queue, store: store, key: key, hasher: hasher}
+}
+
+func (f *resetFixture) encrypt(t *testing.T, plain string) string {
+	ciphertext, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, &f.key.PrivateKey.PublicKey, []byte(plain), nil)
+	require.NoError(t, err)
+	return "oaep:k1:" + base64.StdEncoding.EncodeToString(ciphertext)
+}
+
+func (f *resetFixture) resetRequest(t *testing.T, email string, code string, newPassword string) *v1.ResetPasswordRequest {
+	return &v1.ResetPasswordRequest{
+		Email:      email,
+		Code:       code,
+		Password:   f.encrypt(t, newPassword),
+		RePassword: f.encrypt(t, newPassword),
+	}
+}
+
+// requestCode 发起找回密码并返回邮件中的验证码
+func (f *resetFixture) requestCode(t *testing.T, email string) string {
+	t.Helper()
+	require.NoError(t, f.users.ForgotPassword(context.Background(), email))
+	mail, ok := f.queue.last("reset_password_code")
+	require.True(t, ok)
+	return mail.data.(map[string]interface{})["Code"].(string)
+}
+
+func (f *resetFixture) passwordMatches(t *testing.T, userId uint, plain string) bool {
+	var user model.UserBasics
+	require.NoError(t, f.db.First(&user, userId).Error)
+	ok, _, err := f.hasher.Verify(plain, user.PassWord)
+	require.NoError(t, err)
+	return ok
+}
+
+func createResetUser(t *testing.T, f *resetFixture) *model.UserBasics {
+	hash, err := f.hasher.Hash("old-password")
+	require.NoError(t, err)
+	return createUser(t, f.db, &model.UserBasics{Name: "alice", Email: "alice@example.com", PassWord: hash})
+}
+
+func TestResetPassword_RevokesSessions(t *testing.T) {
+	f := newResetFixture(t)
+	user := createResetUser(t, f)
+	createSession(t, f.tokenFixture, user.ID, "phone")
+	stream, _ := f.hub.SubscribeSession(user.ID, "phone", "")
+	ctx := context.Background()
+
+	code := f.requestCode(t, "alice@example.com")
+	assert.NoError(t, f.users.ResetPassword(ctx, f.resetRequest(t, "alice@example.com", code, "new-password")))
+	assert.True(t, f.passwordMatches(t, user.ID, "new-password"))
+
+	// 所有会话和之前签发的 access token 失效，在线连接断开
+	var session model.Session
+	require.NoError(t, f.db.Where("session_id = ?", "phone").First(&session).Error)
+	assert.NotNil(t, session.RevokedAt)
+	assert.False(t, f.revocations.revokedBefore[user.ID].IsZero())
+	_, closed := drain(stream)
+	assert.True(t, closed)
+
+	_, ok := f.queue.last("password_changed")
+	assert.True(t, ok)
+
+	// 能收到验证码说明邮箱可用
+	var updated model.UserBasics
+	require.NoError(t, f.db.First(&updated, user.ID).Error)
+	assert.NotNil(t, updated.EmailVerifiedAt)
+}
+
+func TestResetPassword_CodeSingleUse(t *testing.T) {
+	f := newResetFixture(t)
+	user := createResetUser(t, f)
+	ctx := context.Background()
+
+	code := f.requestCode(t, "alice@example.com")
+	assert.NoError(t, f.users.ResetPassword(ctx, f.resetRequest(t, "alice@example.com", code, "new-password")))
+
+	err := f.users.ResetPassword(ctx, f.resetRequest(t, "alice@example.com", code, "other-password"))
+	assert.ErrorIs(t, err, v1.ErrEmailCodeError)
+	assert.True(t, f.passwordMatches(t, user.ID, "new-password"))
+}
+
+func TestResetPassword_CodeExpired(t *testing.T) {
+	f := newResetFixture(t)
+	user := createResetUser(t, f)
+	createSession(t, f.tokenFixture, user.ID, "phone")
+
+	code := f.requestCode(t, "alice@example.com")
+	f.store.SetClock(func() time.Time { return time.Now().Add(11 * time.Minute) })
+
+	err := f.users.ResetPassword(context.Background(), f.resetRequest(t, "alice@example.com", code, "new-password"))
+	assert.ErrorIs(t, err, v1.ErrEmailCodeError)
+	assert.True(t, f.passwordMatches(t, user.ID, "old-password"))
+	assert.True(t, f.revocations.revokedBefore[user.ID].IsZero())
+}
+
+func TestResetPassword_WrongCodeForOtherEmail(t *testing.T) {
+	f := newResetFixture(t)
+	user := createResetUser(t, f)
+	createUser(t, f.db, &model.UserBasics{Name: "bob", Email: "bob@example.com"})
+
+	// 验证码只能用于申请找回的邮箱
+	code := f.requestCode(t, "alice@example.com")
+	err := f.users.ResetPassword(context.Background(), f.resetRequest(t, "bob@example.com", code, "new-password"))
+	assert.ErrorIs(t, err, v1.ErrEmailCodeError)
+	assert.True(t, f.passwordMatches(t, user.ID, "old-password"))
+}
+
+func TestForgotPassword_UnknownEmail(t *testing.T) {
+	f := newResetFixture(t)
+
+	// 未注册的邮箱同样返回成功，但不发送邮件
+	assert.NoError(t, f.users.ForgotPassword(context.Background(), "nobody@example.com"))
+	assert.Empty(t, f.queue.sent)
+}
@@ -0,0 +1,101 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "go-chat/api/v1"
+	"go-chat/internal/model"
+	"go-chat/internal/repository"
+	"go-chat/internal/service"
+	"gorm.io/gorm"
+)
+
+func newSearchService(t *testing.T) (service.UserService, *gorm.DB) {
+	conf := newTestConfig(t)
+	repo, db := newTestRepository(t, conf, &model.UserBasics{}, &model.UserRestriction{})
+	svc := newTestService(t, conf, repo, nil)
+	return service.NewUserService(svc, nil, nil, nil, nil, nil, nil, nil, nil, nil, repository.NewUserRepository(repo)), db
+}
+
+func searchNames(t *testing.T, s service.UserService, req *v1.SearchUsersRequest) []string {
+	t.Helper()
+	data, err := s.SearchUsers(context.Background(), "searcher", req)
+	require.NoError(t, err)
+	names := make([]string, 0, len(data.List))
+	for _, p := range data.List {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func TestSearchUsers_HidesUnavailableUsers(t *testing.T) {
+	s, db := newSearchService(t)
+	now := time.Now()
+	later := now.Add(time.Hour)
+	earlier := now.Add(-time.Hour)
+	createUser(t, db, &model.UserBasics{Name: "searcher"})
+	createUser(t, db, &model.UserBasics{Name: "al_active"})
+	createUser(t, db, &model.UserBasics{Name: "al_disabled", DisabledAt: &now})
+	createUser(t, db, &model.UserBasics{Name: "al_leaving", DeletionScheduledAt: &later})
+	banned := createUser(t, db, &model.UserBasics{Name: "al_banned"})
+	expired := createUser(t, db, &model.UserBasics{Name: "al_ban_expired"})
+	muted := createUser(t, db, &model.UserBasics{Name: "al_muted"})
+	require.NoError(t, db.Create(&model.UserRestriction{UserId: banned.ID, Type: model.ModerationActionBan}).Error)
+	require.NoError(t, db.Create(&model.UserRestriction{UserId: expired.ID, Type: model.ModerationActionBan, ExpiresAt: &earlier}).Error)
+	require.NoError(t, db.Create(&model.UserRestriction{UserId: muted.ID, Type: model.ModerationActionMute, ExpiresAt: &later}).Error)
+	deleted := createUser(t, db, &model.UserBasics{Name: "al_deleted"})
+	require.NoError(t, db.Delete(deleted).Error)
+
+	// 禁用、封禁中、申请注销和已注销的用户不出现；禁言不影响搜索
+	assert.Equal(t, []string{"al_active", "al_ban_expired", "al_muted"}, searchNames(t, s, &v1.SearchUsersRequest{Name: "al"}))
+}
+
+func TestSearchUsers_NamePrefixIsLiteral(t *testing.T) {
+	s, db := newSearchService(t)
+	createUser(t, db, &model.UserBasics{Name: "searcher"})
+	createUser(t, db, &model.UserBasics{Name: "a_b"})
+	createUser(t, db, &model.UserBasics{Name: "axb"})
+	createUser(t, db, &model.UserBasics{Name: "100%"})
+	createUser(t, db, &model.UserBasics{Name: "1000"})
+
+	assert.Equal(t, []string{"a_b"}, searchNames(t, s, &v1.SearchUsersRequest{Name: "a_"}))
+	assert.Equal(t, []string{"100%"}, searchNames(t, s, &v1.SearchUsersRequest{Name: "100%"}))
+	// 不返回自己
+	assert.Empty(t, searchNames(t, s, &v1.SearchUsersRequest{Name: "searcher"}))
+}
+
+func TestSearchUsers_DiscoverabilityFlags(t *testing.T) {
+	s, db := newSearchService(t)
+	now := time.Now()
+	createUser(t, db, &model.UserBasics{Name: "searcher"})
+	hidden := createUser(t, db, &model.UserBasics{Name: "hidden", Email: "hidden@example.com", EmailVerifiedAt: &now,
+		Phone: "+8613800138000", PhoneVerifiedAt: &now})
+	require.NoError(t, db.Model(hidden).Update("discoverable_by_name", false).Error)
+	createUser(t, db, &model.UserBasics{Name: "open", Email: "open@example.com", EmailVerifiedAt: &now,
+		Phone: "+8613900139000", PhoneVerifiedAt: &now, DiscoverableByEmail: true, DiscoverableByPhone: true})
+	createUser(t, db, &model.UserBasics{Name: "unverified", Email: "unverified@example.com",
+		Phone: "+8613700137000", DiscoverableByEmail: true, DiscoverableByPhone: true})
+
+	// 按用户名默认可以搜到，关闭后不可以
+	assert.Empty(t, searchNames(t, s, &v1.SearchUsersRequest{Name: "hidden"}))
+	assert.Equal(t, []string{"open"}, searchNames(t, s, &v1.SearchUsersRequest{Name: "open"}))
+
+	// 邮箱和手机号默认不可搜索，且必须已验证
+	assert.Empty(t, searchNames(t, s, &v1.SearchUsersRequest{Email: "hidden@example.com"}))
+	assert.Equal(t, []string{"open"}, searchNames(t, s, &v1.SearchUsersRequest{Email: "open@example.com"}))
+	assert.Empty(t, searchNames(t, s, &v1.SearchUsersRequest{Email: "unverified@example.com"}))
+
+	assert.Empty(t, searchNames(t, s, &v1.SearchUsersRequest{Phone: "13800138000"}))
+	assert.Equal(t, []string{"open"}, searchNames(t, s, &v1.SearchUsersRequest{Phone: "139 0013 9000"}))
+	assert.Empty(t, searchNames(t, s, &v1.SearchUsersRequest{Phone: "13700137000"}))
+
+	// 三种方式只能选一种
+	_, err := s.SearchUsers(context.Background(), "searcher", &v1.SearchUsersRequest{Name: "open", Email: "open@example.com"})
+	assert.ErrorIs(t, err, v1.ErrBadRequest)
+	_, err = s.SearchUsers(context.Background(), "searcher", &v1.SearchUsersRequest{})
+	assert.ErrorIs(t, err, v1.ErrBadRequest)
+}
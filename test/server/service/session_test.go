@@ -0,0 +1,125 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "go-chat/api/v1"
+	"go-chat/internal/model"
+	"go-chat/internal/repository"
+	"go-chat/internal/service"
+	"go-chat/pkg/event"
+)
+
+// failingRevoke 数据库中的吊销写入成功后返回错误，使事务回滚
+type failingRevoke struct {
+	service.TokenService
+	closed int
+}
+
+func (f *failingRevoke) RevokeSessions(ctx context.Context, user *model.UserBasics, sessionIds []string) error {
+	if err := f.TokenService.RevokeSessions(ctx, user, sessionIds); err != nil {
+		return err
+	}
+	return errors.New("commit failed")
+}
+
+func (f *failingRevoke) CloseSessions(ctx context.Context, user *model.UserBasics, sessionIds []string) {
+	f.closed++
+	f.TokenService.CloseSessions(ctx, user, sessionIds)
+}
+
+func newSessionService(f *tokenFixture, tokens service.TokenService) service.SessionService {
+	return service.NewSessionService(f.svc, tokens, f.audit, repository.NewSessionRepository(f.repo), repository.NewUserRepository(f.repo))
+}
+
+func createSession(t *testing.T, f *tokenFixture, userId uint, sessionId string) {
+	t.Helper()
+	now := time.Now()
+	if err := f.db.Create(&model.Session{UserId: userId, SessionId: sessionId, LastSeenAt: now, ExpiresAt: now.Add(time.Hour)}).Error; err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+}
+
+// drain 读出已缓冲的事件，返回 C 是否已关闭
+func drain(sub *event.Subscription) ([]*event.Event, bool) {
+	var events []*event.Event
+	for {
+		select {
+		case e, ok := <-sub.C:
+			if !ok {
+				return events, true
+			}
+			events = append(events, e)
+		case <-time.After(50 * time.Millisecond):
+			return events, false
+		}
+	}
+}
+
+func TestSession_RevokeClosesStreamAfterCommit(t *testing.T) {
+	f := newTokenFixture(t, nil)
+	user := createUser(t, f.db, &model.UserBasics{Name: "alice"})
+	createSession(t, f, user.ID, "phone")
+	createSession(t, f, user.ID, "laptop")
+	phone, _ := f.hub.SubscribeSession(user.ID, "phone", "")
+	laptop, _ := f.hub.SubscribeSession(user.ID, "laptop", "")
+	defer laptop.Close()
+
+	assert.NoError(t, newSessionService(f, f.tokens).RevokeSession(context.Background(), "alice", "phone"))
+
+	events, closed := drain(phone)
+	assert.True(t, closed)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "session.revoked", events[0].Type)
+	}
+	assert.True(t, f.revocations.revoked["session:phone"])
+
+	// 其他会话只收到通知，连接保持
+	events, closed = drain(laptop)
+	assert.False(t, closed)
+	assert.Len(t, events, 1)
+}
+
+func TestSession_RollbackKeepsStreamOpen(t *testing.T) {
+	f := newTokenFixture(t, nil)
+	user := createUser(t, f.db, &model.UserBasics{Name: "alice"})
+	createSession(t, f, user.ID, "phone")
+	createSession(t, f, user.ID, "laptop")
+	phone, _ := f.hub.SubscribeSession(user.ID, "phone", "")
+	defer phone.Close()
+	tokens := &failingRevoke{TokenService: f.tokens}
+
+	_, err := newSessionService(f, tokens).RevokeOtherSessions(context.Background(), "alice", "laptop")
+	assert.EqualError(t, err, "commit failed")
+	assert.Equal(t, 0, tokens.closed)
+
+	events, closed := drain(phone)
+	assert.False(t, closed)
+	assert.Empty(t, events)
+	assert.Empty(t, f.revocations.revoked)
+
+	// 事务回滚，会话和审计日志都没有写入
+	var session model.Session
+	assert.NoError(t, f.db.Where("session_id = ?", "phone").First(&session).Error)
+	assert.Nil(t, session.RevokedAt)
+	var audits int64
+	f.db.Model(&model.AuditLog{}).Where("action = ?", model.AuditActionSessionRevoke).Count(&audits)
+	assert.Equal(t, int64(0), audits)
+
+	_, err = newSessionService(f, f.tokens).RevokeOtherSessions(context.Background(), "alice", "laptop")
+	assert.NoError(t, err)
+	_, closed = drain(phone)
+	assert.True(t, closed)
+}
+
+func TestSession_RevokeUnknownSession(t *testing.T) {
+	f := newTokenFixture(t, nil)
+	createUser(t, f.db, &model.UserBasics{Name: "alice"})
+
+	err := newSessionService(f, f.tokens).RevokeSession(context.Background(), "alice", "missing")
+	assert.ErrorIs(t, err, v1.ErrSessionNotFound)
+}
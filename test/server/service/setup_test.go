@@ -0,0 +1,64 @@
+package service_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/spf13/viper"
+	"go-chat/internal/model"
+	"go-chat/internal/repository"
+	"go-chat/internal/service"
+	"go-chat/pkg/jwt"
+	"go-chat/pkg/log"
+	"go-chat/pkg/sid"
+	"gorm.io/gorm"
+)
+
+// newTestRepository 使用临时目录中的 sqlite 数据库，不依赖 redis 的仓储可以直接使用
+func newTestRepository(t *testing.T, conf *viper.Viper, models ...interface{}) (*repository.Repository, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	if err = db.AutoMigrate(models...); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return repository.NewRepository(newTestLogger(conf), db, nil), db
+}
+
+func newTestConfig(t *testing.T) *viper.Viper {
+	conf := viper.New()
+	conf.Set("security.jwt.key", "test")
+	conf.Set("log.log_file_name", filepath.Join(t.TempDir(), "test.log"))
+	return conf
+}
+
+func newTestLogger(conf *viper.Viper) *log.Logger {
+	return log.NewLog(conf)
+}
+
+// newTestService sf 为 nil 时用于不需要生成 ID 的场景
+func newTestService(t *testing.T, conf *viper.Viper, repo *repository.Repository, sf *sid.Sid) *service.Service {
+	return service.NewService(repository.NewTransaction(repo), newTestLogger(conf), sf, jwt.NewJwt(conf))
+}
+
+// newTestSid sonyflake 需要私有网段 IP 生成机器 ID，没有时跳过测试
+func newTestSid(t *testing.T) (sf *sid.Sid) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != nil {
+			t.Skipf("sonyflake unavailable: %v", r)
+		}
+	}()
+	return sid.NewSid()
+}
+
+func createUser(t *testing.T, db *gorm.DB, user *model.UserBasics) *model.UserBasics {
+	t.Helper()
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return user
+}
@@ -0,0 +1,219 @@
+package service_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	jwtv5 "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	v1 "go-chat/api/v1"
+	"go-chat/internal/model"
+	"go-chat/internal/repository"
+	"go-chat/internal/service"
+	"go-chat/pkg/event"
+	"go-chat/pkg/jwt"
+	"go-chat/pkg/sid"
+	"gorm.io/gorm"
+)
+
+// memoryRevocation 用内存代替 Redis 中的吊销记录，refresh token 仍然落在 sqlite 中
+type memoryRevocation struct {
+	repository.TokenRepository
+	mu            sync.Mutex
+	revokedBefore map[uint]time.Time
+	revoked       map[string]bool
+}
+
+func newMemoryRevocation(tokenRepo repository.TokenRepository) *memoryRevocation {
+	return &memoryRevocation{
+		TokenRepository: tokenRepo,
+		revokedBefore:   make(map[uint]time.Time),
+		revoked:         make(map[string]bool),
+	}
+}
+
+func (m *memoryRevocation) SetRevokedBefore(ctx context.Context, userId uint, t time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.revokedBefore[userId] = t
+	return nil
+}
+
+func (m *memoryRevocation) RevokeAccessToken(ctx context.Context, tokenId string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.revoked["token:"+tokenId] = true
+	return nil
+}
+
+func (m *memoryRevocation) RevokeSession(ctx context.Context, sessionId string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.revoked["session:"+sessionId] = true
+	return nil
+}
+
+func (m *memoryRevocation) GetRevocation(ctx context.Context, userId uint, sessionId string, tokenId string) (time.Time, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.revokedBefore[userId], m.revoked["token:"+tokenId] || m.revoked["session:"+sessionId], nil
+}
+
+type tokenFixture struct {
+	repo        *repository.Repository
+	svc         *service.Service
+	audit       service.AuditService
+	tokens      service.TokenService
+	revocations *memoryRevocation
+	hub         *event.Hub
+	db          *gorm.DB
+	jwt         *jwt.JWT
+}
+
+func newTokenFixture(t *testing.T, sf *sid.Sid) *tokenFixture {
+	conf := newTestConfig(t)
+	repo, db := newTestRepository(t, conf,
+		&model.UserBasics{}, &model.RefreshToken{}, &model.Session{}, &model.AuditLog{},
+		&model.Report{}, &model.ModerationAction{}, &model.UserRestriction{})
+	svc := newTestService(t, conf, repo, sf)
+	hub := event.NewHub()
+	userRepo := repository.NewUserRepository(repo)
+	auditService := service.NewAuditService(svc, repository.NewAuditRepository(repo), userRepo)
+	reportService := service.NewReportService(svc, conf, hub, auditService, repository.NewReportRepository(repo), userRepo)
+	revocations := newMemoryRevocation(repository.NewTokenRepository(repo))
+	return &tokenFixture{
+		repo:        repo,
+		svc:         svc,
+		audit:       auditService,
+		tokens:      service.NewTokenService(svc, conf, hub, auditService, reportService, revocations, repository.NewSessionRepository(repo), userRepo),
+		revocations: revocations,
+		hub:         hub,
+		db:          db,
+		jwt:         jwt.NewJwt(conf),
+	}
+}
+
+func (f *tokenFixture) isRevoked(t *testing.T, accessToken string) bool {
+	t.Helper()
+	claims, err := f.jwt.ParseToken(accessToken)
+	if err != nil {
+		t.Fatalf("failed to parse token: %v", err)
+	}
+	revoked, err := f.tokens.IsRevoked(context.Background(), claims)
+	if err != nil {
+		t.Fatalf("IsRevoked: %v", err)
+	}
+	return revoked
+}
+
+func TestToken_RotateThenReplayRevokesFamily(t *testing.T) {
+	f := newTokenFixture(t, newTestSid(t))
+	user := createUser(t, f.db, &model.UserBasics{Name: "alice"})
+	ctx := context.Background()
+
+	first, err := f.tokens.IssueTokens(ctx, user)
+	assert.NoError(t, err)
+	second, err := f.tokens.Refresh(ctx, first.RefreshToken)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+	assert.False(t, f.isRevoked(t, second.AccessToken))
+
+	// 重放已轮换的 refresh token，整个家族失效
+	_, err = f.tokens.Refresh(ctx, first.RefreshToken)
+	assert.ErrorIs(t, err, v1.ErrRefreshTokenReused)
+
+	_, err = f.tokens.Refresh(ctx, second.RefreshToken)
+	assert.ErrorIs(t, err, v1.ErrRefreshTokenInvalid)
+	assert.True(t, f.isRevoked(t, first.AccessToken))
+	assert.True(t, f.isRevoked(t, second.AccessToken))
+
+	var active int64
+	f.db.Model(&model.Session{}).Where("user_id = ? AND revoked_at IS NULL", user.ID).Count(&active)
+	assert.Equal(t, int64(0), active)
+
+	// 同一用户的其他登录不受影响
+	other, err := f.tokens.IssueTokens(ctx, user)
+	assert.NoError(t, err)
+	assert.False(t, f.isRevoked(t, other.AccessToken))
+	_, err = f.tokens.Refresh(ctx, other.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func TestToken_ReplayRotatedTokenRevokesFamily(t *testing.T) {
+	f := newTokenFixture(t, nil)
+	user := createUser(t, f.db, &model.UserBasics{Name: "alice"})
+	now := time.Now()
+	// 模拟 old 已经轮换为 current
+	assert.NoError(t, f.db.Create(&model.Session{UserId: user.ID, SessionId: "family", LastSeenAt: now, ExpiresAt: now.Add(time.Hour)}).Error)
+	assert.NoError(t, f.db.Create(&model.RefreshToken{
+		UserId: user.ID, FamilyId: "family", TokenHash: hashToken("old"), AccessTokenId: "jti-old",
+		ExpiresAt: now.Add(time.Hour), UsedAt: &now,
+	}).Error)
+	assert.NoError(t, f.db.Create(&model.RefreshToken{
+		UserId: user.ID, FamilyId: "family", TokenHash: hashToken("current"), AccessTokenId: "jti-current",
+		ExpiresAt: now.Add(time.Hour),
+	}).Error)
+
+	_, err := f.tokens.Refresh(context.Background(), "old")
+	assert.ErrorIs(t, err, v1.ErrRefreshTokenReused)
+
+	_, err = f.tokens.Refresh(context.Background(), "current")
+	assert.ErrorIs(t, err, v1.ErrRefreshTokenInvalid)
+	assert.True(t, f.revocations.revoked["token:jti-current"])
+	assert.True(t, f.revocations.revoked["session:family"])
+
+	var session model.Session
+	assert.NoError(t, f.db.Where("session_id = ?", "family").First(&session).Error)
+	assert.NotNil(t, session.RevokedAt)
+
+	var audits int64
+	f.db.Model(&model.AuditLog{}).Where("action = ?", model.AuditActionRefreshTokenReuse).Count(&audits)
+	assert.Equal(t, int64(1), audits)
+}
+
+func TestToken_IsRevokedCutoffSecond(t *testing.T) {
+	f := newTokenFixture(t, nil)
+	ctx := context.Background()
+	cutoff := time.Unix(1700000000, 600000000)
+	assert.NoError(t, f.revocations.SetRevokedBefore(ctx, 1, cutoff))
+
+	claimsAt := func(uid uint, issuedAt time.Time) *jwt.MyCustomClaims {
+		return &jwt.MyCustomClaims{
+			UserId: "alice",
+			RegisteredClaims: jwtv5.RegisteredClaims{
+				Subject:  strconv.FormatUint(uint64(uid), 10),
+				ID:       "jti",
+				IssuedAt: jwtv5.NewNumericDate(issuedAt),
+			},
+		}
+	}
+
+	// 吊销后立即重新登录，新 token 与吊销时间在同一秒内签发，仍然有效
+	revoked, err := f.tokens.IsRevoked(ctx, claimsAt(1, cutoff))
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	revoked, err = f.tokens.IsRevoked(ctx, claimsAt(1, cutoff.Add(-time.Second)))
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	// 其他用户不受影响
+	revoked, err = f.tokens.IsRevoked(ctx, claimsAt(2, cutoff.Add(-time.Second)))
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	// 没有 sub 的旧 token 一律视为已吊销
+	revoked, err = f.tokens.IsRevoked(ctx, &jwt.MyCustomClaims{UserId: "alice"})
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}